@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+
+	"github.com/spf13/cobra"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/database/repository"
+	"github.com/welovemedia/ffmate/internal/metrics"
+	"github.com/welovemedia/ffmate/internal/presets"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+)
+
+var presetsCmd = &cobra.Command{
+	Use:   "presets",
+	Short: "import, export and install presets",
+}
+
+var presetsExportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "export all presets to a json or yaml bundle",
+	Args:  cobra.NoArgs,
+	Run:   exportPresets,
+}
+
+var presetsImportCmd = &cobra.Command{
+	Use:   "import <file>",
+	Short: "import presets from a json or yaml bundle, use - to read from stdin",
+	Args:  cobra.ExactArgs(1),
+	Run:   importPresets,
+}
+
+var presetsLibraryCmd = &cobra.Command{
+	Use:   "library",
+	Short: "list the presets of the built-in library",
+	Args:  cobra.NoArgs,
+	Run:   listLibrary,
+}
+
+var presetsInstallCmd = &cobra.Command{
+	Use:   "install <name>",
+	Short: "install a preset of the built-in library, use --upgrade to upgrade an installed one",
+	Args:  cobra.ExactArgs(1),
+	Run:   installLibraryPreset,
+}
+
+func init() {
+	// the database flag is not bound to viper as it would override the binding of the server command
+	if runtime.GOOS == "windows" {
+		presetsCmd.PersistentFlags().StringP("database", "b", "%APPDATA%\\ffmate\\db.sql", "the path do the database")
+	} else {
+		presetsCmd.PersistentFlags().StringP("database", "b", "~/.ffmate/db.sqlite", "the path do the database")
+	}
+	presetsExportCmd.Flags().String("format", "json", "format of the bundle (json or yaml)")
+	presetsExportCmd.Flags().StringP("output", "o", "", "file to write the bundle to (default: stdout)")
+	presetsImportCmd.Flags().Bool("overwrite", false, "overwrite presets that already exist")
+	presetsInstallCmd.Flags().Bool("upgrade", false, "upgrade the installed preset to the library version")
+
+	presetsCmd.AddCommand(presetsExportCmd, presetsImportCmd, presetsLibraryCmd, presetsInstallCmd)
+	rootCmd.AddCommand(presetsCmd)
+}
+
+// setupPresets opens the database and initializes the services without starting the server
+func setupPresets(cmd *cobra.Command) *sev.Sev {
+	database, _ := cmd.Flags().GetString("database")
+	s := sev.New("ffmate", config.Config().AppVersion, database, 0)
+
+	(&repository.Preset{DB: s.DB()}).Setup()
+	(&repository.Webhook{DB: s.DB()}).Setup()
+
+	metrics := &metrics.Metrics{}
+	for name, gauge := range metrics.Gauges() {
+		s.Metrics().RegisterGauge(name, gauge)
+	}
+	for name, gauge := range metrics.GaugesVec() {
+		s.Metrics().RegisterGaugeVec(name, gauge)
+	}
+
+	service.Init(s)
+	return s
+}
+
+func exportPresets(cmd *cobra.Command, args []string) {
+	s := setupPresets(cmd)
+	format, _ := cmd.Flags().GetString("format")
+	output, _ := cmd.Flags().GetString("output")
+
+	bundle, err := service.PresetService().ExportPresets()
+	if err != nil {
+		s.Logger().Errorf("failed to export presets: %v", err)
+		os.Exit(1)
+	}
+	b, err := presets.Encode(bundle, presets.Format(format))
+	if err != nil {
+		s.Logger().Errorf("failed to export presets: %v", err)
+		os.Exit(1)
+	}
+
+	if output == "" {
+		os.Stdout.Write(b)
+		return
+	}
+	if err := os.WriteFile(output, b, 0644); err != nil {
+		s.Logger().Errorf("failed to write presets: %v", err)
+		os.Exit(1)
+	}
+	s.Logger().Infof("exported %d presets to %s", len(bundle.Presets), output)
+}
+
+func importPresets(cmd *cobra.Command, args []string) {
+	s := setupPresets(cmd)
+	overwrite, _ := cmd.Flags().GetBool("overwrite")
+
+	var b []byte
+	var err error
+	if args[0] == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(args[0])
+	}
+	if err != nil {
+		s.Logger().Errorf("failed to read presets: %v", err)
+		os.Exit(1)
+	}
+
+	bundle, err := presets.Decode(b)
+	if err != nil {
+		s.Logger().Errorf("failed to import presets: %v", err)
+		os.Exit(1)
+	}
+	if _, err := service.PresetService().ImportPresets(bundle, overwrite); err != nil {
+		s.Logger().Errorf("failed to import presets: %v", err)
+		os.Exit(1)
+	}
+}
+
+func listLibrary(cmd *cobra.Command, args []string) {
+	s := setupPresets(cmd)
+	library, err := service.PresetService().Library()
+	if err != nil {
+		s.Logger().Errorf("failed to list the preset library: %v", err)
+		os.Exit(1)
+	}
+	for _, preset := range library {
+		status := "not installed"
+		switch {
+		case preset.UpdateAvailable:
+			status = fmt.Sprintf("installed v%d, update available", preset.InstalledVersion)
+		case preset.Installed != "":
+			status = "installed"
+		}
+		fmt.Printf("%-20s v%-3d %-20s %s\n", preset.Name, preset.Version, status, preset.Preset.Description)
+	}
+}
+
+func installLibraryPreset(cmd *cobra.Command, args []string) {
+	s := setupPresets(cmd)
+	upgrade, _ := cmd.Flags().GetBool("upgrade")
+
+	install := service.PresetService().InstallLibraryPreset
+	if upgrade {
+		changes, err := service.PresetService().DiffLibraryPreset(args[0])
+		if err != nil {
+			s.Logger().Errorf("failed to upgrade library preset: %v", err)
+			os.Exit(1)
+		}
+		for _, change := range changes {
+			fmt.Printf("%s: %v -> %v\n", change.Field, change.Old, change.New)
+		}
+		install = service.PresetService().UpgradeLibraryPreset
+	}
+
+	preset, err := install(args[0])
+	if err != nil {
+		s.Logger().Errorf("failed to install library preset: %v", err)
+		os.Exit(1)
+	}
+	s.Logger().Infof("installed library preset '%s' v%d (uuid: %s)", args[0], preset.GlobalPresetVersion, preset.Uuid)
+}
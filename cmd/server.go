@@ -44,6 +44,7 @@ func init() {
 	rootCmd.AddCommand(serverCmd)
 
 	serverCmd.PersistentFlags().StringP("ffmpeg", "f", "", "path to ffmpeg binary")
+	serverCmd.PersistentFlags().String("svtav1encapp", "SvtAv1EncApp", "path to SvtAv1EncApp binary")
 	serverCmd.PersistentFlags().StringP("port", "p", "3000", "the port to listen to")
 	serverCmd.PersistentFlags().BoolP("tray", "t", false, "start with tray menu (experimental)")
 	if runtime.GOOS == "windows" {
@@ -54,14 +55,40 @@ func init() {
 	serverCmd.PersistentFlags().UintP("max-concurrent-tasks", "m", 3, "define maximum concurrent running tasks")
 	serverCmd.PersistentFlags().BoolP("send-telemetry", "s", true, "enable sending anonymous telemetry data")
 	serverCmd.PersistentFlags().BoolP("no-ui", "n", false, "do not open the ui in the browser")
+	serverCmd.PersistentFlags().String("s3-endpoint", "", "endpoint of an s3 compatible storage (default: aws)")
+	serverCmd.PersistentFlags().String("s3-region", "", "region of the s3 storage")
+	serverCmd.PersistentFlags().String("s3-access-key", "", "access key of the s3 storage")
+	serverCmd.PersistentFlags().String("s3-secret-key", "", "secret key of the s3 storage")
+	serverCmd.PersistentFlags().String("sftp-key-file", "", "private key used for sftp storage (default: ~/.ssh/id_*)")
+	serverCmd.PersistentFlags().String("sftp-known-hosts", "", "known_hosts file used to verify sftp hosts (default: ~/.ssh/known_hosts)")
+	serverCmd.PersistentFlags().String("workspace-root", "", "directory task workspaces are created in (default: <tmp>/ffmate)")
+	serverCmd.PersistentFlags().Uint("workspace-min-free", 0, "free disk space in MB the workspace root needs to start a task (0 disables the check)")
+	serverCmd.PersistentFlags().Bool("keep-failed-workspaces", false, "keep the workspace of failed tasks for debugging")
+	serverCmd.PersistentFlags().Uint("output-min-free", 0, "free disk space in MB the output volume needs to start a task (0 disables the check)")
+	serverCmd.PersistentFlags().Float64("free-space-factor", 0, "require free disk space of this multiple of the input size on the output and workspace volumes (0 disables the estimate)")
+
+	serverCmd.PersistentFlags().Bool("strict-wildcards", false, "fail tasks that use unknown wildcards instead of keeping them as they are")
 
 	viper.BindPFlag("ffmpeg", serverCmd.PersistentFlags().Lookup("ffmpeg"))
+	viper.BindPFlag("svtav1EncApp", serverCmd.PersistentFlags().Lookup("svtav1encapp"))
 	viper.BindPFlag("port", serverCmd.PersistentFlags().Lookup("port"))
 	viper.BindPFlag("tray", serverCmd.PersistentFlags().Lookup("tray"))
 	viper.BindPFlag("database", serverCmd.PersistentFlags().Lookup("database"))
 	viper.BindPFlag("maxConcurrentTasks", serverCmd.PersistentFlags().Lookup("max-concurrent-tasks"))
 	viper.BindPFlag("sendTelemetry", serverCmd.PersistentFlags().Lookup("send-telemetry"))
 	viper.BindPFlag("noUI", serverCmd.PersistentFlags().Lookup("no-ui"))
+	viper.BindPFlag("s3Endpoint", serverCmd.PersistentFlags().Lookup("s3-endpoint"))
+	viper.BindPFlag("s3Region", serverCmd.PersistentFlags().Lookup("s3-region"))
+	viper.BindPFlag("s3AccessKey", serverCmd.PersistentFlags().Lookup("s3-access-key"))
+	viper.BindPFlag("s3SecretKey", serverCmd.PersistentFlags().Lookup("s3-secret-key"))
+	viper.BindPFlag("sftpKeyFile", serverCmd.PersistentFlags().Lookup("sftp-key-file"))
+	viper.BindPFlag("sftpKnownHosts", serverCmd.PersistentFlags().Lookup("sftp-known-hosts"))
+	viper.BindPFlag("workspaceRoot", serverCmd.PersistentFlags().Lookup("workspace-root"))
+	viper.BindPFlag("workspaceMinFree", serverCmd.PersistentFlags().Lookup("workspace-min-free"))
+	viper.BindPFlag("keepFailedWorkspaces", serverCmd.PersistentFlags().Lookup("keep-failed-workspaces"))
+	viper.BindPFlag("outputMinFree", serverCmd.PersistentFlags().Lookup("output-min-free"))
+	viper.BindPFlag("freeSpaceFactor", serverCmd.PersistentFlags().Lookup("free-space-factor"))
+	viper.BindPFlag("strictWildcards", serverCmd.PersistentFlags().Lookup("strict-wildcards"))
 }
 
 func start(cmd *cobra.Command, args []string) {
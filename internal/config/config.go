@@ -12,7 +12,8 @@ type ConfigDefinition struct {
 	AppName    string `mapstructure:"appName"`
 	AppVersion string `mapstructure:"appVersion"`
 
-	FFMpeg string `mapstructure:"ffmpeg"`
+	FFMpeg       string `mapstructure:"ffmpeg"`
+	SvtAv1EncApp string `mapstructure:"svtav1EncApp"`
 
 	Port               uint   `mapstructure:"port"`
 	Tray               bool   `mapstructure:"tray"`
@@ -23,6 +24,22 @@ type ConfigDefinition struct {
 	SendTelemetry      bool   `mapstructure:"sendTelemetry"`
 	NoUI               bool   `mapstructure:"noUI"`
 
+	S3Endpoint     string `mapstructure:"s3Endpoint"`
+	S3Region       string `mapstructure:"s3Region"`
+	S3AccessKey    string `mapstructure:"s3AccessKey"`
+	S3SecretKey    string `mapstructure:"s3SecretKey"`
+	SftpKeyFile    string `mapstructure:"sftpKeyFile"`
+	SftpKnownHosts string `mapstructure:"sftpKnownHosts"`
+
+	WorkspaceRoot        string `mapstructure:"workspaceRoot"`
+	WorkspaceMinFree     uint   `mapstructure:"workspaceMinFree"` // MB
+	KeepFailedWorkspaces bool   `mapstructure:"keepFailedWorkspaces"`
+
+	OutputMinFree   uint    `mapstructure:"outputMinFree"`   // MB
+	FreeSpaceFactor float64 `mapstructure:"freeSpaceFactor"` // multiple of the input size
+
+	StrictWildcards bool `mapstructure:"strictWildcards"` // fail tasks with unknown wildcards instead of keeping them
+
 	Mutex sync.RWMutex
 }
 
@@ -39,6 +56,20 @@ func Init() {
 	if config.Debug == "" {
 		config.Debug = os.Getenv("DEBUGO")
 	}
+
+	// fall back to the environment variables of the aws tooling
+	if config.S3Endpoint == "" {
+		config.S3Endpoint = os.Getenv("AWS_ENDPOINT_URL_S3")
+	}
+	if config.S3Region == "" {
+		config.S3Region = os.Getenv("AWS_REGION")
+	}
+	if config.S3AccessKey == "" {
+		config.S3AccessKey = os.Getenv("AWS_ACCESS_KEY_ID")
+	}
+	if config.S3SecretKey == "" {
+		config.S3SecretKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+	}
 }
 
 func Config() *ConfigDefinition {
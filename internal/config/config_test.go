@@ -21,6 +21,7 @@ func TestConfig(t *testing.T) {
 	viper.Set("appName", "TestApp")
 	viper.Set("appVersion", "1.0.0")
 	viper.Set("ffmpeg", "/usr/bin/ffmpeg")
+	viper.Set("svtav1EncApp", "/usr/bin/SvtAv1EncApp")
 	viper.Set("port", uint(8080))
 	viper.Set("tray", true)
 	viper.Set("database", "/path/to/db.sqlite")
@@ -29,6 +30,18 @@ func TestConfig(t *testing.T) {
 	viper.Set("maxConcurrentTasks", uint(4))
 	viper.Set("sendTelemetry", true)
 	viper.Set("noUI", true)
+	viper.Set("s3Endpoint", "http://localhost:9000")
+	viper.Set("s3Region", "eu-central-1")
+	viper.Set("s3AccessKey", "minioadmin")
+	viper.Set("s3SecretKey", "miniosecret")
+	viper.Set("sftpKeyFile", "/home/ffmate/.ssh/id_ed25519")
+	viper.Set("sftpKnownHosts", "/home/ffmate/.ssh/known_hosts")
+	viper.Set("workspaceRoot", "/scratch/ffmate")
+	viper.Set("workspaceMinFree", uint(2048))
+	viper.Set("keepFailedWorkspaces", true)
+	viper.Set("outputMinFree", uint(4096))
+	viper.Set("freeSpaceFactor", 1.5)
+	viper.Set("strictWildcards", true)
 
 	Init()
 	c := Config()
@@ -43,6 +56,7 @@ func TestConfig(t *testing.T) {
 		{"AppName", c.AppName, "TestApp", "AppName mismatch"},
 		{"AppVersion", c.AppVersion, "1.0.0", "AppVersion mismatch"},
 		{"FFMpeg", c.FFMpeg, "/usr/bin/ffmpeg", "FFMpeg path mismatch"},
+		{"SvtAv1EncApp", c.SvtAv1EncApp, "/usr/bin/SvtAv1EncApp", "SvtAv1EncApp path mismatch"},
 		{"Port", c.Port, uint(8080), "Port mismatch"},
 		{"Tray", c.Tray, true, "Tray setting mismatch"},
 		{"Database", c.Database, "/path/to/db.sqlite", "Database path mismatch"},
@@ -51,7 +65,19 @@ func TestConfig(t *testing.T) {
 		{"MaxConcurrentTasks", c.MaxConcurrentTasks, uint(4), "MaxConcurrentTasks mismatch"},
 		{"SendTelemetry", c.SendTelemetry, true, "SendTelemetry mismatch"},
 		{"NoUI", c.NoUI, true, "NoUI mismatch"},
-		{"Mutex", c.Mutex, sync.RWMutex{}, "Mutex mismatch"},
+		{"S3Endpoint", c.S3Endpoint, "http://localhost:9000", "S3Endpoint mismatch"},
+		{"S3Region", c.S3Region, "eu-central-1", "S3Region mismatch"},
+		{"S3AccessKey", c.S3AccessKey, "minioadmin", "S3AccessKey mismatch"},
+		{"S3SecretKey", c.S3SecretKey, "miniosecret", "S3SecretKey mismatch"},
+		{"SftpKeyFile", c.SftpKeyFile, "/home/ffmate/.ssh/id_ed25519", "SftpKeyFile mismatch"},
+		{"SftpKnownHosts", c.SftpKnownHosts, "/home/ffmate/.ssh/known_hosts", "SftpKnownHosts mismatch"},
+		{"WorkspaceRoot", c.WorkspaceRoot, "/scratch/ffmate", "WorkspaceRoot mismatch"},
+		{"WorkspaceMinFree", c.WorkspaceMinFree, uint(2048), "WorkspaceMinFree mismatch"},
+		{"KeepFailedWorkspaces", c.KeepFailedWorkspaces, true, "KeepFailedWorkspaces mismatch"},
+		{"OutputMinFree", c.OutputMinFree, uint(4096), "OutputMinFree mismatch"},
+		{"FreeSpaceFactor", c.FreeSpaceFactor, 1.5, "FreeSpaceFactor mismatch"},
+		{"StrictWildcards", c.StrictWildcards, true, "StrictWildcards mismatch"},
+		{"Mutex", reflect.TypeOf(&c.Mutex), reflect.TypeOf(&sync.RWMutex{}), "Mutex mismatch"},
 	}
 
 	// Run tests and track covered fields
@@ -2,10 +2,12 @@ package controller
 
 import (
 	"fmt"
+	"io"
 
 	"github.com/gin-gonic/gin"
 	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/welovemedia/ffmate/internal/interceptor"
+	"github.com/welovemedia/ffmate/internal/presets"
 	"github.com/welovemedia/ffmate/internal/service"
 	"github.com/welovemedia/ffmate/sev"
 	"github.com/welovemedia/ffmate/sev/exceptions"
@@ -25,6 +27,13 @@ func (c *PresetController) Setup(s *sev.Sev) {
 	s.Gin().PUT(c.Prefix+c.getEndpoint()+"/:uuid", c.updatePreset)
 	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.PageLimit, c.listPresets)
 	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid", c.getPreset)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/:uuid/preview", c.previewPreset)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/export", c.exportPresets)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/import", c.importPresets)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/library", c.listLibrary)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/library/:name/install", c.installLibraryPreset)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/library/:name/diff", c.diffLibraryPreset)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/library/:name/upgrade", c.upgradeLibraryPreset)
 }
 
 // @Summary Delete a preset
@@ -135,6 +144,158 @@ func (c *PresetController) updatePreset(gin *gin.Context) {
 	gin.JSON(200, preset.ToDto())
 }
 
+// @Summary Preview the wildcards of a preset
+// @Description Resolve the command and output file of a preset for the given input file like a task would, nothing is queued
+// @Tags presets
+// @Param uuid path string true "the presets uuid"
+// @Accept json
+// @Param request body dto.WildcardPreview true "input file, source and metadata, the other fields override the preset"
+// @Produce json
+// @Success 200 {object} dto.WildcardPreviewResult
+// @Router /presets/{uuid}/preview [post]
+func (c *PresetController) previewPreset(gin *gin.Context) {
+	uuid := gin.Param("uuid")
+	preview := &dto.WildcardPreview{}
+	if !c.sev.Validate().Bind(gin, preview) {
+		return
+	}
+
+	result, err := service.WildcardService().PreviewPreset(uuid, preview)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#previewing-a-preset"))
+		return
+	}
+
+	gin.JSON(200, result)
+}
+
+// @Summary Export all presets
+// @Description Export all presets as a bundle that can be imported into another instance
+// @Tags presets
+// @Param format query string false "json (default) or yaml"
+// @Produce json
+// @Success 200 {object} dto.PresetBundle
+// @Router /presets/export [get]
+func (c *PresetController) exportPresets(gin *gin.Context) {
+	format := presets.Format(gin.DefaultQuery("format", string(presets.FORMAT_JSON)))
+	bundle, err := service.PresetService().ExportPresets()
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#exporting-presets"))
+		return
+	}
+
+	b, err := presets.Encode(bundle, format)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#exporting-presets"))
+		return
+	}
+
+	contentType := "application/json"
+	if format == presets.FORMAT_YAML {
+		contentType = "application/yaml"
+	}
+	gin.Header("Content-Disposition", fmt.Sprintf("attachment; filename=\"presets.%s\"", format))
+	gin.Data(200, contentType, b)
+}
+
+// @Summary Import presets
+// @Description Import a JSON or YAML bundle of presets, presets that already exist are skipped unless overwrite is set
+// @Tags presets
+// @Accept json
+// @Param request body dto.PresetBundle true "preset bundle"
+// @Param overwrite query bool false "overwrite existing presets"
+// @Produce json
+// @Success 200 {object} dto.PresetImportResult
+// @Router /presets/import [post]
+func (c *PresetController) importPresets(gin *gin.Context) {
+	b, err := io.ReadAll(gin.Request.Body)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#importing-presets"))
+		return
+	}
+
+	bundle, err := presets.Decode(b)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#importing-presets"))
+		return
+	}
+
+	result, err := service.PresetService().ImportPresets(bundle, gin.Query("overwrite") == "true")
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#importing-presets"))
+		return
+	}
+
+	gin.JSON(200, result)
+}
+
+// @Summary List the preset library
+// @Description List the presets of the built-in library and whether they are installed or can be upgraded
+// @Tags presets
+// @Produce json
+// @Success 200 {object} []dto.LibraryPresetStatus
+// @Router /presets/library [get]
+func (c *PresetController) listLibrary(gin *gin.Context) {
+	library, err := service.PresetService().Library()
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-library"))
+		return
+	}
+
+	gin.JSON(200, library)
+}
+
+// @Summary Install a library preset
+// @Description Create a preset from the library preset with the given name
+// @Tags presets
+// @Param name path string true "the library presets name"
+// @Produce json
+// @Success 200 {object} dto.Preset
+// @Router /presets/library/{name}/install [post]
+func (c *PresetController) installLibraryPreset(gin *gin.Context) {
+	preset, err := service.PresetService().InstallLibraryPreset(gin.Param("name"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-library"))
+		return
+	}
+
+	gin.JSON(200, preset.ToDto())
+}
+
+// @Summary Diff a library preset
+// @Description List the fields upgrading the installed preset to the current library version would change
+// @Tags presets
+// @Param name path string true "the library presets name"
+// @Produce json
+// @Success 200 {object} []dto.FieldChange
+// @Router /presets/library/{name}/diff [get]
+func (c *PresetController) diffLibraryPreset(gin *gin.Context) {
+	changes, err := service.PresetService().DiffLibraryPreset(gin.Param("name"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-library"))
+		return
+	}
+
+	gin.JSON(200, changes)
+}
+
+// @Summary Upgrade a library preset
+// @Description Replace the installed preset with the current library version, the uuid of the preset is kept
+// @Tags presets
+// @Param name path string true "the library presets name"
+// @Produce json
+// @Success 200 {object} dto.Preset
+// @Router /presets/library/{name}/upgrade [post]
+func (c *PresetController) upgradeLibraryPreset(gin *gin.Context) {
+	preset, err := service.PresetService().UpgradeLibraryPreset(gin.Param("name"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/presets#preset-library"))
+		return
+	}
+
+	gin.JSON(200, preset.ToDto())
+}
+
 func (c *PresetController) GetName() string {
 	return "preset"
 }
@@ -30,7 +30,7 @@ func setupTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 	}
 
 	// Auto migrate models
-	err = db.AutoMigrate(&model.Preset{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Preset{}, &model.Webhook{}, &model.Secret{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -168,3 +168,119 @@ func TestPresetController(t *testing.T) {
 		}
 	})
 }
+
+func TestPresetLibraryController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, s := setupTestDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get underlying database: %v", err)
+	}
+	defer sqlDB.Close()
+
+	(&PresetController{Prefix: ""}).Setup(s)
+
+	request := func(method string, url string, body []byte) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		s.Gin().ServeHTTP(w, httptest.NewRequest(method, url, bytes.NewBuffer(body)))
+		return w
+	}
+
+	var installed dto.Preset
+	t.Run("Install library preset", func(t *testing.T) {
+		w := request("POST", "/v1/presets/library/svtav1-1080p/install", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		json.Unmarshal(w.Body.Bytes(), &installed)
+		if installed.GlobalPresetName != "svtav1-1080p" || installed.GlobalPresetVersion < 1 || installed.Encoder == nil {
+			t.Errorf("Unexpected preset %+v", installed)
+		}
+
+		if w := request("POST", "/v1/presets/library/svtav1-1080p/install", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected installing twice to fail, got %d", w.Code)
+		}
+		if w := request("POST", "/v1/presets/library/unknown/install", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected unknown library preset to fail, got %d", w.Code)
+		}
+	})
+
+	t.Run("Diff and upgrade library preset", func(t *testing.T) {
+		// pretend an older version of the library preset is installed
+		db.Model(&model.Preset{}).Where("uuid = ?", installed.Uuid).Updates(map[string]any{"global_preset_version": 0, "output_file": "/old.mkv"})
+
+		var library []dto.LibraryPresetStatus
+		json.Unmarshal(request("GET", "/v1/presets/library", nil).Body.Bytes(), &library)
+		found := false
+		for _, preset := range library {
+			if preset.Name == "svtav1-1080p" {
+				found = preset.Installed == installed.Uuid && preset.UpdateAvailable
+			}
+		}
+		if !found {
+			t.Errorf("Expected library preset to be installed with an update available: %+v", library)
+		}
+
+		var changes []dto.FieldChange
+		json.Unmarshal(request("GET", "/v1/presets/library/svtav1-1080p/diff", nil).Body.Bytes(), &changes)
+		fields := []string{}
+		for _, change := range changes {
+			fields = append(fields, change.Field)
+		}
+		if len(fields) != 2 || fields[0] != "globalPresetVersion" || fields[1] != "outputFile" {
+			t.Errorf("Unexpected changes %+v", changes)
+		}
+
+		w := request("POST", "/v1/presets/library/svtav1-1080p/upgrade", nil)
+		var upgraded dto.Preset
+		json.Unmarshal(w.Body.Bytes(), &upgraded)
+		if w.Code != http.StatusOK || upgraded.Uuid != installed.Uuid || upgraded.GlobalPresetVersion != installed.GlobalPresetVersion || upgraded.OutputFile != installed.OutputFile {
+			t.Errorf("Unexpected upgrade %d %+v", w.Code, upgraded)
+		}
+	})
+
+	t.Run("Export and import presets", func(t *testing.T) {
+		w := request("GET", "/v1/presets/export?format=yaml", nil)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Type") != "application/yaml" {
+			t.Fatalf("Unexpected export %d %s", w.Code, w.Header().Get("Content-Type"))
+		}
+		bundle := w.Body.Bytes()
+
+		var result dto.PresetImportResult
+		json.Unmarshal(request("POST", "/v1/presets/import", bundle).Body.Bytes(), &result)
+		if len(result.Created) != 0 || len(result.Skipped) == 0 {
+			t.Errorf("Expected existing presets to be skipped: %+v", result)
+		}
+
+		service.PresetService().DeletePreset(installed.Uuid)
+		json.Unmarshal(request("POST", "/v1/presets/import?overwrite=true", bundle).Body.Bytes(), &result)
+		if len(result.Created) != 1 || result.Created[0] != installed.Uuid {
+			t.Errorf("Expected deleted preset to be recreated: %+v", result)
+		}
+		if preset, err := service.PresetService().FindByUuid(installed.Uuid); err != nil || preset.GlobalPresetName != "svtav1-1080p" {
+			t.Errorf("Expected imported preset to keep its library name: %v", err)
+		}
+		var rows int64
+		db.Unscoped().Model(&model.Preset{}).Where("uuid = ?", installed.Uuid).Count(&rows)
+		if rows != 1 {
+			t.Errorf("Expected the deleted preset to be restored instead of duplicated, got %d rows", rows)
+		}
+
+		// a single invalid preset rolls back the whole bundle
+		crf := 30
+		invalid, _ := json.Marshal(dto.PresetBundle{Version: 1, Presets: []dto.Preset{
+			{Uuid: "import-valid", Name: "Valid", Command: "-i ${INPUT_FILE} ${OUTPUT_FILE}"},
+			{Uuid: "import-invalid", Name: "Invalid", Command: "-i ${INPUT_FILE} ${OUTPUT_FILE}", Encoder: &dto.EncoderSettings{Crf: &crf}},
+		}})
+		if w := request("POST", "/v1/presets/import", invalid); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected bundle with an invalid preset to fail, got %d", w.Code)
+		}
+		if _, err := service.PresetService().FindByUuid("import-valid"); err == nil {
+			t.Error("Expected the valid preset of the failed bundle not to be imported")
+		}
+
+		if w := request("POST", "/v1/presets/import", []byte("presets: [")); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected invalid bundle to fail, got %d", w.Code)
+		}
+	})
+}
@@ -0,0 +1,37 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+)
+
+type QueueController struct {
+	sev.Controller
+	sev    *sev.Sev
+	Prefix string
+}
+
+func (c *QueueController) Setup(s *sev.Sev) {
+	c.sev = s
+	s.Gin().GET(c.Prefix+c.getEndpoint(), c.getQueue)
+}
+
+// @Summary Get queue status
+// @Description Get whether the queue is held and why
+// @Tags queue
+// @Produce json
+// @Success 200 {object} dto.QueueStatus
+// @Router /queue [get]
+func (c *QueueController) getQueue(gin *gin.Context) {
+	status := service.QueueService().Status()
+	gin.JSON(200, &status)
+}
+
+func (c *QueueController) GetName() string {
+	return "queue"
+}
+
+func (c *QueueController) getEndpoint() string {
+	return "/v1/queue"
+}
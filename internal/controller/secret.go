@@ -0,0 +1,140 @@
+package controller
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/interceptor"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"github.com/welovemedia/ffmate/sev/exceptions"
+)
+
+type SecretController struct {
+	sev.Controller
+	sev *sev.Sev
+
+	Prefix string
+}
+
+func (c *SecretController) Setup(s *sev.Sev) {
+	c.sev = s
+	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:name", c.deleteSecret)
+	s.Gin().POST(c.Prefix+c.getEndpoint(), c.addSecret)
+	s.Gin().PUT(c.Prefix+c.getEndpoint()+"/:name", c.updateSecret)
+	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.PageLimit, c.listSecrets)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:name", c.getSecret)
+}
+
+// @Summary Get single secret
+// @Description Get a single secret by its name, the value is never returned
+// @Tags secrets
+// @Param name path string true "the secrets name"
+// @Produce json
+// @Success 200 {object} dto.Secret
+// @Router /secrets/{name} [get]
+func (c *SecretController) getSecret(gin *gin.Context) {
+	secret, err := service.SecretService().GetSecretByName(gin.Param("name"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/secrets#getting-a-single-secret"))
+		return
+	}
+
+	gin.JSON(200, secret.ToDto())
+}
+
+// @Summary Delete a secret
+// @Description Delete a secret by its name
+// @Tags secrets
+// @Param name path string true "the secrets name"
+// @Produce json
+// @Success 204
+// @Router /secrets/{name} [delete]
+func (c *SecretController) deleteSecret(gin *gin.Context) {
+	err := service.SecretService().DeleteSecret(gin.Param("name"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/secrets#deleting-a-secret"))
+		return
+	}
+
+	gin.AbortWithStatus(204)
+}
+
+// @Summary List all secrets
+// @Description List all existing secrets without their values
+// @Tags secrets
+// @Produce json
+// @Success 200 {object} []dto.Secret
+// @Router /secrets [get]
+func (c *SecretController) listSecrets(gin *gin.Context) {
+	secrets, total, err := service.SecretService().ListSecrets(gin.GetInt("page"), gin.GetInt("perPage"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/secrets#listing-all-secrets"))
+		return
+	}
+
+	gin.Header("X-Total", fmt.Sprintf("%d", total))
+
+	var secretDTOs = []dto.Secret{}
+	for _, secret := range *secrets {
+		secretDTOs = append(secretDTOs, *secret.ToDto())
+	}
+
+	gin.JSON(200, secretDTOs)
+}
+
+// @Summary Update a secret
+// @Description Update the value and description of a secret
+// @Tags secrets
+// @Accept json
+// @Param name path string true "the secrets name"
+// @Param request body dto.NewSecret true "updated secret"
+// @Produce json
+// @Success 200 {object} dto.Secret
+// @Router /secrets/{name} [put]
+func (c *SecretController) updateSecret(gin *gin.Context) {
+	newSecret := &dto.NewSecret{}
+	if !c.sev.Validate().Bind(gin, newSecret) {
+		return
+	}
+
+	secret, err := service.SecretService().UpdateSecret(gin.Param("name"), newSecret)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/secrets#updating-a-secret"))
+		return
+	}
+
+	gin.JSON(200, secret.ToDto())
+}
+
+// @Summary Add a new secret
+// @Description Add a new secret, presets and tasks that list it in their secrets reference it as ${SECRET_<name>} in their commands and scripts
+// @Tags secrets
+// @Accept json
+// @Param request body dto.NewSecret true "new secret"
+// @Produce json
+// @Success 200 {object} dto.Secret
+// @Router /secrets [post]
+func (c *SecretController) addSecret(gin *gin.Context) {
+	newSecret := &dto.NewSecret{}
+	if !c.sev.Validate().Bind(gin, newSecret) {
+		return
+	}
+
+	secret, err := service.SecretService().NewSecret(newSecret)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/secrets#creating-a-secret"))
+		return
+	}
+
+	gin.JSON(200, secret.ToDto())
+}
+
+func (c *SecretController) GetName() string {
+	return "secret"
+}
+
+func (c *SecretController) getEndpoint() string {
+	return "/v1/secrets"
+}
@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestSecretController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, s := setupTestDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get underlying database: %v", err)
+	}
+	defer sqlDB.Close()
+
+	(&SecretController{Prefix: ""}).Setup(s)
+	(&WildcardController{Prefix: ""}).Setup(s)
+
+	request := func(method string, url string, body any) *httptest.ResponseRecorder {
+		var b []byte
+		if body != nil {
+			b, _ = json.Marshal(body)
+		}
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, url, bytes.NewBuffer(b))
+		req.Header.Set("Content-Type", "application/json")
+		s.Gin().ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Create secret", func(t *testing.T) {
+		w := request("POST", "/v1/secrets", dto.NewSecret{Name: "s3_key", Value: "AKIA-very-secret", Description: "upload key"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if strings.Contains(w.Body.String(), "AKIA-very-secret") {
+			t.Errorf("Response contains the secret value: %s", w.Body.String())
+		}
+
+		if w := request("POST", "/v1/secrets", dto.NewSecret{Name: "s3_key", Value: "other"}); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected duplicate secret to fail, got %d", w.Code)
+		}
+		if w := request("POST", "/v1/secrets", dto.NewSecret{Name: "s3 key", Value: "other"}); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected invalid name to fail, got %d", w.Code)
+		}
+	})
+
+	t.Run("List secrets", func(t *testing.T) {
+		w := request("GET", "/v1/secrets", nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, w.Code)
+		}
+		var secrets []dto.Secret
+		json.Unmarshal(w.Body.Bytes(), &secrets)
+		if len(secrets) != 1 || secrets[0].Name != "s3_key" || secrets[0].Description != "upload key" {
+			t.Errorf("Unexpected secrets %+v", secrets)
+		}
+		if w.Header().Get("X-Total") != "1" || strings.Contains(w.Body.String(), "AKIA-very-secret") {
+			t.Errorf("Unexpected response %s", w.Body.String())
+		}
+	})
+
+	t.Run("Update secret", func(t *testing.T) {
+		if w := request("PUT", "/v1/secrets/s3_key", dto.NewSecret{Name: "s3_key", Value: "AKIA-rotated"}); w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if w := request("PUT", "/v1/secrets/s3_key", dto.NewSecret{Name: "renamed", Value: "x"}); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected rename to fail, got %d", w.Code)
+		}
+	})
+
+	t.Run("Preview masks secret", func(t *testing.T) {
+		w := request("POST", "/v1/wildcards/preview", dto.WildcardPreview{Command: "-i ${INPUT_FILE} ${OUTPUT_FILE} | upload --key ${SECRET_s3_key}", InputFile: "in.mp4", OutputFile: "out.mp4", Secrets: dto.SecretReferences{"s3_key"}})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result dto.WildcardPreviewResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		if !strings.HasSuffix(result.Command, "--key ********") || strings.Contains(w.Body.String(), "AKIA") || len(result.Unknown) != 0 {
+			t.Errorf("Unexpected preview %+v", result)
+		}
+	})
+
+	t.Run("Preview does not resolve unreferenced secret", func(t *testing.T) {
+		w := request("POST", "/v1/wildcards/preview", dto.WildcardPreview{Command: "-i ${INPUT_FILE} ${OUTPUT_FILE} | upload --key ${SECRET_s3_key}", InputFile: "in.mp4", OutputFile: "out.mp4"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		var result dto.WildcardPreviewResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		if !strings.HasSuffix(result.Command, "--key ${SECRET_s3_key}") || len(result.Unknown) != 1 {
+			t.Errorf("Unexpected preview %+v", result)
+		}
+	})
+
+	t.Run("Delete secret", func(t *testing.T) {
+		if w := request("DELETE", "/v1/secrets/s3_key", nil); w.Code != http.StatusNoContent {
+			t.Fatalf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+		}
+		if w := request("GET", "/v1/secrets/s3_key", nil); w.Code != http.StatusBadRequest {
+			t.Errorf("Expected deleted secret to be gone, got %d", w.Code)
+		}
+	})
+}
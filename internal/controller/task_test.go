@@ -116,9 +116,7 @@ func TestTaskController(t *testing.T) {
 		s.Gin().ServeHTTP(w, req)
 
 		// Wait for both batch created and task created events
-		waitForWebhook(t, webhookCalls, dto.TASK_CREATED) // First task
-		waitForWebhook(t, webhookCalls, dto.TASK_CREATED) // Second task
-		waitForWebhook(t, webhookCalls, dto.BATCH_CREATED)
+		waitForWebhooks(t, webhookCalls, dto.TASK_CREATED, dto.TASK_CREATED, dto.BATCH_CREATED)
 
 		if w.Code != http.StatusOK {
 			t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
@@ -25,6 +25,10 @@ func (c *WatchfolderController) Setup(s *sev.Sev) {
 	s.Gin().POST(c.Prefix+c.getEndpoint(), c.addWatchfolder)
 	s.Gin().GET(c.Prefix+c.getEndpoint(), interceptor.PageLimit, c.listWatchfolders)
 	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid", c.getWatchfolder)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid/files", interceptor.PageLimit, c.listWatchfolderFiles)
+	s.Gin().DELETE(c.Prefix+c.getEndpoint()+"/:uuid/files", c.resetWatchfolderFiles)
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/:uuid/dry-run", c.dryRunWatchfolder)
+	s.Gin().GET(c.Prefix+c.getEndpoint()+"/:uuid/stats", c.getWatchfolderStats)
 }
 
 // @Summary Get single watchfolder
@@ -45,6 +49,24 @@ func (c *WatchfolderController) getWatchfolder(gin *gin.Context) {
 	gin.JSON(200, watchfolder.ToDto())
 }
 
+// @Summary Get the stats of a watchfolder
+// @Description Get the health, counters and recent errors and task creations of a watchfolder
+// @Tags watchfolders
+// @Param uuid path string true "the watchfolders uuid"
+// @Produce json
+// @Success 200 {object} dto.WatchfolderStats
+// @Router /watchfolders/{uuid}/stats [get]
+func (c *WatchfolderController) getWatchfolderStats(gin *gin.Context) {
+	uuid := gin.Param("uuid")
+	stats, err := service.WatchfolderService().GetWatchfolderStats(uuid)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/watchfolder#watchfolder-stats"))
+		return
+	}
+
+	gin.JSON(200, stats)
+}
+
 // @Summary Delete a watchfolder
 // @Description Delete a watchfolder by its uuid
 // @Tags watchfolders
@@ -135,6 +157,74 @@ func (c *WatchfolderController) updateWatchfolder(gin *gin.Context) {
 	gin.JSON(200, watchfolder.ToDto())
 }
 
+// @Summary List processed files of a watchfolder
+// @Description List the files a watchfolder already created tasks for
+// @Tags watchfolders
+// @Param uuid path string true "the watchfolders uuid"
+// @Produce json
+// @Success 200 {object} []dto.WatchfolderFile
+// @Router /watchfolders/{uuid}/files [get]
+func (c *WatchfolderController) listWatchfolderFiles(gin *gin.Context) {
+	uuid := gin.Param("uuid")
+	files, total, err := service.WatchfolderService().ListWatchfolderFiles(uuid, gin.GetInt("page"), gin.GetInt("perPage"))
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/watchfolder#listing-processed-files"))
+		return
+	}
+
+	gin.Header("X-Total", fmt.Sprintf("%d", total))
+
+	var fileDTOs = []dto.WatchfolderFile{}
+	for _, file := range *files {
+		fileDTOs = append(fileDTOs, *file.ToDto())
+	}
+
+	gin.JSON(200, fileDTOs)
+}
+
+// @Summary Reset processed files of a watchfolder
+// @Description Forget all processed files of a watchfolder so they are processed again
+// @Tags watchfolders
+// @Param uuid path string true "the watchfolders uuid"
+// @Produce json
+// @Success 204
+// @Router /watchfolders/{uuid}/files [delete]
+func (c *WatchfolderController) resetWatchfolderFiles(gin *gin.Context) {
+	uuid := gin.Param("uuid")
+	_, err := service.WatchfolderService().ResetWatchfolderFiles(uuid)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/watchfolder#resetting-processed-files"))
+		return
+	}
+
+	gin.AbortWithStatus(204)
+}
+
+// @Summary Dry-run the rules of a watchfolder
+// @Description Show which rule a file would hit and which presets, priority and metadata its tasks would get, no tasks are created
+// @Tags watchfolders
+// @Param uuid path string true "the watchfolders uuid"
+// @Accept json
+// @Param request body dto.WatchfolderDryRun true "the file to route"
+// @Produce json
+// @Success 200 {object} dto.WatchfolderRoute
+// @Router /watchfolders/{uuid}/dry-run [post]
+func (c *WatchfolderController) dryRunWatchfolder(gin *gin.Context) {
+	uuid := gin.Param("uuid")
+	dryRun := &dto.WatchfolderDryRun{}
+	if !c.sev.Validate().Bind(gin, dryRun) {
+		return
+	}
+
+	route, err := service.WatchfolderService().DryRunWatchfolder(uuid, dryRun.Path)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/watchfolder#dry-running-rules"))
+		return
+	}
+
+	gin.JSON(200, route)
+}
+
 func (c *WatchfolderController) GetName() string {
 	return "watchfolder"
 }
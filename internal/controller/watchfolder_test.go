@@ -23,7 +23,7 @@ func setupWatchfolderTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Watchfolder{}, &model.Preset{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Watchfolder{}, &model.WatchfolderFile{}, &model.Preset{}, &model.Webhook{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -153,6 +153,56 @@ func TestWatchfolderController(t *testing.T) {
 			}
 		})
 
+		t.Run("Get watchfolder stats", func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/v1/watchfolders/"+firstWatchfolder.Uuid+"/stats", nil)
+			s.Gin().ServeHTTP(w, req)
+
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			var stats dto.WatchfolderStats
+			json.Unmarshal(w.Body.Bytes(), &stats)
+			if stats.Health != dto.WATCHFOLDER_HEALTH_OK || stats.History == nil {
+				t.Errorf("Expected a healthy watchfolder with an empty history, got %+v", stats)
+			}
+		})
+
+		t.Run("List and reset processed files", func(t *testing.T) {
+			_, err := service.WatchfolderService().SaveWatchfolderFile(&model.WatchfolderFile{Watchfolder: firstWatchfolder.Uuid, Path: "/test/watch/movie.mp4", Size: 42})
+			if err != nil {
+				t.Fatalf("Failed to save processed file: %v", err)
+			}
+
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/v1/watchfolders/"+firstWatchfolder.Uuid+"/files", nil)
+			s.Gin().ServeHTTP(w, req)
+			if w.Code != http.StatusOK {
+				t.Errorf("Expected status %d, got %d", http.StatusOK, w.Code)
+			}
+			var files []dto.WatchfolderFile
+			if err := json.Unmarshal(w.Body.Bytes(), &files); err != nil {
+				t.Fatalf("Failed to unmarshal response: %v", err)
+			}
+			if len(files) != 1 || files[0].Path != "/test/watch/movie.mp4" || files[0].Size != 42 {
+				t.Errorf("Unexpected processed files: %+v", files)
+			}
+
+			w = httptest.NewRecorder()
+			req = httptest.NewRequest("DELETE", "/v1/watchfolders/"+firstWatchfolder.Uuid+"/files", nil)
+			s.Gin().ServeHTTP(w, req)
+			if w.Code != http.StatusNoContent {
+				t.Errorf("Expected status %d, got %d", http.StatusNoContent, w.Code)
+			}
+
+			w = httptest.NewRecorder()
+			req = httptest.NewRequest("GET", "/v1/watchfolders/"+firstWatchfolder.Uuid+"/files", nil)
+			s.Gin().ServeHTTP(w, req)
+			if w.Header().Get("X-Total") != "0" {
+				t.Errorf("Expected no processed files after reset, got %s", w.Header().Get("X-Total"))
+			}
+		})
+
 		t.Run("Delete watchfolder", func(t *testing.T) {
 			w := httptest.NewRecorder()
 			req := httptest.NewRequest("DELETE", "/v1/watchfolders/"+firstWatchfolder.Uuid, nil)
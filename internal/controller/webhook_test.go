@@ -49,6 +49,26 @@ func waitForWebhook(t *testing.T, calls chan WebhookCall, expectedEvent dto.Webh
 	}
 }
 
+// waitForWebhooks waits for all expected events in any order, as webhooks are delivered concurrently
+func waitForWebhooks(t *testing.T, calls chan WebhookCall, expectedEvents ...dto.WebhookEvent) {
+	pending := map[dto.WebhookEvent]int{}
+	for _, event := range expectedEvents {
+		pending[event]++
+	}
+	for range expectedEvents {
+		select {
+		case call := <-calls:
+			if pending[call.event] == 0 {
+				t.Errorf("Unexpected webhook event %s, expected %v", call.event, expectedEvents)
+			}
+			pending[call.event]--
+		case <-time.After(time.Second):
+			t.Errorf("Timeout waiting for webhook events %v", expectedEvents)
+			return
+		}
+	}
+}
+
 func setupWebhookTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 	db, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
 	if err != nil {
@@ -0,0 +1,51 @@
+package controller
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"github.com/welovemedia/ffmate/sev/exceptions"
+)
+
+type WildcardController struct {
+	sev.Controller
+	sev    *sev.Sev
+	Prefix string
+}
+
+func (c *WildcardController) Setup(s *sev.Sev) {
+	c.sev = s
+	s.Gin().POST(c.Prefix+c.getEndpoint()+"/preview", c.previewWildcards)
+}
+
+// @Summary Preview wildcards
+// @Description Resolve the wildcards of a command and output file like a task would and return the resolved command and its arguments, nothing is queued
+// @Tags wildcards
+// @Accept json
+// @Param request body dto.WildcardPreview true "templates to resolve"
+// @Produce json
+// @Success 200 {object} dto.WildcardPreviewResult
+// @Router /wildcards/preview [post]
+func (c *WildcardController) previewWildcards(gin *gin.Context) {
+	preview := &dto.WildcardPreview{}
+	if !c.sev.Validate().Bind(gin, preview) {
+		return
+	}
+
+	result, err := service.WildcardService().Preview(preview)
+	if err != nil {
+		gin.JSON(400, exceptions.HttpBadRequest(err, "https://docs.ffmate.io/docs/wildcards#previewing-wildcards"))
+		return
+	}
+
+	gin.JSON(200, result)
+}
+
+func (c *WildcardController) GetName() string {
+	return "wildcard"
+}
+
+func (c *WildcardController) getEndpoint() string {
+	return "/v1/wildcards"
+}
@@ -0,0 +1,105 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+)
+
+func TestWildcardController(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	db, s := setupTestDB(t)
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get underlying database: %v", err)
+	}
+	defer sqlDB.Close()
+
+	(&WildcardController{Prefix: ""}).Setup(s)
+	(&PresetController{Prefix: ""}).Setup(s)
+
+	preview := func(t *testing.T, url string, body dto.WildcardPreview) (int, dto.WildcardPreviewResult) {
+		b, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", url, bytes.NewBuffer(b))
+		req.Header.Set("Content-Type", "application/json")
+		s.Gin().ServeHTTP(w, req)
+
+		var result dto.WildcardPreviewResult
+		json.Unmarshal(w.Body.Bytes(), &result)
+		return w.Code, result
+	}
+
+	t.Run("Preview wildcards", func(t *testing.T) {
+		code, result := preview(t, "/v1/wildcards/preview", dto.WildcardPreview{
+			Command:    "-i ${INPUT_FILE} -c:v ${METADATA_codec:-libx264} -metadata title=${METADATA_title} ${CUSTOM} ${OUTPUT_FILE} | tee ${OUTPUT_FILE_DIR}/log.txt",
+			InputFile:  "/in/my movie.mp4",
+			OutputFile: "/out/${INPUT_FILE_BASENAME | sanitize}.mkv",
+			Metadata:   &dto.InterfaceMap{"title": ""},
+		})
+		if code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+		}
+		if result.OutputFile != "/out/my_movie.mkv" {
+			t.Errorf("Unexpected output file %s", result.OutputFile)
+		}
+		if len(result.Args) != 2 || !slices.Contains(result.Args[0], "/in/my movie.mp4") || !slices.Contains(result.Args[0], "libx264") || result.Args[1][0] != "tee" {
+			t.Errorf("Unexpected args %q", result.Args)
+		}
+		if !slices.Equal(result.Unknown, []string{"CUSTOM"}) || !slices.Equal(result.Empty, []string{"METADATA_title"}) {
+			t.Errorf("Unexpected unknown %v and empty %v wildcards", result.Unknown, result.Empty)
+		}
+	})
+
+	t.Run("Preview invalid template", func(t *testing.T) {
+		code, _ := preview(t, "/v1/wildcards/preview", dto.WildcardPreview{Command: "-i ${INPUT_FILE | nope}"})
+		if code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, code)
+		}
+	})
+
+	t.Run("Preview task variables", func(t *testing.T) {
+		code, result := preview(t, "/v1/wildcards/preview", dto.WildcardPreview{
+			Command:    "-i ${INPUT_FILE} ${ENCODER_ARGS} ${OUTPUT_FILE_TEMP}",
+			InputFile:  "/in/clip.mov",
+			OutputFile: "/out/clip.mp4",
+		})
+		if code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+		}
+		if result.Command != `-i "/in/clip.mov"  "/out/clip.mp4"` || len(result.Unknown) != 0 {
+			t.Errorf("Unexpected preview %+v", result)
+		}
+	})
+
+	t.Run("Preview strict wildcards", func(t *testing.T) {
+		config.Config().StrictWildcards = true
+		defer func() { config.Config().StrictWildcards = false }()
+		code, _ := preview(t, "/v1/wildcards/preview", dto.WildcardPreview{Command: "-i ${INPUT_FILE} ${CUSTOM} ${OUTPUT_FILE}"})
+		if code != http.StatusBadRequest {
+			t.Errorf("Expected status %d, got %d", http.StatusBadRequest, code)
+		}
+	})
+
+	t.Run("Preview preset", func(t *testing.T) {
+		preset, err := service.PresetService().NewPreset(&dto.NewPreset{Name: "Preview", Command: "-i ${INPUT_FILE} ${OUTPUT_FILE}", OutputFile: "/out/${INPUT_FILE_BASENAME}.mp4"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		code, result := preview(t, "/v1/presets/"+preset.Uuid+"/preview", dto.WildcardPreview{InputFile: "/in/clip.mov"})
+		if code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d", http.StatusOK, code)
+		}
+		if result.OutputFile != "/out/clip.mp4" || result.Command != `-i "/in/clip.mov" "/out/clip.mp4"` {
+			t.Errorf("Unexpected preview %+v", result)
+		}
+	})
+}
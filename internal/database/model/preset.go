@@ -19,14 +19,29 @@ type Preset struct {
 	Command string
 	Name    string
 
+	Executor dto.Executor
+	Encoder  *dto.EncoderSettings `gorm:"type:json"`
+
+	QualitySearch *dto.NewQualitySearch `gorm:"type:json"`
+	Chunking      *dto.NewChunking      `gorm:"type:json"`
+	Verification  *dto.NewVerification  `gorm:"type:json"`
+
+	Secrets dto.SecretReferences `gorm:"type:json"`
+
 	OutputFile string
 
+	AtomicOutput bool
+	OutputPolicy dto.OutputPolicy
+
 	Priority uint
 
 	PreProcessing  *dto.NewPrePostProcessing `gorm:"type:json"`
 	PostProcessing *dto.NewPrePostProcessing `gorm:"type:json"`
 
 	Description string
+
+	GlobalPresetName    string // name of the library preset it was installed from
+	GlobalPresetVersion int
 }
 
 func (m *Preset) ToDto() *dto.Preset {
@@ -37,13 +52,28 @@ func (m *Preset) ToDto() *dto.Preset {
 		Name:        m.Name,
 		Description: m.Description,
 
+		Executor: m.Executor,
+		Encoder:  m.Encoder,
+
+		QualitySearch: m.QualitySearch,
+		Chunking:      m.Chunking,
+		Verification:  m.Verification,
+
+		Secrets: m.Secrets,
+
 		OutputFile: m.OutputFile,
 
+		AtomicOutput: m.AtomicOutput,
+		OutputPolicy: m.OutputPolicy,
+
 		Priority: m.Priority,
 
 		PreProcessing:  m.PreProcessing,
 		PostProcessing: m.PostProcessing,
 
+		GlobalPresetName:    m.GlobalPresetName,
+		GlobalPresetVersion: m.GlobalPresetVersion,
+
 		CreatedAt: m.CreatedAt,
 		UpdatedAt: m.UpdatedAt,
 	}
@@ -0,0 +1,35 @@
+package model
+
+import (
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+	"gorm.io/gorm"
+)
+
+type Secret struct {
+	ID uint `gorm:"primarykey"`
+
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+
+	Name        string `gorm:"index"`
+	Value       string
+	Description string
+}
+
+// ToDto never contains the value of the secret
+func (m *Secret) ToDto() *dto.Secret {
+	return &dto.Secret{
+		Name:        m.Name,
+		Description: m.Description,
+
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func (Secret) TableName() string {
+	return "secret"
+}
@@ -21,8 +21,26 @@ type Task struct {
 	InputFile  *dto.RawResolved `gorm:"type:json"`
 	OutputFile *dto.RawResolved `gorm:"type:json"`
 
+	AtomicOutput   bool
+	OutputPolicy   dto.OutputPolicy
+	TempOutputFile string
+
+	Workdir string
+
 	Metadata *dto.InterfaceMap `gorm:"serializer:json"` // Additional metadata for the task
 
+	Executor dto.Executor
+	Encoder  *dto.EncoderSettings `gorm:"type:json"`
+
+	QualitySearch *dto.QualitySearch `gorm:"type:json"`
+	Chunking      *dto.Chunking      `gorm:"type:json"`
+	Verification  *dto.Verification  `gorm:"type:json"`
+
+	Secrets dto.SecretReferences `gorm:"type:json"`
+
+	Parent string     `gorm:"index"`
+	Chunk  *dto.Chunk `gorm:"type:json"`
+
 	Status    dto.TaskStatus `gorm:"index"`
 	Error     string
 	Progress  float64
@@ -52,8 +70,26 @@ func (m *Task) ToDto() *dto.Task {
 		InputFile:  m.InputFile,
 		OutputFile: m.OutputFile,
 
+		AtomicOutput:   m.AtomicOutput,
+		OutputPolicy:   m.OutputPolicy,
+		TempOutputFile: m.TempOutputFile,
+
+		Workdir: m.Workdir,
+
 		Metadata: m.Metadata,
 
+		Executor: m.Executor,
+		Encoder:  m.Encoder,
+
+		QualitySearch: m.QualitySearch,
+		Chunking:      m.Chunking,
+		Verification:  m.Verification,
+
+		Secrets: m.Secrets,
+
+		Parent: m.Parent,
+		Chunk:  m.Chunk,
+
 		Status:    m.Status,
 		Progress:  m.Progress,
 		Remaining: m.Remaining,
@@ -21,11 +21,35 @@ type Watchfolder struct {
 	Interval     int
 	GrowthChecks int
 
+	Mode              dto.WatchfolderMode
+	ReconcileInterval int
+
+	Unit dto.WatchfolderUnit
+
+	HashFiles bool
+
+	OnSuccess *dto.WatchfolderAction `gorm:"type:json"`
+	OnError   *dto.WatchfolderAction `gorm:"type:json"`
+
 	Filter *dto.WatchfolderFilter
 
-	Preset string
+	Preset  string
+	Presets dto.WatchfolderPresets `gorm:"type:json"`
+
+	Rules dto.WatchfolderRules `gorm:"type:json"`
+
+	Suspended    bool
+	SuspendAfter int
+
+	Health            dto.WatchfolderHealth
+	ConsecutiveErrors int
 
-	Suspended bool
+	FilesSeen    int64
+	TasksCreated int64
+	LastTask     string
+	LastTaskAt   int64
+
+	History *dto.WatchfolderHistory `gorm:"type:json"`
 
 	Error     string
 	LastCheck int64
@@ -42,11 +66,28 @@ func (m *Watchfolder) ToDto() *dto.Watchfolder {
 		Interval:     m.Interval,
 		GrowthChecks: m.GrowthChecks,
 
-		Preset: m.Preset,
+		Mode:              m.Mode,
+		ReconcileInterval: m.ReconcileInterval,
+
+		Unit: m.Unit,
+
+		HashFiles: m.HashFiles,
+
+		OnSuccess: m.OnSuccess,
+		OnError:   m.OnError,
+
+		Preset:  m.Preset,
+		Presets: m.Presets,
+
+		Rules: m.Rules,
 
 		Filter: m.Filter,
 
-		Suspended: m.Suspended,
+		Suspended:    m.Suspended,
+		SuspendAfter: m.SuspendAfter,
+
+		Health:            m.health(),
+		ConsecutiveErrors: m.ConsecutiveErrors,
 
 		CreatedAt: m.CreatedAt,
 		UpdatedAt: m.UpdatedAt,
@@ -56,6 +97,43 @@ func (m *Watchfolder) ToDto() *dto.Watchfolder {
 	}
 }
 
+func (m *Watchfolder) ToStatsDto() *dto.WatchfolderStats {
+	history := m.History
+	if history == nil {
+		history = &dto.WatchfolderHistory{}
+	}
+	return &dto.WatchfolderStats{
+		Health:            m.health(),
+		ConsecutiveErrors: m.ConsecutiveErrors,
+
+		FilesSeen:    m.FilesSeen,
+		TasksCreated: m.TasksCreated,
+		LastTask:     m.LastTask,
+		LastTaskAt:   m.LastTaskAt,
+
+		History: history,
+	}
+}
+
+// health defaults to ok for watchfolders that were never checked
+func (m *Watchfolder) health() dto.WatchfolderHealth {
+	if m.Health == "" {
+		return dto.WATCHFOLDER_HEALTH_OK
+	}
+	return m.Health
+}
+
+// AllPresets returns the presets tasks are created with
+func (m *Watchfolder) AllPresets() []string {
+	if len(m.Presets) > 0 {
+		return m.Presets
+	}
+	if m.Preset != "" {
+		return []string{m.Preset}
+	}
+	return nil
+}
+
 func (Watchfolder) TableName() string {
 	return "watchfolder"
 }
@@ -0,0 +1,42 @@
+package model
+
+import "github.com/welovemedia/ffmate/internal/dto"
+
+// WatchfolderFile records a file a watchfolder created a task for, so it is not processed again after a restart
+type WatchfolderFile struct {
+	ID uint `gorm:"primarykey"`
+
+	CreatedAt int64 `gorm:"autoCreateTime:milli"`
+	UpdatedAt int64 `gorm:"autoUpdateTime:milli"`
+
+	Watchfolder string `gorm:"index"`
+	Path        string
+
+	Size    int64
+	ModTime int64
+	Hash    string
+
+	Task  string
+	Batch string
+}
+
+func (m *WatchfolderFile) ToDto() *dto.WatchfolderFile {
+	return &dto.WatchfolderFile{
+		Watchfolder: m.Watchfolder,
+		Path:        m.Path,
+
+		Size:    m.Size,
+		ModTime: m.ModTime,
+		Hash:    m.Hash,
+
+		Task:  m.Task,
+		Batch: m.Batch,
+
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
+
+func (WatchfolderFile) TableName() string {
+	return "watchfolder_file"
+}
@@ -1,7 +1,6 @@
 package repository
 
 import (
-	"github.com/google/uuid"
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/dto"
 	"gorm.io/gorm"
@@ -23,8 +22,15 @@ func (m *Preset) List(page int, perPage int) (*[]model.Preset, int64, error) {
 }
 
 func (m *Preset) Update(w *model.Preset) error {
-	m.DB.Save(w)
-	return m.DB.Error
+	db := m.DB.Save(w)
+	return db.Error
+}
+
+// Restore undeletes the soft-deleted preset and saves its settings
+func (m *Preset) Restore(w *model.Preset) error {
+	w.DeletedAt = gorm.DeletedAt{}
+	db := m.DB.Unscoped().Save(w)
+	return db.Error
 }
 
 func (m *Preset) Delete(w *model.Preset) error {
@@ -32,16 +38,27 @@ func (m *Preset) Delete(w *model.Preset) error {
 	return m.DB.Error
 }
 
-func (m *Preset) Create(newPreset *dto.NewPreset) (*model.Preset, error) {
+func (m *Preset) Create(newPreset *dto.NewPreset, uuid string) (*model.Preset, error) {
 	preset := &model.Preset{
-		Uuid:           uuid.NewString(),
+		Uuid:           uuid,
 		Command:        newPreset.Command,
+		Executor:       newPreset.Executor,
+		Encoder:        newPreset.Encoder,
+		QualitySearch:  newPreset.QualitySearch,
+		Chunking:       newPreset.Chunking,
+		Verification:   newPreset.Verification,
+		Secrets:        newPreset.Secrets,
 		Name:           newPreset.Name,
 		Description:    newPreset.Description,
 		Priority:       newPreset.Priority,
 		OutputFile:     newPreset.OutputFile,
+		AtomicOutput:   newPreset.AtomicOutput,
+		OutputPolicy:   newPreset.OutputPolicy,
 		PreProcessing:  newPreset.PreProcessing,
 		PostProcessing: newPreset.PostProcessing,
+
+		GlobalPresetName:    newPreset.GlobalPresetName,
+		GlobalPresetVersion: newPreset.GlobalPresetVersion,
 	}
 	db := m.DB.Create(preset)
 	return preset, db.Error
@@ -59,6 +76,13 @@ func (m *Preset) FindByUuid(uuid string) (*model.Preset, error) {
 	return preset, db.Error
 }
 
+// FindByUuidUnscoped returns the preset with the given uuid, including soft-deleted ones
+func (m *Preset) FindByUuidUnscoped(uuid string) (*model.Preset, error) {
+	var preset *model.Preset
+	db := m.DB.Unscoped().Where("uuid", uuid).Find(&preset)
+	return preset, db.Error
+}
+
 func (m *Preset) Count() (int64, error) {
 	var count int64
 	db := m.DB.Model(&model.Preset{}).Count(&count)
@@ -70,3 +94,16 @@ func (m *Preset) CountDeleted() (int64, error) {
 	db := m.DB.Unscoped().Model(&model.Preset{}).Where("deleted_at IS NOT NULL").Count(&count)
 	return count, db.Error
 }
+
+func (m *Preset) All() (*[]model.Preset, error) {
+	var presets = &[]model.Preset{}
+	db := m.DB.Order("created_at ASC").Find(&presets)
+	return presets, db.Error
+}
+
+// ByGlobalPresetName returns the presets installed from the library preset
+func (m *Preset) ByGlobalPresetName(name string) (*[]model.Preset, error) {
+	var presets = &[]model.Preset{}
+	db := m.DB.Where("global_preset_name = ?", name).Order("created_at ASC").Find(&presets)
+	return presets, db.Error
+}
@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"gorm.io/gorm"
+)
+
+type Secret struct {
+	DB *gorm.DB
+}
+
+func (t *Secret) Setup() {
+	t.DB.AutoMigrate(&model.Secret{})
+}
+
+func (m *Secret) List(page int, perPage int) (*[]model.Secret, int64, error) {
+	total, _ := m.Count()
+	var secrets = &[]model.Secret{}
+	db := m.DB.Order("name ASC").Limit(perPage).Offset(page * perPage).Find(&secrets)
+	return secrets, total, db.Error
+}
+
+// All returns every secret including its value
+func (m *Secret) All() (*[]model.Secret, error) {
+	var secrets = &[]model.Secret{}
+	db := m.DB.Find(&secrets)
+	return secrets, db.Error
+}
+
+func (m *Secret) First(name string) (*model.Secret, error) {
+	var secret = &model.Secret{}
+	err := m.DB.Where("name = ?", name).First(&secret).Error
+	if err != nil {
+		return nil, err
+	}
+	return secret, nil
+}
+
+func (m *Secret) Count() (int64, error) {
+	var count int64
+	db := m.DB.Model(&model.Secret{}).Count(&count)
+	return count, db.Error
+}
+
+func (m *Secret) Update(s *model.Secret) (*model.Secret, error) {
+	db := m.DB.Save(s)
+	return s, db.Error
+}
+
+// Delete removes the secret permanently so its value does not stay in the database
+func (m *Secret) Delete(s *model.Secret) error {
+	return m.DB.Unscoped().Delete(s).Error
+}
+
+func (m *Secret) Create(name string, value string, description string) (*model.Secret, error) {
+	secret := &model.Secret{Name: name, Value: value, Description: description}
+	db := m.DB.Create(secret)
+	return secret, db.Error
+}
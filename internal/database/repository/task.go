@@ -46,8 +46,8 @@ func (m *Task) CountAllStatus(session string) (queued, running, doneSuccessful,
 		switch r.Status {
 		case "QUEUED":
 			queued = r.Count
-		case "RUNNING", "PRE_PROCESSING", "POST_PROCESSING":
-			running = r.Count
+		case "RUNNING", "PRE_PROCESSING", "DOWNLOADING", "QUALITY_SEARCH", "CHUNKING", "VERIFYING", "UPLOADING", "POST_PROCESSING":
+			running += r.Count
 		case "DONE_SUCCESSFUL":
 			doneSuccessful = r.Count
 		case "DONE_ERROR":
@@ -75,37 +75,71 @@ func (m *Task) List(page int, perPage int, status string) (*[]model.Task, int64,
 }
 
 func (m *Task) Create(newTask *dto.NewTask, batch string, source string, session string) (*model.Task, error) {
+	var priority uint
+	if newTask.Priority != nil {
+		priority = *newTask.Priority
+	}
 	task := &model.Task{
-		Uuid:       uuid.NewString(),
-		Command:    &dto.RawResolved{Raw: newTask.Command},
-		InputFile:  &dto.RawResolved{Raw: newTask.InputFile},
-		OutputFile: &dto.RawResolved{Raw: newTask.OutputFile},
-		Metadata:   newTask.Metadata, // Ensure Metadata is not nil
-		Name:       newTask.Name,
-		Priority:   newTask.Priority,
-		Progress:   0,
-		Source:     source,
-		Status:     dto.QUEUED,
-		Batch:      batch,
-		Session:    session,
+		Uuid:         uuid.NewString(),
+		Command:      &dto.RawResolved{Raw: newTask.Command},
+		InputFile:    &dto.RawResolved{Raw: newTask.InputFile},
+		OutputFile:   &dto.RawResolved{Raw: newTask.OutputFile},
+		AtomicOutput: newTask.AtomicOutput,
+		OutputPolicy: newTask.OutputPolicy,
+		Metadata:     newTask.Metadata, // Ensure Metadata is not nil
+		Executor:     newTask.Executor,
+		Encoder:      newTask.Encoder,
+		Name:         newTask.Name,
+		Priority:     priority,
+		Progress:     0,
+		Source:       source,
+		Status:       dto.QUEUED,
+		Batch:        batch,
+		Session:      session,
+		Parent:       newTask.Parent,
+		Chunk:        newTask.Chunk,
+		Secrets:      newTask.Secrets,
+	}
+	if newTask.QualitySearch != nil {
+		task.QualitySearch = &dto.QualitySearch{NewQualitySearch: *newTask.QualitySearch}
+	}
+	if newTask.Chunking != nil {
+		task.Chunking = &dto.Chunking{NewChunking: *newTask.Chunking}
+	}
+	if newTask.Verification != nil {
+		task.Verification = &dto.Verification{NewVerification: *newTask.Verification}
 	}
 	if newTask.PreProcessing != nil {
 		task.PreProcessing = &dto.PrePostProcessing{
 			ScriptPath:    &dto.RawResolved{Raw: newTask.PreProcessing.ScriptPath},
 			SidecarPath:   &dto.RawResolved{Raw: newTask.PreProcessing.SidecarPath},
 			ImportSidecar: newTask.PreProcessing.ImportSidecar,
+			ImportOutput:  newTask.PreProcessing.ImportOutput,
+			Timeout:       newTask.PreProcessing.Timeout,
+			Actions:       newActions(newTask.PreProcessing.Actions),
 		}
 	}
 	if newTask.PostProcessing != nil {
 		task.PostProcessing = &dto.PrePostProcessing{
-			ScriptPath:  &dto.RawResolved{Raw: newTask.PostProcessing.ScriptPath},
-			SidecarPath: &dto.RawResolved{Raw: newTask.PostProcessing.SidecarPath},
+			ScriptPath:   &dto.RawResolved{Raw: newTask.PostProcessing.ScriptPath},
+			SidecarPath:  &dto.RawResolved{Raw: newTask.PostProcessing.SidecarPath},
+			ImportOutput: newTask.PostProcessing.ImportOutput,
+			Timeout:      newTask.PostProcessing.Timeout,
+			Actions:      newActions(newTask.PostProcessing.Actions),
 		}
 	}
 	db := m.DB.Create(task)
 	return task, db.Error
 }
 
+func newActions(actions []dto.NewAction) []dto.Action {
+	var a []dto.Action
+	for _, action := range actions {
+		a = append(a, dto.Action{NewAction: action})
+	}
+	return a
+}
+
 func (m *Task) Delete(w *model.Task) error {
 	m.DB.Delete(w)
 	return m.DB.Error
@@ -125,9 +159,22 @@ func (m *Task) ByBatchId(uuid string, page int, perPage int) (*[]model.Task, int
 	return tasks, total, m.DB.Error
 }
 
+// RootsByBatchId returns the tasks of a batch without their chunks
+func (m *Task) RootsByBatchId(uuid string) (*[]model.Task, error) {
+	var tasks = &[]model.Task{}
+	db := m.DB.Order("created_at ASC").Where("batch = ? and parent = ''", uuid).Find(&tasks)
+	return tasks, db.Error
+}
+
+func (m *Task) ByParent(uuid string) (*[]model.Task, error) {
+	var tasks = &[]model.Task{}
+	db := m.DB.Order("created_at ASC").Where("parent = ?", uuid).Find(&tasks)
+	return tasks, db.Error
+}
+
 func (m *Task) CountNonFinishedTasksByBatchId(uuid string) (int64, error) {
 	var count int64
-	db := m.DB.Model(&model.Task{}).Where("batch = ? and status != 'DONE_SUCCESSFUL' and status != 'DONE_ERROR' and status != 'DONE_CANCELED'").Count(&count)
+	db := m.DB.Model(&model.Task{}).Where("batch = ? and status != 'DONE_SUCCESSFUL' and status != 'DONE_ERROR' and status != 'DONE_CANCELED'", uuid).Count(&count)
 	return count, db.Error
 }
 
@@ -63,11 +63,23 @@ func (m *Watchfolder) Create(newWatchfolder *dto.NewWatchfolder) (*model.Watchfo
 		Name:         newWatchfolder.Name,
 		Description:  newWatchfolder.Description,
 		Preset:       newWatchfolder.Preset,
+		Presets:      newWatchfolder.Presets,
+		Rules:        newWatchfolder.Rules,
 		Path:         newWatchfolder.Path,
 		Interval:     newWatchfolder.Interval,
 		Filter:       newWatchfolder.Filter,
 		GrowthChecks: newWatchfolder.GrowthChecks,
 		Suspended:    newWatchfolder.Suspended,
+		SuspendAfter: newWatchfolder.SuspendAfter,
+
+		Mode:              newWatchfolder.Mode,
+		ReconcileInterval: newWatchfolder.ReconcileInterval,
+		Unit:              newWatchfolder.Unit,
+
+		HashFiles: newWatchfolder.HashFiles,
+
+		OnSuccess: newWatchfolder.OnSuccess,
+		OnError:   newWatchfolder.OnError,
 	}
 	db := m.DB.Create(watchfolder)
 	return watchfolder, db.Error
@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"gorm.io/gorm"
+)
+
+type WatchfolderFile struct {
+	DB *gorm.DB
+}
+
+func (t *WatchfolderFile) Setup() {
+	t.DB.AutoMigrate(&model.WatchfolderFile{})
+}
+
+func (m *WatchfolderFile) List(watchfolder string, page int, perPage int) (*[]model.WatchfolderFile, int64, error) {
+	var total int64
+	m.DB.Model(&model.WatchfolderFile{}).Where("watchfolder = ?", watchfolder).Count(&total)
+	var files = &[]model.WatchfolderFile{}
+	db := m.DB.Order("updated_at DESC").Where("watchfolder = ?", watchfolder)
+	if page >= 0 && perPage >= 0 {
+		db = db.Limit(perPage).Offset(page * perPage)
+	}
+	db = db.Find(&files)
+	return files, total, db.Error
+}
+
+func (m *WatchfolderFile) Save(f *model.WatchfolderFile) (*model.WatchfolderFile, error) {
+	db := m.DB.Save(f)
+	return f, db.Error
+}
+
+func (m *WatchfolderFile) DeleteByWatchfolder(watchfolder string) (int64, error) {
+	db := m.DB.Where("watchfolder = ?", watchfolder).Delete(&model.WatchfolderFile{})
+	return db.RowsAffected, db.Error
+}
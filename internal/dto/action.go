@@ -0,0 +1,80 @@
+package dto
+
+import "fmt"
+
+type ActionType string
+
+const (
+	ACTION_COPY          ActionType = "copy"
+	ACTION_MOVE          ActionType = "move"
+	ACTION_CHECKSUM      ActionType = "checksum"
+	ACTION_THUMBNAIL     ActionType = "thumbnail"
+	ACTION_HTTP          ActionType = "http"
+	ACTION_DELETE_SOURCE ActionType = "deleteSource"
+)
+
+type ActionStatus string
+
+const (
+	ACTION_SUCCESSFUL ActionStatus = "successful"
+	ACTION_FAILED     ActionStatus = "failed"
+	ACTION_SKIPPED    ActionStatus = "skipped"
+)
+
+// NewAction is a built-in pre- or postProcessing step, actions run in order after the script
+type NewAction struct {
+	Type ActionType `json:"type" validate:"required,oneof=copy move checksum thumbnail http deleteSource"`
+
+	// copy, move: the target file or directory (ending with a separator), checksum, thumbnail: the file to write
+	Destination string `json:"destination,omitempty"`
+
+	Algorithm string `json:"algorithm,omitempty" validate:"omitempty,oneof=md5 sha256"` // checksum, sha256 by default
+
+	Time  float64 `json:"time,omitempty" validate:"omitempty,min=0"`  // thumbnail, seconds into the output
+	Width int     `json:"width,omitempty" validate:"omitempty,min=0"` // thumbnail, the height keeps the aspect ratio
+
+	Url     string            `json:"url,omitempty"`                                                  // http
+	Method  string            `json:"method,omitempty" validate:"omitempty,oneof=GET POST PUT PATCH"` // http, POST by default
+	Headers map[string]string `json:"headers,omitempty"`                                              // http
+	Body    string            `json:"body,omitempty"`                                                 // http, the task as JSON by default
+
+	ContinueOnError bool `json:"continueOnError,omitempty"` // run the following actions and do not fail the task if this action fails
+}
+
+type Action struct {
+	NewAction
+
+	Status     ActionStatus `json:"status,omitempty"`
+	Output     string       `json:"output,omitempty"` // the written file, checksum or response status
+	Error      string       `json:"error,omitempty"`
+	StartedAt  int64        `json:"startedAt,omitempty"`
+	FinishedAt int64        `json:"finishedAt,omitempty"`
+}
+
+// ValidateActions checks what the validation tags of the actions can not express, actions working with the output
+// are only available in postProcessing and remote outputs can not be moved
+func (p *NewPrePostProcessing) ValidateActions(processorType string, remoteOutput bool) error {
+	if p == nil {
+		return nil
+	}
+	for i, action := range p.Actions {
+		prefix := fmt.Sprintf("%sProcessing: action %d (%s)", processorType, i+1, action.Type)
+		if processorType == "pre" && action.Type != ACTION_HTTP {
+			return fmt.Errorf("%s: only http actions are available in preProcessing", prefix)
+		}
+		switch action.Type {
+		case ACTION_COPY, ACTION_MOVE:
+			if action.Destination == "" {
+				return fmt.Errorf("%s: destination is required", prefix)
+			}
+			if action.Type == ACTION_MOVE && remoteOutput {
+				return fmt.Errorf("%s: remote outputs can not be moved", prefix)
+			}
+		case ACTION_HTTP:
+			if action.Url == "" {
+				return fmt.Errorf("%s: url is required", prefix)
+			}
+		}
+	}
+	return nil
+}
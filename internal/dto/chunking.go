@@ -0,0 +1,97 @@
+package dto
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+type ChunkingMode string
+
+const (
+	CHUNKING_MODE_SCENE    ChunkingMode = "scene"
+	CHUNKING_MODE_INTERVAL ChunkingMode = "interval"
+)
+
+type ChunkingPhase string
+
+const (
+	CHUNKING_PHASE_ENCODE ChunkingPhase = "encode"
+	CHUNKING_PHASE_CONCAT ChunkingPhase = "concat"
+)
+
+// NewChunking splits a task into chunks that are encoded as individual tasks and concatenated afterwards
+type NewChunking struct {
+	Mode ChunkingMode `json:"mode,omitempty"`
+
+	Interval         float64 `json:"interval,omitempty"`         // seconds between split points in interval mode
+	SceneThreshold   float64 `json:"sceneThreshold,omitempty"`   // scene change score (0-1) in scene mode
+	MinChunkDuration float64 `json:"minChunkDuration,omitempty"` // seconds
+
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+
+	ConcatCommand string `json:"concatCommand,omitempty"`
+}
+
+type Chunking struct {
+	NewChunking
+
+	Run       string        `json:"run,omitempty"`
+	Phase     ChunkingPhase `json:"phase,omitempty"`
+	Chunks    int           `json:"chunks,omitempty"`
+	Directory string        `json:"directory,omitempty"`
+}
+
+// Chunk describes the part of the parent's input a chunk task encodes
+type Chunk struct {
+	Run      string  `json:"run"`
+	Index    int     `json:"index"`
+	Start    float64 `json:"start"`
+	Duration float64 `json:"duration"`
+	Attempts int     `json:"attempts"`
+}
+
+func (n NewChunking) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *NewChunking) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
+
+func (c Chunking) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *Chunking) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, c)
+}
+
+func (c Chunk) Value() (driver.Value, error) {
+	return json.Marshal(c)
+}
+
+func (c *Chunk) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, c)
+}
@@ -0,0 +1,42 @@
+package dto
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// EncoderSettings describes SVT-AV1 encoder parameters that are rendered into the ${ENCODER_ARGS} wildcard
+type EncoderSettings struct {
+	Preset *int `json:"preset,omitempty"`
+
+	Crf           *int `json:"crf,omitempty"`
+	TargetBitrate *int `json:"targetBitrate,omitempty"` // kbit/s, switches rate control to VBR
+
+	Keyint *int `json:"keyint,omitempty"`
+
+	FilmGrain        *int  `json:"filmGrain,omitempty"`
+	FilmGrainDenoise *bool `json:"filmGrainDenoise,omitempty"`
+
+	Tune                 *int  `json:"tune,omitempty"`
+	SceneChangeDetection *bool `json:"sceneChangeDetection,omitempty"`
+	Lookahead            *int  `json:"lookahead,omitempty"`
+
+	TenBit     bool `json:"tenBit,omitempty"` // encodes yuv420p10le with the ffmpeg executor, SvtAv1EncApp follows the bit depth of its y4m input
+	FastDecode *int `json:"fastDecode,omitempty"`
+}
+
+func (e EncoderSettings) Value() (driver.Value, error) {
+	return json.Marshal(e)
+}
+
+func (e *EncoderSettings) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, e)
+}
@@ -0,0 +1,8 @@
+package dto
+
+// FieldChange is the change of a single field, e.g. of a task by a re-imported sidecar or of a preset by a library upgrade
+type FieldChange struct {
+	Field string `json:"field"`
+	Old   any    `json:"old"`
+	New   any    `json:"new"`
+}
@@ -3,15 +3,28 @@ package dto
 type NewPreset struct {
 	Command string `json:"command"`
 
+	Executor Executor         `json:"executor" validate:"omitempty,oneof=ffmpeg svtav1"`
+	Encoder  *EncoderSettings `json:"encoder,omitempty"`
+
+	QualitySearch *NewQualitySearch `json:"qualitySearch,omitempty"`
+	Chunking      *NewChunking      `json:"chunking,omitempty"`
+	Verification  *NewVerification  `json:"verification,omitempty"`
+
+	Secrets SecretReferences `json:"secrets,omitempty"` // the secrets its commands and scripts may reference
+
 	Priority uint `json:"priority"`
 
 	OutputFile string `json:"outputFile"`
 
+	AtomicOutput bool         `json:"atomicOutput,omitempty"` // write to a temporary sibling and rename after success
+	OutputPolicy OutputPolicy `json:"outputPolicy,omitempty" validate:"omitempty,oneof=overwrite skip fail suffix"`
+
 	PreProcessing  *NewPrePostProcessing `json:"preProcessing"`
 	PostProcessing *NewPrePostProcessing `json:"postProcessing"`
 
 	Name        string `json:"name"`
 	Description string `json:"description"`
 
-	GlobalPresetName string `json:"globalPresetName"`
+	GlobalPresetName    string `json:"globalPresetName"`
+	GlobalPresetVersion int    `json:"globalPresetVersion,omitempty"`
 }
@@ -0,0 +1,7 @@
+package dto
+
+type NewSecret struct {
+	Name        string `json:"name" validate:"required"` // referenced as ${SECRET_<name>}
+	Value       string `json:"value" validate:"required"`
+	Description string `json:"description"`
+}
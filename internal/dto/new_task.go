@@ -4,14 +4,29 @@ type NewTask struct {
 	Command string `json:"command"`
 	Preset  string `json:"preset"`
 
+	Executor Executor         `json:"executor" validate:"omitempty,oneof=ffmpeg svtav1"`
+	Encoder  *EncoderSettings `json:"encoder,omitempty"`
+
+	QualitySearch *NewQualitySearch `json:"qualitySearch,omitempty"`
+	Chunking      *NewChunking      `json:"chunking,omitempty"`
+	Verification  *NewVerification  `json:"verification,omitempty"`
+
+	Secrets SecretReferences `json:"secrets,omitempty"` // the secrets its commands and scripts may reference
+
+	Parent string `json:"-"` // set for chunk tasks created by the queue
+	Chunk  *Chunk `json:"-"`
+
 	Name string `json:"name"`
 
 	InputFile  string `json:"inputFile"`
 	OutputFile string `json:"outputFile"`
 
+	AtomicOutput bool         `json:"atomicOutput,omitempty"` // write to a temporary sibling and rename after success
+	OutputPolicy OutputPolicy `json:"outputPolicy,omitempty" validate:"omitempty,oneof=overwrite skip fail suffix"`
+
 	Metadata *InterfaceMap `json:"metadata,omitempty"` // Additional metadata for the task
 
-	Priority uint `json:"priority"`
+	Priority *uint `json:"priority"` // the preset priority is used if omitted
 
 	PreProcessing  *NewPrePostProcessing `json:"preProcessing"`
 	PostProcessing *NewPrePostProcessing `json:"postProcessing"`
@@ -8,9 +8,26 @@ type NewWatchfolder struct {
 	Interval     int    `json:"interval"`
 	GrowthChecks int    `json:"growthChecks"`
 
+	Mode              WatchfolderMode `json:"mode" validate:"omitempty,oneof=poll events"`
+	ReconcileInterval int             `json:"reconcileInterval" validate:"omitempty,min=0"`
+
+	Unit WatchfolderUnit `json:"unit" validate:"omitempty,oneof=file directory"`
+
+	// HashFiles stores a checksum of processed files, changed files with an unchanged checksum are not processed again
+	HashFiles bool `json:"hashFiles"`
+
+	OnSuccess *WatchfolderAction `json:"onSuccess"`
+	OnError   *WatchfolderAction `json:"onError"`
+
 	Filter *WatchfolderFilter `json:"filter"`
 
 	Suspended bool `json:"suspended"`
+	// SuspendAfter suspends the watchfolder after this many consecutive failed checks, 0 never suspends it
+	SuspendAfter int `json:"suspendAfter" validate:"omitempty,min=0"`
 
 	Preset string `json:"preset"`
+	// Presets creates one task per preset for every file, Preset is used if empty
+	Presets WatchfolderPresets `json:"presets"`
+	// Rules route files to other presets, priorities or metadata, the first matching rule wins
+	Rules WatchfolderRules `json:"rules"`
 }
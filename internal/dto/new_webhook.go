@@ -21,6 +21,10 @@ const (
 	WATCHFOLDER_CREATED WebhookEvent = "watchfolder.created"
 	WATCHFOLDER_UPDATED WebhookEvent = "watchfolder.updated"
 	WATCHFOLDER_DELETED WebhookEvent = "watchfolder.deleted"
+	WATCHFOLDER_FAILING WebhookEvent = "watchfolder.failing"
+
+	QUEUE_HELD    WebhookEvent = "queue.held"
+	QUEUE_RESUMED WebhookEvent = "queue.resumed"
 )
 
 type NewWebhook struct {
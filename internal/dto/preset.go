@@ -9,13 +9,28 @@ type Preset struct {
 	Name        string `json:"name"`
 	Description string `json:"description,omitempty"`
 
+	Executor Executor         `json:"executor,omitempty"`
+	Encoder  *EncoderSettings `json:"encoder,omitempty"`
+
+	QualitySearch *NewQualitySearch `json:"qualitySearch,omitempty"`
+	Chunking      *NewChunking      `json:"chunking,omitempty"`
+	Verification  *NewVerification  `json:"verification,omitempty"`
+
+	Secrets SecretReferences `json:"secrets,omitempty"` // the secrets its commands and scripts may reference
+
 	OutputFile string `json:"outputFile"`
 
+	AtomicOutput bool         `json:"atomicOutput,omitempty"`
+	OutputPolicy OutputPolicy `json:"outputPolicy,omitempty"`
+
 	Priority uint `json:"priority"`
 
 	PreProcessing  *NewPrePostProcessing `json:"preProcessing,omitempty"`
 	PostProcessing *NewPrePostProcessing `json:"postProcessing,omitempty"`
 
+	GlobalPresetName    string `json:"globalPresetName,omitempty"`
+	GlobalPresetVersion int    `json:"globalPresetVersion,omitempty"`
+
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
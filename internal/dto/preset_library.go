@@ -0,0 +1,28 @@
+package dto
+
+// PresetBundle is the file presets are exported to and imported from, as JSON or YAML
+type PresetBundle struct {
+	Version int      `json:"version"` // version of the bundle format
+	Presets []Preset `json:"presets"`
+}
+
+type PresetImportResult struct {
+	Created []string `json:"created"`
+	Updated []string `json:"updated"`
+	Skipped []string `json:"skipped"`
+}
+
+// LibraryPreset is a preset of the built-in library, installed presets keep its name and version as globalPresetName and globalPresetVersion
+type LibraryPreset struct {
+	Name    string    `json:"name"`
+	Version int       `json:"version"`
+	Preset  NewPreset `json:"preset"`
+}
+
+type LibraryPresetStatus struct {
+	LibraryPreset
+
+	Installed        string `json:"installed,omitempty"` // uuid of the installed preset
+	InstalledVersion int    `json:"installedVersion,omitempty"`
+	UpdateAvailable  bool   `json:"updateAvailable"`
+}
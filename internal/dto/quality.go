@@ -0,0 +1,75 @@
+package dto
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+type QualityMetric string
+
+const (
+	QUALITY_METRIC_VMAF QualityMetric = "vmaf"
+	QUALITY_METRIC_SSIM QualityMetric = "ssim"
+)
+
+// NewQualitySearch configures a per-title CRF search that encodes short samples at several CRF values and picks the highest CRF reaching the target score
+type NewQualitySearch struct {
+	Metric QualityMetric `json:"metric,omitempty"`
+	Target float64       `json:"target"`
+
+	Samples        int     `json:"samples,omitempty"`
+	SampleDuration float64 `json:"sampleDuration,omitempty"`
+
+	MinCrf int `json:"minCrf,omitempty"`
+	MaxCrf int `json:"maxCrf,omitempty"`
+}
+
+type QualitySearch struct {
+	NewQualitySearch
+
+	Results []QualitySample `json:"results,omitempty"`
+	Crf     int             `json:"crf,omitempty"`
+
+	Error      string `json:"error,omitempty"`
+	StartedAt  int64  `json:"startedAt,omitempty"`
+	FinishedAt int64  `json:"finishedAt,omitempty"`
+}
+
+// QualitySample holds the averaged score of all sample segments encoded at a single CRF
+type QualitySample struct {
+	Crf    int       `json:"crf"`
+	Score  float64   `json:"score"`
+	Scores []float64 `json:"scores"`
+	Size   int64     `json:"size"`
+}
+
+func (n NewQualitySearch) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *NewQualitySearch) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
+
+func (q QualitySearch) Value() (driver.Value, error) {
+	return json.Marshal(q)
+}
+
+func (q *QualitySearch) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, q)
+}
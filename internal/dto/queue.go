@@ -0,0 +1,7 @@
+package dto
+
+type QueueStatus struct {
+	Held      bool   `json:"held"`
+	Reason    string `json:"reason,omitempty"`
+	HeldSince int64  `json:"heldSince,omitempty"`
+}
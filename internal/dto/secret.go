@@ -0,0 +1,34 @@
+package dto
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+type Secret struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// SecretReferences are the names of the secrets a preset or task may resolve, other ${SECRET_<name>} wildcards stay unresolved
+type SecretReferences []string
+
+func (n SecretReferences) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *SecretReferences) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
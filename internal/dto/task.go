@@ -13,30 +13,72 @@ const (
 	QUEUED          TaskStatus = "QUEUED"
 	RUNNING         TaskStatus = "RUNNING"
 	PRE_PROCESSING  TaskStatus = "PRE_PROCESSING"
+	QUALITY_SEARCH  TaskStatus = "QUALITY_SEARCH"
+	CHUNKING        TaskStatus = "CHUNKING"
+	VERIFYING       TaskStatus = "VERIFYING"
+	DOWNLOADING     TaskStatus = "DOWNLOADING"
+	UPLOADING       TaskStatus = "UPLOADING"
 	POST_PROCESSING TaskStatus = "POST_PROCESSING"
 	DONE_SUCCESSFUL TaskStatus = "DONE_SUCCESSFUL"
 	DONE_ERROR      TaskStatus = "DONE_ERROR"
 	DONE_CANCELED   TaskStatus = "DONE_CANCELED"
 )
 
+// IsFinal reports whether the task is done, successful or not
+func (s TaskStatus) IsFinal() bool {
+	return s == DONE_SUCCESSFUL || s == DONE_ERROR || s == DONE_CANCELED
+}
+
+// IsActive reports whether the task is being processed, active tasks have to be canceled before they can be deleted
+func (s TaskStatus) IsActive() bool {
+	return s != QUEUED && !s.IsFinal()
+}
+
+type OutputPolicy string
+
+const (
+	OUTPUT_POLICY_OVERWRITE OutputPolicy = "overwrite"
+	OUTPUT_POLICY_SKIP      OutputPolicy = "skip"
+	OUTPUT_POLICY_FAIL      OutputPolicy = "fail"
+	OUTPUT_POLICY_SUFFIX    OutputPolicy = "suffix"
+)
+
+type Executor string
+
+const (
+	EXECUTOR_FFMPEG Executor = "ffmpeg"
+	EXECUTOR_SVTAV1 Executor = "svtav1"
+)
+
 type NewPrePostProcessing struct {
 	ScriptPath    string `json:"scriptPath,omitempty"`
 	SidecarPath   string `json:"sidecarPath,omitempty"`
 	ImportSidecar bool   `json:"importSidecar,omitempty"`
+	ImportOutput  bool   `json:"importOutput,omitempty"`                       // apply the JSON document the script prints on stdout
+	Timeout       int    `json:"timeout,omitempty" validate:"omitempty,min=0"` // seconds the script may run, 0 for no limit
+
+	Actions []NewAction `json:"actions,omitempty" validate:"dive"`
 }
 
 type PrePostProcessing struct {
-	ScriptPath    *RawResolved `json:"scriptPath,omitempty"`
-	SidecarPath   *RawResolved `json:"sidecarPath,omitempty"`
-	ImportSidecar bool         `json:"importSidecar,omitempty"`
-	Error         string       `json:"error,omitempty"`
-	StartedAt     int64        `json:"startedAt,omitempty"`
-	FinishedAt    int64        `json:"finishedAt,omitempty"`
+	ScriptPath    *RawResolved  `json:"scriptPath,omitempty"`
+	SidecarPath   *RawResolved  `json:"sidecarPath,omitempty"`
+	ImportSidecar bool          `json:"importSidecar,omitempty"`
+	ImportOutput  bool          `json:"importOutput,omitempty"`
+	Timeout       int           `json:"timeout,omitempty"`
+	Stdout        string        `json:"stdout,omitempty"` // the end of the script's output
+	Stderr        string        `json:"stderr,omitempty"`
+	Changes       []FieldChange `json:"changes,omitempty"` // task fields changed by the sidecar or script output
+	Actions       []Action      `json:"actions,omitempty"`
+	Error         string        `json:"error,omitempty"`
+	StartedAt     int64         `json:"startedAt,omitempty"`
+	FinishedAt    int64         `json:"finishedAt,omitempty"`
 }
 
 type RawResolved struct {
 	Raw      string `json:"raw"`
 	Resolved string `json:"resolved,omitempty"`
+	Local    string `json:"local,omitempty"` // staged copy of a remote file
 }
 
 type Task struct {
@@ -49,8 +91,26 @@ type Task struct {
 	InputFile  *RawResolved `json:"inputFile"`
 	OutputFile *RawResolved `json:"outputFile"`
 
+	AtomicOutput   bool         `json:"atomicOutput,omitempty"`
+	OutputPolicy   OutputPolicy `json:"outputPolicy,omitempty"`
+	TempOutputFile string       `json:"tempOutputFile,omitempty"`
+
+	Workdir string `json:"workdir,omitempty"`
+
 	Metadata *InterfaceMap `json:"metadata,omitempty"` // Additional metadata for the task
 
+	Executor Executor         `json:"executor,omitempty"`
+	Encoder  *EncoderSettings `json:"encoder,omitempty"`
+
+	QualitySearch *QualitySearch `json:"qualitySearch,omitempty"`
+	Chunking      *Chunking      `json:"chunking,omitempty"`
+	Verification  *Verification  `json:"verification,omitempty"`
+
+	Secrets SecretReferences `json:"secrets,omitempty"`
+
+	Parent string `json:"parent,omitempty"`
+	Chunk  *Chunk `json:"chunk,omitempty"`
+
 	Status    TaskStatus `json:"status"`
 	Progress  float64    `json:"progress"`
 	Remaining float64    `json:"remaining"`
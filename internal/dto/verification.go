@@ -0,0 +1,67 @@
+package dto
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// NewVerification configures the checks run against the output after a successful encode
+type NewVerification struct {
+	DurationTolerance *float64 `json:"durationTolerance,omitempty"` // seconds the output duration may differ from the input, 1 if omitted
+	SkipDuration      bool     `json:"skipDuration,omitempty"`
+
+	VideoCodec   string `json:"videoCodec,omitempty"`
+	AudioCodec   string `json:"audioCodec,omitempty"`
+	VideoStreams *int   `json:"videoStreams,omitempty"`
+	AudioStreams *int   `json:"audioStreams,omitempty"`
+
+	Decode bool `json:"decode,omitempty"` // decode the whole output to catch corrupt frames
+}
+
+type Verification struct {
+	NewVerification
+
+	Checks []VerificationCheck `json:"checks,omitempty"`
+
+	Error      string `json:"error,omitempty"`
+	StartedAt  int64  `json:"startedAt,omitempty"`
+	FinishedAt int64  `json:"finishedAt,omitempty"`
+}
+
+type VerificationCheck struct {
+	Name     string `json:"name"`
+	Passed   bool   `json:"passed"`
+	Expected string `json:"expected,omitempty"`
+	Actual   string `json:"actual,omitempty"`
+}
+
+func (n NewVerification) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *NewVerification) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
+
+func (v Verification) Value() (driver.Value, error) {
+	return json.Marshal(v)
+}
+
+func (v *Verification) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, v)
+}
@@ -16,11 +16,29 @@ type Watchfolder struct {
 	Interval     int    `json:"interval"`
 	GrowthChecks int    `json:"growthChecks"`
 
+	Mode              WatchfolderMode `json:"mode"`
+	ReconcileInterval int             `json:"reconcileInterval"`
+
+	Unit WatchfolderUnit `json:"unit"`
+
+	HashFiles bool `json:"hashFiles"`
+
+	OnSuccess *WatchfolderAction `json:"onSuccess,omitempty"`
+	OnError   *WatchfolderAction `json:"onError,omitempty"`
+
 	Suspended bool `json:"suspended"`
+	// SuspendAfter suspends the watchfolder after this many consecutive failed checks, 0 never suspends it
+	SuspendAfter int `json:"suspendAfter"`
+
+	Health            WatchfolderHealth `json:"health"`
+	ConsecutiveErrors int               `json:"consecutiveErrors"`
 
 	Filter *WatchfolderFilter `json:"filter"`
 
-	Preset string `json:"preset"`
+	Preset  string             `json:"preset"`
+	Presets WatchfolderPresets `json:"presets,omitempty"`
+
+	Rules WatchfolderRules `json:"rules,omitempty"`
 
 	CreatedAt int64 `json:"createdAt"`
 	UpdatedAt int64 `json:"updatedAt"`
@@ -29,8 +47,150 @@ type Watchfolder struct {
 	LastCheck int64  `json:"lastCheck"`
 }
 
+type WatchfolderHealth string
+
+const (
+	WATCHFOLDER_HEALTH_OK WatchfolderHealth = "ok"
+	// WATCHFOLDER_HEALTH_DEGRADED means the last checks failed but not often enough to consider the watchfolder failing
+	WATCHFOLDER_HEALTH_DEGRADED WatchfolderHealth = "degraded"
+	WATCHFOLDER_HEALTH_FAILING  WatchfolderHealth = "failing"
+)
+
+type WatchfolderMode string
+
+const (
+	// WATCHFOLDER_MODE_POLL walks the whole folder every interval, it works on every filesystem
+	WATCHFOLDER_MODE_POLL WatchfolderMode = "poll"
+	// WATCHFOLDER_MODE_EVENTS reacts to filesystem notifications and only walks the folder every reconcile interval
+	WATCHFOLDER_MODE_EVENTS WatchfolderMode = "events"
+)
+
+// WatchfolderPresets are the presets a watchfolder creates a task for each drop with, all tasks of a drop share a batch
+type WatchfolderPresets []string
+
+func (n WatchfolderPresets) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *WatchfolderPresets) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
+
+// WatchfolderRule routes matching files to its presets, rules are evaluated in order and the first match wins
+type WatchfolderRule struct {
+	Name  string               `json:"name,omitempty"`
+	Match WatchfolderRuleMatch `json:"match"`
+
+	// Presets replace the presets of the watchfolder for matching files, the watchfolder presets are used if empty
+	Presets WatchfolderPresets `json:"presets,omitempty"`
+	// Priority overrides the priority of the presets
+	Priority *uint `json:"priority,omitempty"`
+	// Metadata is added to the metadata of the created tasks
+	Metadata *InterfaceMap `json:"metadata,omitempty"`
+}
+
+// WatchfolderRuleMatch holds the conditions of a rule, all set conditions must match
+type WatchfolderRuleMatch struct {
+	Extensions []string `json:"extensions,omitempty"`
+	// Include and Exclude are glob patterns like in the watchfolder filter, e.g. *_proxy*
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	Regex   string   `json:"regex,omitempty"`
+
+	MinSize int64 `json:"minSize,omitempty"` // bytes
+	MaxSize int64 `json:"maxSize,omitempty"` // bytes
+
+	// the resolution of the first video stream, files are only probed if a rule sets one of them
+	MinWidth  int `json:"minWidth,omitempty"`
+	MaxWidth  int `json:"maxWidth,omitempty"`
+	MinHeight int `json:"minHeight,omitempty"`
+	MaxHeight int `json:"maxHeight,omitempty"`
+}
+
+type WatchfolderRules []WatchfolderRule
+
+func (n WatchfolderRules) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *WatchfolderRules) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
+
+type WatchfolderUnit string
+
+const (
+	// WATCHFOLDER_UNIT_FILE creates a task for every file
+	WATCHFOLDER_UNIT_FILE WatchfolderUnit = "file"
+	// WATCHFOLDER_UNIT_DIRECTORY creates a task for every subdirectory of the watchfolder, e.g. for image sequences or camera cards
+	WATCHFOLDER_UNIT_DIRECTORY WatchfolderUnit = "directory"
+)
+
+type WatchfolderActionType string
+
+const (
+	WATCHFOLDER_ACTION_LEAVE  WatchfolderActionType = "leave"
+	WATCHFOLDER_ACTION_MOVE   WatchfolderActionType = "move"
+	WATCHFOLDER_ACTION_DELETE WatchfolderActionType = "delete"
+)
+
+// WatchfolderAction is applied to the source file once its task reached a final state
+type WatchfolderAction struct {
+	Action WatchfolderActionType `json:"action" validate:"omitempty,oneof=leave move delete"`
+	// Directory the source file is moved to keeping its relative path, relative directories are resolved against the watchfolder path (default: done/ or error/)
+	Directory string `json:"directory,omitempty"`
+}
+
+func (n WatchfolderAction) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *WatchfolderAction) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
+
 type WatchfolderFilter struct {
 	Extensions *WatchfolderFilterExtensions `json:"extensions"`
+
+	// Include and Exclude are glob patterns (with ** support) matched against the path relative to the watchfolder, patterns without a slash match the file name
+	Include []string `json:"include,omitempty"`
+	Exclude []string `json:"exclude,omitempty"`
+	// Regex must match the path relative to the watchfolder
+	Regex string `json:"regex,omitempty"`
+
+	MinSize int64 `json:"minSize,omitempty"` // bytes
+	MaxSize int64 `json:"maxSize,omitempty"` // bytes
+	MinAge  int   `json:"minAge,omitempty"`  // seconds since the last modification
+
+	// MaxDepth limits how deep files are looked for, 1 only processes files in the watchfolder itself
+	MaxDepth int `json:"maxDepth,omitempty"`
+	// IgnoreDirs are glob patterns of directories that are not looked into
+	IgnoreDirs []string `json:"ignoreDirs,omitempty"`
+
+	// ReadyMarkers are extensions (e.g. done, md5) of sibling files that must exist before a file is processed,
+	// movie.mp4 is ready once movie.mp4.done or movie.done exists
+	ReadyMarkers []string `json:"readyMarkers,omitempty"`
 }
 
 type WatchfolderFilterExtensions struct {
@@ -0,0 +1,16 @@
+package dto
+
+type WatchfolderFile struct {
+	Watchfolder string `json:"watchfolder"`
+	Path        string `json:"path"`
+
+	Size    int64  `json:"size"`
+	ModTime int64  `json:"modTime"`
+	Hash    string `json:"hash,omitempty"`
+
+	Task  string `json:"task,omitempty"`
+	Batch string `json:"batch,omitempty"`
+
+	CreatedAt int64 `json:"createdAt"`
+	UpdatedAt int64 `json:"updatedAt"`
+}
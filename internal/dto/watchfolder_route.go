@@ -0,0 +1,17 @@
+package dto
+
+type WatchfolderDryRun struct {
+	// Path of the file, relative paths are resolved against the watchfolder path
+	Path string `json:"path" validate:"required"`
+}
+
+// WatchfolderRoute describes which rule a file hits and which tasks are created for it
+type WatchfolderRoute struct {
+	Path string `json:"path"`
+	// Rule is the index of the matching rule, -1 if no rule matched and the watchfolder presets are used
+	Rule     int                `json:"rule"`
+	RuleName string             `json:"ruleName,omitempty"`
+	Presets  WatchfolderPresets `json:"presets"`
+	Priority *uint              `json:"priority,omitempty"`
+	Metadata *InterfaceMap      `json:"metadata,omitempty"`
+}
@@ -0,0 +1,53 @@
+package dto
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// WatchfolderStats describes the health and activity of a watchfolder since it was created
+type WatchfolderStats struct {
+	Health            WatchfolderHealth `json:"health"`
+	ConsecutiveErrors int               `json:"consecutiveErrors"`
+
+	FilesSeen    int64  `json:"filesSeen"`
+	TasksCreated int64  `json:"tasksCreated"`
+	LastTask     string `json:"lastTask,omitempty"`
+	LastTaskAt   int64  `json:"lastTaskAt,omitempty"`
+
+	History *WatchfolderHistory `json:"history"`
+}
+
+// WatchfolderHistory keeps the most recent errors and task creations of a watchfolder
+type WatchfolderHistory struct {
+	Errors []WatchfolderHistoryEntry `json:"errors"`
+	Tasks  []WatchfolderHistoryEntry `json:"tasks"`
+}
+
+type WatchfolderHistoryEntry struct {
+	Time int64 `json:"time"`
+
+	Error string `json:"error,omitempty"`
+	// Count is how often the same error occurred in a row, Time is its last occurrence
+	Count int `json:"count,omitempty"`
+
+	Path  string `json:"path,omitempty"`
+	Task  string `json:"task,omitempty"`
+	Batch string `json:"batch,omitempty"`
+}
+
+func (n WatchfolderHistory) Value() (driver.Value, error) {
+	return json.Marshal(n)
+}
+
+func (n *WatchfolderHistory) Scan(value interface{}) error {
+	if value == nil {
+		return nil
+	}
+	bytes, ok := value.([]byte)
+	if !ok {
+		return errors.New("type assertion to []byte failed")
+	}
+	return json.Unmarshal(bytes, n)
+}
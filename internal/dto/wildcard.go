@@ -0,0 +1,30 @@
+package dto
+
+// WildcardPreview holds the templates to resolve, presets fill the command, output file, executor, encoder and secrets if they are omitted
+type WildcardPreview struct {
+	Command    string `json:"command"`
+	InputFile  string `json:"inputFile"`
+	OutputFile string `json:"outputFile"`
+
+	Source   string        `json:"source"`
+	Metadata *InterfaceMap `json:"metadata,omitempty"`
+
+	Executor Executor         `json:"executor" validate:"omitempty,oneof=ffmpeg svtav1"`
+	Encoder  *EncoderSettings `json:"encoder,omitempty"`
+
+	Secrets SecretReferences `json:"secrets,omitempty"`
+}
+
+type WildcardPreviewResult struct {
+	Command    string `json:"command"`
+	InputFile  string `json:"inputFile"`
+	OutputFile string `json:"outputFile"`
+
+	// Args are the arguments of every process the command starts, including the binary
+	Args [][]string `json:"args"`
+
+	// Unknown wildcards are kept as they are, or fail the task if strict wildcards are enabled
+	Unknown []string `json:"unknown"`
+	Empty   []string `json:"empty"`
+	Strict  bool     `json:"strict"`
+}
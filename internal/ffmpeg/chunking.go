@@ -0,0 +1,148 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"math"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+// DefaultConcatCommand concatenates the video of all chunks and copies the remaining streams from the original input
+const DefaultConcatCommand = "-y -f concat -safe 0 -i ${CHUNK_LIST} -i ${INPUT_FILE} -map 0:v -map 1 -map -1:v -c copy ${OUTPUT_FILE}"
+
+// ChunkRange is a single part of the input in seconds
+type ChunkRange struct {
+	Start    float64
+	Duration float64
+}
+
+// ChunkingDefaults fills unset fields of the chunking settings with sane defaults
+func ChunkingDefaults(c *dto.NewChunking) {
+	if c.Mode == "" {
+		c.Mode = dto.CHUNKING_MODE_SCENE
+	}
+	if c.Interval == 0 {
+		c.Interval = 60
+	}
+	if c.SceneThreshold == 0 {
+		c.SceneThreshold = 0.4
+	}
+	if c.MinChunkDuration == 0 {
+		c.MinChunkDuration = 10
+	}
+	if c.MaxAttempts == 0 {
+		c.MaxAttempts = 3
+	}
+	if c.ConcatCommand == "" {
+		c.ConcatCommand = DefaultConcatCommand
+	}
+}
+
+// ValidateChunking checks the chunking settings
+func ValidateChunking(c *dto.NewChunking) error {
+	if c == nil {
+		return nil
+	}
+	ChunkingDefaults(c)
+
+	switch c.Mode {
+	case dto.CHUNKING_MODE_SCENE, dto.CHUNKING_MODE_INTERVAL:
+	default:
+		return fmt.Errorf("chunking: unsupported mode '%s'", c.Mode)
+	}
+	if c.Interval < 1 {
+		return fmt.Errorf("chunking: interval must be at least 1 second (got: %g)", c.Interval)
+	}
+	if c.SceneThreshold <= 0 || c.SceneThreshold >= 1 {
+		return fmt.Errorf("chunking: sceneThreshold must be between 0 and 1 (got: %g)", c.SceneThreshold)
+	}
+	if c.MinChunkDuration < 1 {
+		return fmt.Errorf("chunking: minChunkDuration must be at least 1 second (got: %g)", c.MinChunkDuration)
+	}
+	if c.MaxAttempts < 1 || c.MaxAttempts > 10 {
+		return fmt.Errorf("chunking: maxAttempts must be between 1 and 10 (got: %d)", c.MaxAttempts)
+	}
+	if !strings.Contains(c.ConcatCommand, "${CHUNK_LIST}") {
+		return fmt.Errorf("chunking: concatCommand must contain ${CHUNK_LIST}")
+	}
+	return nil
+}
+
+var rePtsTime = regexp.MustCompile(`pts_time:\s*([\d.]+)`)
+
+// DetectScenes returns the timestamps of all scene changes of the first video stream above the given threshold
+func DetectScenes(ctx context.Context, input string, threshold float64) ([]float64, error) {
+	binary := executorFor(dto.EXECUTOR_FFMPEG).binary()
+	if binary == "" {
+		binary = "ffmpeg"
+	}
+	filter := fmt.Sprintf("select='gt(scene,%s)',showinfo", strconv.FormatFloat(threshold, 'f', -1, 64))
+	cmd := exec.CommandContext(ctx, binary, "-hide_banner", "-nostats", "-i", input, "-map", "0:v:0", "-vf", filter, "-f", "null", "-")
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("FFMPEG - failed to get stderr pipe: %v", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("FFMPEG - failed to start scene detection: %v", err)
+	}
+
+	var scenes []float64
+	var tail []string
+	scanner := bufio.NewScanner(stderr)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := rePtsTime.FindStringSubmatch(line); match != nil && strings.Contains(line, "showinfo") {
+			if pts, err := strconv.ParseFloat(match[1], 64); err == nil {
+				scenes = append(scenes, pts)
+			}
+			continue
+		}
+		tail = append(tail, line)
+		if len(tail) > 20 {
+			tail = tail[1:]
+		}
+	}
+	if err := cmd.Wait(); err != nil {
+		return nil, fmt.Errorf("FFMPEG - scene detection failed: %v\n%s", err, strings.Join(tail, "\n"))
+	}
+	return scenes, nil
+}
+
+// IntervalPoints returns split points every interval seconds
+func IntervalPoints(duration float64, interval float64) []float64 {
+	var points []float64
+	for t := interval; t < duration; t += interval {
+		points = append(points, t)
+	}
+	return points
+}
+
+// ChunkRanges turns split points into chunks of at least minDuration, a too short tail is merged into the previous chunk
+func ChunkRanges(points []float64, duration float64, minDuration float64) []ChunkRange {
+	sort.Float64s(points)
+	var ranges []ChunkRange
+	start := 0.0
+	for _, p := range points {
+		if p-start < minDuration || duration-p < minDuration {
+			continue
+		}
+		ranges = append(ranges, ChunkRange{Start: start, Duration: p - start})
+		start = p
+	}
+	return append(ranges, ChunkRange{Start: start, Duration: duration - start})
+}
+
+// ChunkArgs returns the input arguments that restrict an executor to a single chunk
+func ChunkArgs(executor dto.Executor, chunk ChunkRange, fps float64) string {
+	if executor == dto.EXECUTOR_SVTAV1 {
+		return fmt.Sprintf("--skip %d -n %d", int(math.Round(chunk.Start*fps)), int(math.Round(chunk.Duration*fps)))
+	}
+	return fmt.Sprintf("-ss %s -t %s", strconv.FormatFloat(chunk.Start, 'f', 6, 64), strconv.FormatFloat(chunk.Duration, 'f', 6, 64))
+}
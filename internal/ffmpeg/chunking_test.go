@@ -0,0 +1,81 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestChunkRanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		points   []float64
+		duration float64
+		min      float64
+		want     []ChunkRange
+	}{
+		{
+			name:     "No split points",
+			duration: 30,
+			min:      10,
+			want:     []ChunkRange{{Start: 0, Duration: 30}},
+		},
+		{
+			name:     "Short scenes are merged",
+			points:   []float64{5, 12, 14, 30},
+			duration: 45,
+			min:      10,
+			want:     []ChunkRange{{Start: 0, Duration: 12}, {Start: 12, Duration: 18}, {Start: 30, Duration: 15}},
+		},
+		{
+			name:     "Short tail is merged into the previous chunk",
+			points:   []float64{20, 38},
+			duration: 40,
+			min:      10,
+			want:     []ChunkRange{{Start: 0, Duration: 20}, {Start: 20, Duration: 20}},
+		},
+		{
+			name:     "Interval",
+			points:   IntervalPoints(150, 60),
+			duration: 150,
+			min:      10,
+			want:     []ChunkRange{{Start: 0, Duration: 60}, {Start: 60, Duration: 60}, {Start: 120, Duration: 30}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ChunkRanges(tt.points, tt.duration, tt.min)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ChunkRanges() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChunkArgs(t *testing.T) {
+	chunk := ChunkRange{Start: 12.5, Duration: 10}
+	if got := ChunkArgs(dto.EXECUTOR_FFMPEG, chunk, 24); got != "-ss 12.500000 -t 10.000000" {
+		t.Errorf("ChunkArgs(ffmpeg) = %s", got)
+	}
+	if got := ChunkArgs(dto.EXECUTOR_SVTAV1, chunk, 24); got != "--skip 300 -n 240" {
+		t.Errorf("ChunkArgs(svtav1) = %s", got)
+	}
+}
+
+func TestValidateChunking(t *testing.T) {
+	c := &dto.NewChunking{}
+	if err := ValidateChunking(c); err != nil {
+		t.Fatalf("ValidateChunking() with defaults failed: %v", err)
+	}
+	if c.Mode != dto.CHUNKING_MODE_SCENE || c.ConcatCommand != DefaultConcatCommand {
+		t.Errorf("ValidateChunking() did not apply defaults: %+v", c)
+	}
+	if err := ValidateChunking(&dto.NewChunking{Mode: "gop"}); err == nil {
+		t.Error("ValidateChunking() accepted unknown mode")
+	}
+	if err := ValidateChunking(&dto.NewChunking{ConcatCommand: "-i list.txt ${OUTPUT_FILE}"}); err == nil {
+		t.Error("ValidateChunking() accepted concat command without ${CHUNK_LIST}")
+	}
+}
@@ -0,0 +1,158 @@
+package ffmpeg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+type encoderRange struct {
+	name     string
+	value    *int
+	min, max int
+}
+
+// ValidateEncoder checks the encoder settings and makes sure the command receives them through ${ENCODER_ARGS}
+func ValidateEncoder(e *dto.EncoderSettings, command string) error {
+	if e == nil {
+		return nil
+	}
+	if !strings.Contains(command, "${ENCODER_ARGS}") {
+		return fmt.Errorf("encoder: command must contain ${ENCODER_ARGS} to apply the encoder settings")
+	}
+	return validateSettings(e)
+}
+
+// validateSettings checks the encoder settings against the ranges supported by SVT-AV1 and rejects invalid combinations
+func validateSettings(e *dto.EncoderSettings) error {
+	for _, r := range []encoderRange{
+		{"preset", e.Preset, -1, 13},
+		{"crf", e.Crf, 1, 63},
+		{"targetBitrate", e.TargetBitrate, 1, 100000},
+		{"keyint", e.Keyint, -1, 1000},
+		{"filmGrain", e.FilmGrain, 0, 50},
+		{"tune", e.Tune, 0, 2},
+		{"lookahead", e.Lookahead, -1, 120},
+		{"fastDecode", e.FastDecode, 0, 2},
+	} {
+		if r.value != nil && (*r.value < r.min || *r.value > r.max) {
+			return fmt.Errorf("encoder: %s must be between %d and %d (got: %d)", r.name, r.min, r.max, *r.value)
+		}
+	}
+
+	if e.Keyint != nil && *e.Keyint == 0 {
+		return fmt.Errorf("encoder: keyint must be -1 (infinite) or a positive number of frames")
+	}
+	if e.Crf != nil && e.TargetBitrate != nil {
+		return fmt.Errorf("encoder: crf and targetBitrate are mutually exclusive")
+	}
+	if e.FilmGrainDenoise != nil && (e.FilmGrain == nil || *e.FilmGrain == 0) {
+		return fmt.Errorf("encoder: filmGrainDenoise requires filmGrain to be enabled")
+	}
+	return nil
+}
+
+// EncoderArgs renders the encoder settings into arguments for the given executor
+func EncoderArgs(e *dto.EncoderSettings, executor dto.Executor) (string, error) {
+	if e == nil {
+		return "", nil
+	}
+	if err := validateSettings(e); err != nil {
+		return "", err
+	}
+	if executor == dto.EXECUTOR_SVTAV1 {
+		return svtAv1EncAppArgs(e), nil
+	}
+	return libsvtav1Args(e), nil
+}
+
+// libsvtav1Args renders the settings for ffmpeg's libsvtav1 wrapper
+func libsvtav1Args(e *dto.EncoderSettings) string {
+	args := []string{"-c:v", "libsvtav1"}
+	var params []string
+
+	if e.Preset != nil {
+		args = append(args, "-preset", fmt.Sprint(*e.Preset))
+	}
+	if e.Crf != nil {
+		args = append(args, "-crf", fmt.Sprint(*e.Crf))
+	}
+	if e.TargetBitrate != nil {
+		args = append(args, "-b:v", fmt.Sprintf("%dk", *e.TargetBitrate))
+		params = append(params, "rc=1")
+	}
+	if e.Keyint != nil {
+		args = append(args, "-g", fmt.Sprint(*e.Keyint))
+	}
+	if e.TenBit {
+		args = append(args, "-pix_fmt", "yuv420p10le")
+	}
+	if e.Tune != nil {
+		params = append(params, fmt.Sprintf("tune=%d", *e.Tune))
+	}
+	if e.FilmGrain != nil {
+		params = append(params, fmt.Sprintf("film-grain=%d", *e.FilmGrain))
+	}
+	if e.FilmGrainDenoise != nil {
+		params = append(params, fmt.Sprintf("film-grain-denoise=%d", boolToInt(*e.FilmGrainDenoise)))
+	}
+	if e.SceneChangeDetection != nil {
+		params = append(params, fmt.Sprintf("scd=%d", boolToInt(*e.SceneChangeDetection)))
+	}
+	if e.Lookahead != nil {
+		params = append(params, fmt.Sprintf("lookahead=%d", *e.Lookahead))
+	}
+	if e.FastDecode != nil {
+		params = append(params, fmt.Sprintf("fast-decode=%d", *e.FastDecode))
+	}
+
+	if len(params) > 0 {
+		args = append(args, "-svtav1-params", strings.Join(params, ":"))
+	}
+	return strings.Join(args, " ")
+}
+
+// svtAv1EncAppArgs renders the settings for a standalone SvtAv1EncApp run
+// TenBit is not rendered as SvtAv1EncApp takes the bit depth from the y4m stream it reads
+func svtAv1EncAppArgs(e *dto.EncoderSettings) string {
+	var args []string
+	if e.Preset != nil {
+		args = append(args, "--preset", fmt.Sprint(*e.Preset))
+	}
+	if e.Crf != nil {
+		args = append(args, "--crf", fmt.Sprint(*e.Crf))
+	}
+	if e.TargetBitrate != nil {
+		args = append(args, "--rc", "1", "--tbr", fmt.Sprint(*e.TargetBitrate))
+	}
+	if e.Keyint != nil {
+		args = append(args, "--keyint", fmt.Sprint(*e.Keyint))
+	}
+	if e.Tune != nil {
+		args = append(args, "--tune", fmt.Sprint(*e.Tune))
+	}
+	if e.FilmGrain != nil {
+		args = append(args, "--film-grain", fmt.Sprint(*e.FilmGrain))
+	}
+	if e.FilmGrainDenoise != nil {
+		args = append(args, "--film-grain-denoise", fmt.Sprint(boolToInt(*e.FilmGrainDenoise)))
+	}
+	if e.SceneChangeDetection != nil {
+		args = append(args, "--scd", fmt.Sprint(boolToInt(*e.SceneChangeDetection)))
+	}
+	if e.Lookahead != nil {
+		args = append(args, "--lookahead", fmt.Sprint(*e.Lookahead))
+	}
+	if e.FastDecode != nil {
+		args = append(args, "--fast-decode", fmt.Sprint(*e.FastDecode))
+	}
+	return strings.Join(args, " ")
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,88 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func intPtr(i int) *int {
+	return &i
+}
+
+func boolPtr(b bool) *bool {
+	return &b
+}
+
+func TestEncoderArgs(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoder  *dto.EncoderSettings
+		executor dto.Executor
+		want     string
+		wantErr  bool
+	}{
+		{
+			name:     "No encoder settings",
+			encoder:  nil,
+			executor: dto.EXECUTOR_FFMPEG,
+			want:     "",
+		},
+		{
+			name: "libsvtav1 crf",
+			encoder: &dto.EncoderSettings{
+				Preset:               intPtr(6),
+				Crf:                  intPtr(30),
+				Keyint:               intPtr(240),
+				Tune:                 intPtr(0),
+				FilmGrain:            intPtr(8),
+				SceneChangeDetection: boolPtr(true),
+				TenBit:               true,
+			},
+			executor: dto.EXECUTOR_FFMPEG,
+			want:     "-c:v libsvtav1 -preset 6 -crf 30 -g 240 -pix_fmt yuv420p10le -svtav1-params tune=0:film-grain=8:scd=1",
+		},
+		{
+			name: "SvtAv1EncApp vbr",
+			encoder: &dto.EncoderSettings{
+				Preset:        intPtr(8),
+				TargetBitrate: intPtr(3000),
+				Lookahead:     intPtr(60),
+				FastDecode:    intPtr(1),
+				TenBit:        true,
+			},
+			executor: dto.EXECUTOR_SVTAV1,
+			want:     "--preset 8 --rc 1 --tbr 3000 --lookahead 60 --fast-decode 1",
+		},
+		{
+			name:     "Preset out of range",
+			encoder:  &dto.EncoderSettings{Preset: intPtr(14)},
+			executor: dto.EXECUTOR_FFMPEG,
+			wantErr:  true,
+		},
+		{
+			name:     "Crf and target bitrate",
+			encoder:  &dto.EncoderSettings{Crf: intPtr(30), TargetBitrate: intPtr(3000)},
+			executor: dto.EXECUTOR_FFMPEG,
+			wantErr:  true,
+		},
+		{
+			name:     "Film grain denoise without film grain",
+			encoder:  &dto.EncoderSettings{FilmGrainDenoise: boolPtr(true)},
+			executor: dto.EXECUTOR_FFMPEG,
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := EncoderArgs(tt.encoder, tt.executor)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("EncoderArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("EncoderArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,96 +3,289 @@ package ffmpeg
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
-	"math"
+	"io"
+	"os"
 	"os/exec"
 	"regexp"
 	"runtime"
 	"strings"
+	"sync"
+	"syscall"
 
 	"github.com/mattn/go-shellwords"
-	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/yosev/debugo"
 )
 
 var debug = debugo.New("ffmpeg")
 
+// stage is a single process of a pipeline
+type stage struct {
+	index    int
+	name     string
+	args     []string
+	cmd      *exec.Cmd
+	executor executor
+
+	stderr     bytes.Buffer
+	stderrPipe io.ReadCloser
+	scanned    chan struct{}
+}
+
+// stageError reports which stage of a pipeline failed
+type stageError struct {
+	stage *stage
+	err   error
+}
+
 // ExecuteFFmpeg runs the ffmpeg command, provides progress updates, and checks the result
 func Execute(request *ExecutionRequest) error {
-	commands := strings.Split(request.Command, "&&")
-	for index, cmdStr := range commands {
-		cmdStr = strings.TrimSpace(cmdStr)
-		var args []string
-		var err error
-		if runtime.GOOS == "windows" {
-			args, err = shellwordsUnicodeSafe(cmdStr)
-		} else {
-			args, err = shellwords.NewParser().Parse(cmdStr)
+	commands, err := splitCommand(request.Command)
+	if err != nil {
+		return fmt.Errorf("FFMPEG - failed to parse command: %v", err)
+	}
+	for index, pipeline := range commands {
+		if err := executePipeline(request, pipeline, index == 0); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// executePipeline starts all stages of a pipeline, connects each stage's stdout to the next stage's stdin and waits for all of them to finish
+func executePipeline(request *ExecutionRequest, pipeline []string, first bool) error {
+	ctx, cancel := context.WithCancel(request.Ctx)
+	defer cancel()
+
+	stages := make([]*stage, len(pipeline))
+	for index, cmdStr := range pipeline {
+		var implicit executor
+		if first && index == 0 {
+			implicit = executorFor(request.Executor)
+		}
+		s, err := newStage(ctx, index, cmdStr, implicit)
+		if err != nil {
+			return err
+		}
+		s.cmd.Dir = request.Dir
+		s.cmd.Env = request.Env
+		stages[index] = s
+	}
+
+	// connect stdout of each stage to stdin of the next one
+	var pipes []*os.File
+	closePipes := func() {
+		for _, p := range pipes {
+			p.Close()
 		}
+		pipes = nil
+	}
+	for index := 0; index < len(stages)-1; index++ {
+		r, w, err := os.Pipe()
 		if err != nil {
-			return fmt.Errorf("FFMPEG - failed to parse command: %v", err)
+			closePipes()
+			return fmt.Errorf("FFMPEG - failed to create pipe between stage %d and %d: %v", index+1, index+2, err)
 		}
-		args = append(args, "-progress", "pipe:2")
-		if !strings.Contains(cmdStr, "-stats_period") {
-			args = append(args, "-stats_period", "1")
+		stages[index].cmd.Stdout = w
+		stages[index+1].cmd.Stdin = r
+		pipes = append(pipes, r, w)
+	}
+
+	// only the first stage with a known executor reports progress, every other stage is captured for error reporting
+	progressStage := -1
+	for _, s := range stages {
+		if s.executor != nil {
+			progressStage = s.index
+			break
 		}
-		config.Config().Mutex.RLock()
-		var cmd *exec.Cmd
-		if index > 0 {
-			cmd = exec.CommandContext(request.Ctx, "", args...)
+	}
+
+	for _, s := range stages {
+		if s.index == progressStage {
+			stderrPipe, err := s.cmd.StderrPipe()
+			if err != nil {
+				closePipes()
+				return fmt.Errorf("FFMPEG - failed to get stderr pipe: %v", err)
+			}
+			s.stderrPipe = stderrPipe
 		} else {
-			cmd = exec.CommandContext(request.Ctx, config.Config().FFMpeg, args...)
+			s.cmd.Stderr = &s.stderr
+		}
+	}
+
+	for _, s := range stages {
+		if err := s.cmd.Start(); err != nil {
+			cancel()
+			for _, started := range stages[:s.index] {
+				started.wait()
+			}
+			for _, pending := range stages[s.index+1:] {
+				if pending.stderrPipe != nil {
+					pending.stderrPipe.Close()
+				}
+			}
+			closePipes()
+			if len(stages) == 1 {
+				return fmt.Errorf("FFMPEG - failed to start ffmpeg: %v", err)
+			}
+			return fmt.Errorf("FFMPEG - failed to start pipeline stage %d/%d (%s): %v", s.index+1, len(stages), s.name, err)
+		}
+		if s.stderrPipe != nil {
+			s.scanned = make(chan struct{})
+			go s.scanProgress(s.executor.newParser(s.args, request), request)
 		}
-		config.Config().Mutex.RUnlock()
+		debug.Debugf("started pipeline stage %d/%d '%s' (uuid: %s)", s.index+1, len(stages), s.name, request.Task.Uuid)
+	}
+	// the child processes hold their own copies of the pipe ends
+	closePipes()
 
-		var stderrBuf bytes.Buffer
-		var lastLine string
-		var duration float64
+	// wait for all stages, a failing stage ends the pipeline by closing its end of the pipes
+	errs := make([]error, len(stages))
+	var wg sync.WaitGroup
+	for _, s := range stages {
+		wg.Add(1)
+		go func(s *stage) {
+			defer wg.Done()
+			errs[s.index] = s.wait()
+		}(s)
+	}
+	wg.Wait()
 
-		stderrPipe, err := cmd.StderrPipe()
+	// stages writing into a failed stage die of a broken pipe, the first stage failing for another reason is the cause
+	var failed *stageError
+	for _, s := range stages {
+		if errs[s.index] == nil || request.Ctx.Err() != nil {
+			continue
+		}
+		// the following stages finished without reading all of the output (e.g. head -c), that is not a failure
+		if brokenPipe(s, errs[s.index]) && succeeded(errs[s.index+1:]) {
+			continue
+		}
+		if failed == nil || (brokenPipe(failed.stage, failed.err) && !brokenPipe(s, errs[s.index])) {
+			failed = &stageError{stage: s, err: errs[s.index]}
+		}
+	}
+	if failed == nil && request.Ctx.Err() != nil {
+		failed = &stageError{stage: stages[0], err: request.Ctx.Err()}
+	}
+	if failed == nil {
+		return nil
+	}
+	if len(stages) == 1 {
+		return errors.New(failed.stage.stderr.String())
+	}
+	return fmt.Errorf("FFMPEG - pipeline stage %d/%d (%s) failed: %v\n%s", failed.stage.index+1, len(stages), failed.stage.name, failed.err, failed.stage.stderr.String())
+}
+
+// brokenPipe reports whether the stage failed because the next stage stopped reading its output
+func brokenPipe(s *stage, err error) bool {
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if status, ok := exitErr.Sys().(syscall.WaitStatus); ok && status.Signaled() && status.Signal() == syscall.SIGPIPE {
+			return true
+		}
+	}
+	return errors.Is(err, syscall.EPIPE) || strings.Contains(s.stderr.String(), "Broken pipe")
+}
+
+// succeeded reports whether all stages exited cleanly
+func succeeded(errs []error) bool {
+	for _, err := range errs {
 		if err != nil {
-			return fmt.Errorf("FFMPEG - failed to get stderr pipe: %v", err)
+			return false
 		}
+	}
+	return true
+}
 
-		if err := cmd.Start(); err != nil {
-			return fmt.Errorf("FFMPEG - failed to start ffmpeg: %v", err)
+// newStage parses a single pipeline stage, the very first stage of a command is passed to the binary of the requested executor
+func newStage(ctx context.Context, index int, cmdStr string, implicit executor) (*stage, error) {
+	var args []string
+	var err error
+	if runtime.GOOS == "windows" {
+		args, err = shellwordsUnicodeSafe(cmdStr)
+	} else {
+		args, err = shellwords.NewParser().Parse(cmdStr)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("FFMPEG - failed to parse command: %v", err)
+	}
+
+	s := &stage{index: index, executor: implicit}
+	var binary string
+	if implicit != nil {
+		binary = implicit.binary()
+		s.name = programName(binary)
+		if s.name == "" || s.name == "." {
+			s.name = "ffmpeg"
 		}
+	} else {
+		if len(args) == 0 {
+			return nil, fmt.Errorf("FFMPEG - failed to parse command: empty pipeline stage %d", index+1)
+		}
+		binary = args[0]
+		s.name = args[0]
+		s.executor = executorForProgram(args[0])
+		args = args[1:]
+	}
 
-		reDuration := regexp.MustCompile(`Duration: (\d+:\d+:\d+\.\d+)`)
+	if s.executor != nil {
+		args = s.executor.prepareArgs(args, cmdStr)
+	}
+	s.args = args
+	s.cmd = exec.CommandContext(ctx, binary, args...)
+	return s, nil
+}
 
-		go func() {
-			scanner := bufio.NewScanner(stderrPipe)
-			for scanner.Scan() {
-				line := scanner.Text()
-				stderrBuf.WriteString(line + "\n")
-				lastLine = line
-				if match := reDuration.FindStringSubmatch(line); match != nil {
-					durationStr := match[1]
-					duration = parseDuration(durationStr)
-				}
-				if progress := parseFFmpegOutput(line, duration); progress != nil {
-					p := math.Min(100, math.Round((progress.Time/duration*100)*100)/100)
-					debug.Debugf("progress: %f %+v (uuid: %s)", p, progress, request.Task.Uuid)
-					remainingTime, err := progress.EstimateRemainingTime(duration)
-					if err != nil {
-						debug.Debugf("failed to estimate remaining time: %v", err)
-						remainingTime = -1
-					}
-					request.UpdateFunc(p, remainingTime)
-				}
+// wait waits for the stage's progress scanner, if present, to drain stderr and then for its process
+func (s *stage) wait() error {
+	if s.scanned != nil {
+		<-s.scanned
+	}
+	return s.cmd.Wait()
+}
+
+var reDuration = regexp.MustCompile(`Duration: (\d+:\d+:\d+\.\d+)`)
+
+// scanProgress reads the stderr of a stage and reports its progress
+func (s *stage) scanProgress(parser progressParser, request *ExecutionRequest) {
+	defer close(s.scanned)
+	scanner := bufio.NewScanner(s.stderrPipe)
+	scanner.Split(s.executor.split())
+	for scanner.Scan() {
+		line := scanner.Text()
+		s.stderr.WriteString(line + "\n")
+		if progress, remaining, ok := parser.parse(line); ok {
+			request.UpdateFunc(progress, remaining)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		request.Logger.Warnf("FFMPEG - error reading progress: %v\n", err)
+	}
+}
+
+// CommandArgs returns the arguments of every process of the command, including the binary, exactly as Execute starts them
+func CommandArgs(command string, e dto.Executor) ([][]string, error) {
+	commands, err := splitCommand(command)
+	if err != nil {
+		return nil, fmt.Errorf("FFMPEG - failed to parse command: %v", err)
+	}
+	args := [][]string{}
+	for index, pipeline := range commands {
+		for i, cmdStr := range pipeline {
+			var implicit executor
+			if index == 0 && i == 0 {
+				implicit = executorFor(e)
 			}
-			if err := scanner.Err(); err != nil {
-				request.Logger.Warnf("FFMPEG - error reading progress: %v\n", err)
+			s, err := newStage(context.Background(), i, cmdStr, implicit)
+			if err != nil {
+				return nil, err
 			}
-		}()
-
-		err = cmd.Wait()
-		stderr := stderrBuf.String()
-		if err != nil {
-			return errors.New(stderr)
+			args = append(args, s.cmd.Args)
 		}
-		fmt.Sprintf("last line: %s", lastLine)
 	}
-	return nil
+	return args, nil
 }
@@ -0,0 +1,51 @@
+package ffmpeg
+
+import (
+	"context"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/database/model"
+)
+
+func TestExecutePipelineFailedStage(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a posix shell")
+	}
+	// the first stage runs the configured ffmpeg binary, the progress arguments end up as positional parameters of sh
+	config.Config().FFMpeg = "/bin/sh"
+	defer func() { config.Config().FFMpeg = "" }()
+
+	execute := func(command string) error {
+		return Execute(&ExecutionRequest{
+			Task:       &model.Task{Uuid: "test"},
+			Command:    command,
+			Logger:     logrus.New(),
+			Ctx:        context.Background(),
+			UpdateFunc: func(progress float64, remaining float64) {},
+		})
+	}
+
+	// the writer dies of a broken pipe after the reader failed, the reader is the cause
+	err := execute(`-c "exec yes" | sh -c "sleep 0.2; echo reader failed >&2; exit 3"`)
+	if err == nil || !strings.Contains(err.Error(), "stage 2/2") || !strings.Contains(err.Error(), "reader failed") {
+		t.Errorf("Expected the reading stage to be reported, got: %v", err)
+	}
+
+	// an earlier stage failing on its own is reported even if a later one fails as well
+	err = execute(`-c "echo writer failed >&2; exit 2" | sh -c "exit 3"`)
+	if err == nil || !strings.Contains(err.Error(), "stage 1/2") || !strings.Contains(err.Error(), "writer failed") {
+		t.Errorf("Expected the writing stage to be reported, got: %v", err)
+	}
+
+	if err := execute(`-c "echo ok" | sh -c "cat > /dev/null"`); err != nil {
+		t.Errorf("Expected pipeline to succeed, got: %v", err)
+	}
+	// the writer dies of a broken pipe because the reader stopped early and exited cleanly
+	if err := execute(`-c "exec yes" | sh -c "head -c 10 > /dev/null"`); err != nil {
+		t.Errorf("Expected pipeline with an early exiting reader to succeed, got: %v", err)
+	}
+}
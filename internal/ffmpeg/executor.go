@@ -0,0 +1,129 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"bytes"
+	"math"
+	"path/filepath"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+// executor knows how to invoke an encoder binary and how to read its progress from stderr
+type executor interface {
+	// binary returns the configured binary used for the implicit first stage of a command
+	binary() string
+	// matches reports whether the program of a pipeline stage is handled by this executor
+	matches(program string) bool
+	// prepareArgs adds the arguments required to receive progress output
+	prepareArgs(args []string, cmdStr string) []string
+	// newParser creates a parser for a single run of the given arguments
+	newParser(args []string, request *ExecutionRequest) progressParser
+	// split tokenizes the stderr output into lines
+	split() bufio.SplitFunc
+}
+
+// progressParser turns lines of encoder output into progress updates
+type progressParser interface {
+	// parse returns the progress (0-100) and remaining seconds (-1 if unknown) for a line, ok is false if the line holds no progress
+	parse(line string) (progress float64, remaining float64, ok bool)
+}
+
+var executors = map[dto.Executor]executor{
+	dto.EXECUTOR_FFMPEG: &ffmpegExecutor{},
+	dto.EXECUTOR_SVTAV1: &svtAv1Executor{},
+}
+
+// executorFor returns the executor for the given type, defaulting to ffmpeg
+func executorFor(e dto.Executor) executor {
+	if ex, ok := executors[e]; ok {
+		return ex
+	}
+	return executors[dto.EXECUTOR_FFMPEG]
+}
+
+// executorForProgram returns the executor handling the given program or nil
+func executorForProgram(program string) executor {
+	for _, e := range []dto.Executor{dto.EXECUTOR_FFMPEG, dto.EXECUTOR_SVTAV1} {
+		if executors[e].matches(program) {
+			return executors[e]
+		}
+	}
+	return nil
+}
+
+// programName returns the lower-cased name of a program without path and extension
+func programName(program string) string {
+	return strings.TrimSuffix(strings.ToLower(filepath.Base(program)), ".exe")
+}
+
+type ffmpegExecutor struct{}
+
+func (e *ffmpegExecutor) binary() string {
+	config.Config().Mutex.RLock()
+	defer config.Config().Mutex.RUnlock()
+	return config.Config().FFMpeg
+}
+
+func (e *ffmpegExecutor) matches(program string) bool {
+	if configured := e.binary(); configured != "" && program == configured {
+		return true
+	}
+	return programName(program) == "ffmpeg"
+}
+
+func (e *ffmpegExecutor) prepareArgs(args []string, cmdStr string) []string {
+	args = append(args, "-progress", "pipe:2")
+	if !strings.Contains(cmdStr, "-stats_period") {
+		args = append(args, "-stats_period", "1")
+	}
+	return args
+}
+
+func (e *ffmpegExecutor) newParser(args []string, request *ExecutionRequest) progressParser {
+	return &ffmpegParser{request: request}
+}
+
+func (e *ffmpegExecutor) split() bufio.SplitFunc {
+	return bufio.ScanLines
+}
+
+type ffmpegParser struct {
+	request  *ExecutionRequest
+	duration float64
+}
+
+func (p *ffmpegParser) parse(line string) (float64, float64, bool) {
+	if match := reDuration.FindStringSubmatch(line); match != nil {
+		durationStr := match[1]
+		p.duration = parseDuration(durationStr)
+	}
+	progress := parseFFmpegOutput(line, p.duration)
+	if progress == nil {
+		return 0, 0, false
+	}
+	percent := math.Min(100, math.Round((progress.Time/p.duration*100)*100)/100)
+	debug.Debugf("progress: %f %+v (uuid: %s)", percent, progress, p.request.Task.Uuid)
+	remainingTime, err := progress.EstimateRemainingTime(p.duration)
+	if err != nil {
+		debug.Debugf("failed to estimate remaining time: %v", err)
+		remainingTime = -1
+	}
+	return percent, remainingTime, true
+}
+
+// scanLinesOrCR splits on '\n' as well as on '\r' as encoders tend to rewrite their progress line
+func scanLinesOrCR(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	if atEOF && len(data) == 0 {
+		return 0, nil, nil
+	}
+	if i := bytes.IndexAny(data, "\r\n"); i >= 0 {
+		return i + 1, data[0:i], nil
+	}
+	if atEOF {
+		return len(data), data, nil
+	}
+	return 0, nil, nil
+}
@@ -0,0 +1,126 @@
+package ffmpeg
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/config"
+)
+
+// ProbeResult holds the parts of ffprobe's output ffmate cares about
+type ProbeResult struct {
+	Format  ProbeFormat   `json:"format"`
+	Streams []ProbeStream `json:"streams"`
+}
+
+type ProbeFormat struct {
+	FormatName string `json:"format_name"`
+	Duration   string `json:"duration"`
+	Size       string `json:"size"`
+	BitRate    string `json:"bit_rate"`
+}
+
+type ProbeStream struct {
+	Index        int    `json:"index"`
+	CodecType    string `json:"codec_type"`
+	CodecName    string `json:"codec_name"`
+	Width        int    `json:"width,omitempty"`
+	Height       int    `json:"height,omitempty"`
+	PixFmt       string `json:"pix_fmt,omitempty"`
+	RFrameRate   string `json:"r_frame_rate,omitempty"`
+	AvgFrameRate string `json:"avg_frame_rate,omitempty"`
+	NbFrames     string `json:"nb_frames,omitempty"`
+	Duration     string `json:"duration,omitempty"`
+}
+
+// FFProbe returns the path to the ffprobe binary living next to the configured ffmpeg binary
+func FFProbe() string {
+	config.Config().Mutex.RLock()
+	ffmpeg := config.Config().FFMpeg
+	config.Config().Mutex.RUnlock()
+
+	if ffmpeg != "" {
+		name := "ffprobe"
+		if strings.HasSuffix(strings.ToLower(ffmpeg), ".exe") {
+			name += ".exe"
+		}
+		probe := filepath.Join(filepath.Dir(ffmpeg), name)
+		if _, err := os.Stat(probe); err == nil {
+			return probe
+		}
+	}
+	return "ffprobe"
+}
+
+// Probe runs ffprobe against the given file
+func Probe(ctx context.Context, path string) (*ProbeResult, error) {
+	cmd := exec.CommandContext(ctx, FFProbe(), "-v", "error", "-print_format", "json", "-show_format", "-show_streams", path)
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("FFPROBE - failed to probe '%s': %s", path, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("FFPROBE - failed to probe '%s': %v", path, err)
+	}
+	result := &ProbeResult{}
+	if err := json.Unmarshal(out, result); err != nil {
+		return nil, fmt.Errorf("FFPROBE - failed to parse probe result of '%s': %v", path, err)
+	}
+	return result, nil
+}
+
+// Duration returns the container duration in seconds
+func (p *ProbeResult) Duration() float64 {
+	d, _ := strconv.ParseFloat(p.Format.Duration, 64)
+	return d
+}
+
+// VideoStream returns the first video stream or nil
+func (p *ProbeResult) VideoStream() *ProbeStream {
+	for i := range p.Streams {
+		if p.Streams[i].CodecType == "video" {
+			return &p.Streams[i]
+		}
+	}
+	return nil
+}
+
+// FrameCount returns the number of frames of the first video stream, estimated from duration and frame rate if the container does not state it
+func (p *ProbeResult) FrameCount() int {
+	v := p.VideoStream()
+	if v == nil {
+		return 0
+	}
+	if n, err := strconv.Atoi(v.NbFrames); err == nil && n > 0 {
+		return n
+	}
+	duration, _ := strconv.ParseFloat(v.Duration, 64)
+	if duration == 0 {
+		duration = p.Duration()
+	}
+	return int(duration*v.FrameRate() + 0.5)
+}
+
+// FrameRate returns the frame rate of the stream in frames per second
+func (s *ProbeStream) FrameRate() float64 {
+	rate := s.AvgFrameRate
+	if rate == "" || rate == "0/0" {
+		rate = s.RFrameRate
+	}
+	num, den, found := strings.Cut(rate, "/")
+	n, _ := strconv.ParseFloat(num, 64)
+	if !found {
+		return n
+	}
+	d, _ := strconv.ParseFloat(den, 64)
+	if d == 0 {
+		return 0
+	}
+	return n / d
+}
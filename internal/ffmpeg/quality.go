@@ -0,0 +1,152 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+// QualitySearchDefaults fills unset fields of a quality search with sane defaults
+func QualitySearchDefaults(q *dto.NewQualitySearch) {
+	if q.Metric == "" {
+		q.Metric = dto.QUALITY_METRIC_VMAF
+	}
+	if q.Samples == 0 {
+		q.Samples = 3
+	}
+	if q.SampleDuration == 0 {
+		q.SampleDuration = 5
+	}
+	if q.MinCrf == 0 {
+		q.MinCrf = 20
+	}
+	if q.MaxCrf == 0 {
+		q.MaxCrf = 50
+	}
+}
+
+// ValidateQualitySearch checks the quality search settings and makes sure the command is able to receive the searched CRF
+func ValidateQualitySearch(q *dto.NewQualitySearch, command string, encoder *dto.EncoderSettings) error {
+	if q == nil {
+		return nil
+	}
+	QualitySearchDefaults(q)
+
+	switch q.Metric {
+	case dto.QUALITY_METRIC_VMAF:
+		if q.Target <= 0 || q.Target > 100 {
+			return fmt.Errorf("qualitySearch: vmaf target must be between 0 and 100 (got: %g)", q.Target)
+		}
+	case dto.QUALITY_METRIC_SSIM:
+		if q.Target <= 0 || q.Target > 1 {
+			return fmt.Errorf("qualitySearch: ssim target must be between 0 and 1 (got: %g)", q.Target)
+		}
+	default:
+		return fmt.Errorf("qualitySearch: unsupported metric '%s'", q.Metric)
+	}
+	if q.Samples < 1 || q.Samples > 10 {
+		return fmt.Errorf("qualitySearch: samples must be between 1 and 10 (got: %d)", q.Samples)
+	}
+	if q.SampleDuration < 1 || q.SampleDuration > 60 {
+		return fmt.Errorf("qualitySearch: sampleDuration must be between 1 and 60 seconds (got: %g)", q.SampleDuration)
+	}
+	if q.MinCrf < 1 || q.MaxCrf > 63 || q.MinCrf >= q.MaxCrf {
+		return fmt.Errorf("qualitySearch: minCrf and maxCrf must be between 1 and 63 with minCrf < maxCrf (got: %d-%d)", q.MinCrf, q.MaxCrf)
+	}
+
+	usesEncoderArgs := encoder != nil && strings.Contains(command, "${ENCODER_ARGS}")
+	if !strings.Contains(command, "${CRF}") && !usesEncoderArgs {
+		return fmt.Errorf("qualitySearch: command must contain ${CRF} or ${ENCODER_ARGS} together with encoder settings")
+	}
+	if !usesEncoderArgs {
+		if arg := unsupportedSampleArg(command); arg != "" {
+			return fmt.Errorf("qualitySearch: command argument '%s' can not be applied to the video-only samples, use ${ENCODER_ARGS} together with encoder settings instead", arg)
+		}
+	}
+	if encoder != nil && encoder.TargetBitrate != nil {
+		return fmt.Errorf("qualitySearch: can not be combined with encoder targetBitrate")
+	}
+	return nil
+}
+
+var reNonVideoMap = regexp.MustCompile(`:[asdt](:|$)`)
+
+// unsupportedSampleArg returns the first argument of the command that maps non-video streams or changes the video's dimensions,
+// the samples only hold the first video stream and libvmaf requires the encode to match the reference resolution
+func unsupportedSampleArg(command string) string {
+	args := strings.Fields(command)
+	for i, arg := range args {
+		switch arg {
+		case "-vf", "-filter:v", "-filter_complex", "-lavfi", "-s", "-s:v":
+			return arg
+		case "-map":
+			if i+1 < len(args) && reNonVideoMap.MatchString(args[i+1]) && !strings.HasSuffix(args[i+1], "?") {
+				return arg + " " + args[i+1]
+			}
+		}
+	}
+	return ""
+}
+
+// ExtractSample cuts a losslessly encoded video-only segment out of the input to be used as quality reference
+func ExtractSample(ctx context.Context, input string, offset float64, duration float64, output string, y4m bool) error {
+	args := []string{"-y", "-v", "error", "-ss", strconv.FormatFloat(offset, 'f', 3, 64), "-i", input, "-t", strconv.FormatFloat(duration, 'f', 3, 64), "-map", "0:v:0", "-an", "-sn", "-dn"}
+	if y4m {
+		args = append(args, "-f", "yuv4mpegpipe", "-strict", "-1")
+	} else {
+		args = append(args, "-c:v", "ffv1")
+	}
+	args = append(args, output)
+	return runFFmpeg(ctx, args)
+}
+
+var (
+	reVmafScore = regexp.MustCompile(`VMAF score[:=]\s*([\d.]+)`)
+	reSsimScore = regexp.MustCompile(`SSIM .*All:([\d.]+)`)
+)
+
+// MeasureQuality compares the distorted file against the reference using libvmaf or the ssim filter
+func MeasureQuality(ctx context.Context, metric dto.QualityMetric, distorted string, reference string) (float64, error) {
+	var filter string
+	var re *regexp.Regexp
+	switch metric {
+	case dto.QUALITY_METRIC_SSIM:
+		filter, re = "[0:v][1:v]ssim", reSsimScore
+	default:
+		filter, re = "[0:v][1:v]libvmaf", reVmafScore
+	}
+
+	out, err := ffmpegOutput(ctx, []string{"-hide_banner", "-nostats", "-i", distorted, "-i", reference, "-lavfi", filter, "-f", "null", "-"})
+	if err != nil {
+		return 0, err
+	}
+	match := re.FindStringSubmatch(out)
+	if match == nil {
+		return 0, fmt.Errorf("FFMPEG - failed to find %s score in output", metric)
+	}
+	return strconv.ParseFloat(match[1], 64)
+}
+
+// runFFmpeg runs a helper ffmpeg command and returns its stderr as error on failure
+func runFFmpeg(ctx context.Context, args []string) error {
+	_, err := ffmpegOutput(ctx, args)
+	return err
+}
+
+// ffmpegOutput runs a helper ffmpeg command and returns its combined output
+func ffmpegOutput(ctx context.Context, args []string) (string, error) {
+	binary := executorFor(dto.EXECUTOR_FFMPEG).binary()
+	if binary == "" {
+		binary = "ffmpeg"
+	}
+	out, err := exec.CommandContext(ctx, binary, args...).CombinedOutput()
+	if err != nil {
+		return string(out), fmt.Errorf("FFMPEG - %v: %s", err, strings.TrimSpace(string(out)))
+	}
+	return string(out), nil
+}
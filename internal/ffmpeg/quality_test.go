@@ -0,0 +1,86 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestValidateQualitySearch(t *testing.T) {
+	tests := []struct {
+		name    string
+		search  *dto.NewQualitySearch
+		command string
+		encoder *dto.EncoderSettings
+		wantErr bool
+	}{
+		{
+			name:    "Defaults with crf wildcard",
+			search:  &dto.NewQualitySearch{Target: 93},
+			command: "-i ${INPUT_FILE} -c:v libsvtav1 -crf ${CRF} ${OUTPUT_FILE}",
+		},
+		{
+			name:    "Encoder args",
+			search:  &dto.NewQualitySearch{Metric: dto.QUALITY_METRIC_SSIM, Target: 0.98},
+			command: "-i ${INPUT_FILE} ${ENCODER_ARGS} ${OUTPUT_FILE}",
+			encoder: &dto.EncoderSettings{Preset: intPtr(8)},
+		},
+		{
+			name:    "Encoder args with audio and filters",
+			search:  &dto.NewQualitySearch{Target: 93},
+			command: "-i ${INPUT_FILE} -map 0:v -map 0:a -vf scale=1280:-2 ${ENCODER_ARGS} -c:a copy ${OUTPUT_FILE}",
+			encoder: &dto.EncoderSettings{Preset: intPtr(8)},
+		},
+		{
+			name:    "Optional audio map with crf wildcard",
+			search:  &dto.NewQualitySearch{Target: 93},
+			command: "-i ${INPUT_FILE} -map 0:v -map 0:a? -c:v libx264 -crf ${CRF} ${OUTPUT_FILE}",
+		},
+		{
+			name:    "Audio map with crf wildcard",
+			search:  &dto.NewQualitySearch{Target: 93},
+			command: "-i ${INPUT_FILE} -map 0:v -map 0:a -c:v libx264 -crf ${CRF} ${OUTPUT_FILE}",
+			wantErr: true,
+		},
+		{
+			name:    "Scale filter with crf wildcard",
+			search:  &dto.NewQualitySearch{Target: 93},
+			command: "-i ${INPUT_FILE} -vf scale=1280:-2 -c:v libx264 -crf ${CRF} ${OUTPUT_FILE}",
+			wantErr: true,
+		},
+		{
+			name:    "Missing crf wildcard",
+			search:  &dto.NewQualitySearch{Target: 93},
+			command: "-i ${INPUT_FILE} -c:v libsvtav1 -crf 30 ${OUTPUT_FILE}",
+			wantErr: true,
+		},
+		{
+			name:    "SSIM target out of range",
+			search:  &dto.NewQualitySearch{Metric: dto.QUALITY_METRIC_SSIM, Target: 93},
+			command: "-crf ${CRF}",
+			wantErr: true,
+		},
+		{
+			name:    "Inverted crf range",
+			search:  &dto.NewQualitySearch{Target: 93, MinCrf: 40, MaxCrf: 30},
+			command: "-crf ${CRF}",
+			wantErr: true,
+		},
+		{
+			name:    "Target bitrate",
+			search:  &dto.NewQualitySearch{Target: 93},
+			command: "${ENCODER_ARGS}",
+			encoder: &dto.EncoderSettings{TargetBitrate: intPtr(3000)},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateQualitySearch(tt.search, tt.command, tt.encoder)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ValidateQualitySearch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package ffmpeg
+
+import (
+	"bufio"
+	"math"
+	"regexp"
+	"strconv"
+
+	"github.com/welovemedia/ffmate/internal/config"
+)
+
+type svtAv1Executor struct{}
+
+func (e *svtAv1Executor) binary() string {
+	config.Config().Mutex.RLock()
+	defer config.Config().Mutex.RUnlock()
+	if config.Config().SvtAv1EncApp == "" {
+		return "SvtAv1EncApp"
+	}
+	return config.Config().SvtAv1EncApp
+}
+
+func (e *svtAv1Executor) matches(program string) bool {
+	if program == e.binary() {
+		return true
+	}
+	return programName(program) == "svtav1encapp"
+}
+
+// prepareArgs leaves the arguments untouched as SvtAv1EncApp reports its progress on stderr by default
+func (e *svtAv1Executor) prepareArgs(args []string, cmdStr string) []string {
+	return args
+}
+
+// newParser determines the total frame count from the -n argument or by probing the input file
+func (e *svtAv1Executor) newParser(args []string, request *ExecutionRequest) progressParser {
+	p := &svtAv1Parser{request: request}
+	var input string
+	for i := 0; i < len(args)-1; i++ {
+		switch args[i] {
+		case "-n", "--frames":
+			p.total, _ = strconv.Atoi(args[i+1])
+		case "-i", "--input":
+			input = args[i+1]
+		}
+	}
+	if p.total == 0 && input != "" && input != "stdin" && input != "-" {
+		probe, err := Probe(request.Ctx, input)
+		if err != nil {
+			debug.Debugf("failed to probe frame count of SvtAv1EncApp input: %v (uuid: %s)", err, request.Task.Uuid)
+		} else {
+			p.total = probe.FrameCount()
+		}
+	}
+	debug.Debugf("SvtAv1EncApp total frames: %d (uuid: %s)", p.total, request.Task.Uuid)
+	return p
+}
+
+func (e *svtAv1Executor) split() bufio.SplitFunc {
+	return scanLinesOrCR
+}
+
+// svtAv1Parser parses SvtAv1EncApp's "Encoding frame N ... fps" progress output as well as the "Encoding: N/M Frames @ X fps" variant of --progress 2
+type svtAv1Parser struct {
+	request *ExecutionRequest
+	total   int
+}
+
+var (
+	reSvtAv1Frame    = regexp.MustCompile(`Encoding frame\s+(\d+)(?:.*?([\d.]+)\s*fps)?`)
+	reSvtAv1Progress = regexp.MustCompile(`Encoding:\s*(\d+)\s*/\s*(\d+)\s*Frames\s*@\s*([\d.]+)\s*fp([sm])`)
+)
+
+func (p *svtAv1Parser) parse(line string) (float64, float64, bool) {
+	var frame int
+	var fps float64
+	if match := reSvtAv1Progress.FindStringSubmatch(line); match != nil {
+		frame, _ = strconv.Atoi(match[1])
+		if total, _ := strconv.Atoi(match[2]); total > 0 {
+			p.total = total
+		}
+		fps, _ = strconv.ParseFloat(match[3], 64)
+		if match[4] == "m" {
+			fps /= 60
+		}
+	} else if match := reSvtAv1Frame.FindStringSubmatch(line); match != nil {
+		frame, _ = strconv.Atoi(match[1])
+		fps, _ = strconv.ParseFloat(match[2], 64)
+	} else {
+		return 0, 0, false
+	}
+
+	if p.total == 0 {
+		debug.Debugf("progress: frame %d at %f fps without known total (uuid: %s)", frame, fps, p.request.Task.Uuid)
+		return 0, 0, false
+	}
+
+	percent := math.Min(100, math.Round(float64(frame)/float64(p.total)*100*100)/100)
+	remaining := float64(-1)
+	if fps > 0 {
+		remaining = math.Round(math.Max(0, float64(p.total-frame)) / fps)
+	}
+	debug.Debugf("progress: %f frame %d/%d at %f fps (uuid: %s)", percent, frame, p.total, fps, p.request.Task.Uuid)
+	return percent, remaining, true
+}
@@ -0,0 +1,53 @@
+package ffmpeg
+
+import (
+	"context"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+)
+
+func TestSvtAv1Parser(t *testing.T) {
+	request := &ExecutionRequest{Task: &model.Task{Uuid: "test"}, Ctx: context.Background()}
+
+	t.Run("Total frames from -n argument", func(t *testing.T) {
+		parser := (&svtAv1Executor{}).newParser([]string{"-i", "stdin", "-n", "200", "-b", "out.ivf"}, request)
+
+		progress, remaining, ok := parser.parse("Encoding frame   50 1234.56 kbps 25.00 fps")
+		if !ok {
+			t.Fatal("Expected line to be parsed as progress")
+		}
+		if progress != 25 {
+			t.Errorf("Expected progress 25, got %f", progress)
+		}
+		if remaining != 6 {
+			t.Errorf("Expected remaining 6, got %f", remaining)
+		}
+	})
+
+	t.Run("Total frames from progress line", func(t *testing.T) {
+		parser := (&svtAv1Executor{}).newParser([]string{"-i", "stdin", "-b", "out.ivf"}, request)
+
+		if _, _, ok := parser.parse("Encoding frame   50 1234.56 kbps 25.00 fps"); ok {
+			t.Error("Expected no progress without a known total")
+		}
+
+		progress, remaining, ok := parser.parse("Encoding:  100/ 400 Frames @ 10.00 fps | 1234.56 kbps | Time: 0:00:10 [-0:00:30] | Size: 1.23 MB [4.92 MB]")
+		if !ok {
+			t.Fatal("Expected line to be parsed as progress")
+		}
+		if progress != 25 {
+			t.Errorf("Expected progress 25, got %f", progress)
+		}
+		if remaining != 30 {
+			t.Errorf("Expected remaining 30, got %f", remaining)
+		}
+	})
+
+	t.Run("Non progress lines", func(t *testing.T) {
+		parser := (&svtAv1Executor{}).newParser([]string{"-n", "10"}, request)
+		if _, _, ok := parser.parse("Svt[info]: SVT [version]:	SVT-AV1 Encoder Lib v2.1.0"); ok {
+			t.Error("Expected info line to be ignored")
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+)
+
+// Thumbnail writes a single frame of the input as image, a width of 0 keeps the size of the input
+func Thumbnail(ctx context.Context, input string, offset float64, width int, output string) error {
+	args := []string{"-y", "-v", "error", "-ss", strconv.FormatFloat(offset, 'f', 3, 64), "-i", input, "-frames:v", "1"}
+	if width > 0 {
+		args = append(args, "-vf", fmt.Sprintf("scale=%d:-2", width))
+	}
+	args = append(args, output)
+	return runFFmpeg(ctx, args)
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/sirupsen/logrus"
 	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
 )
 
 // FFmpegProgress holds parsed progress data
@@ -43,6 +44,12 @@ type ExecutionRequest struct {
 
 	Command string
 
+	Executor dto.Executor
+
+	Dir string // working directory of all stages
+
+	Env []string // environment of all stages, the server environment if empty
+
 	Logger *logrus.Logger
 
 	UpdateFunc func(progress float64, remaining float64)
@@ -3,6 +3,7 @@ package ffmpeg
 import (
 	"fmt"
 	"regexp"
+	"runtime"
 	"strconv"
 	"strings"
 )
@@ -57,3 +58,57 @@ func parseFFmpegOutput(line string, duration float64) *FFmpegProgress {
 	}
 	return progress
 }
+
+// splitCommand splits a command into its `&&` separated commands and each of those into its `|` separated pipeline stages.
+// Quotes and escapes are preserved so the stages can be handed to the shellwords parser afterwards.
+func splitCommand(command string) ([][]string, error) {
+	var commands [][]string
+	var stages []string
+	var current strings.Builder
+	var quoteChar rune
+	escaped := false
+	escapes := runtime.GOOS != "windows"
+
+	runes := []rune(command)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		switch {
+		case escaped:
+			escaped = false
+		case r == '\\' && escapes && quoteChar != '\'':
+			escaped = true
+		case quoteChar != 0:
+			if r == quoteChar {
+				quoteChar = 0
+			}
+		case r == '"' || r == '\'':
+			quoteChar = r
+		case r == '|':
+			stages = append(stages, strings.TrimSpace(current.String()))
+			current.Reset()
+			continue
+		case r == '&' && i+1 < len(runes) && runes[i+1] == '&':
+			stages = append(stages, strings.TrimSpace(current.String()))
+			commands = append(commands, stages)
+			stages = nil
+			current.Reset()
+			i++
+			continue
+		}
+		current.WriteRune(r)
+	}
+	if quoteChar != 0 {
+		return nil, fmt.Errorf("unclosed quote")
+	}
+	stages = append(stages, strings.TrimSpace(current.String()))
+	commands = append(commands, stages)
+
+	for _, stages := range commands {
+		for index, stage := range stages {
+			if stage == "" && (len(stages) > 1 || len(commands) > 1) {
+				return nil, fmt.Errorf("empty pipeline stage %d", index+1)
+			}
+		}
+	}
+	return commands, nil
+}
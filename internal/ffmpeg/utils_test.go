@@ -0,0 +1,58 @@
+package ffmpeg
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command string
+		want    [][]string
+		wantErr bool
+	}{
+		{
+			name:    "Single command",
+			command: "-i input.mp4 output.mp4",
+			want:    [][]string{{"-i input.mp4 output.mp4"}},
+		},
+		{
+			name:    "Pipeline",
+			command: "-i input.mp4 -f yuv4mpegpipe - | SvtAv1EncApp -i stdin -b out.ivf",
+			want:    [][]string{{"-i input.mp4 -f yuv4mpegpipe -", "SvtAv1EncApp -i stdin -b out.ivf"}},
+		},
+		{
+			name:    "Sequential commands with pipelines",
+			command: "-i a.mp4 -f nut - | ffmpeg -i - b.mp4 && mp4box -add b.mp4 c.mp4",
+			want:    [][]string{{"-i a.mp4 -f nut -", "ffmpeg -i - b.mp4"}, {"mp4box -add b.mp4 c.mp4"}},
+		},
+		{
+			name:    "Quoted pipes and ampersands",
+			command: `-i "a | b.mp4" -metadata title='x && y' out.mp4`,
+			want:    [][]string{{`-i "a | b.mp4" -metadata title='x && y' out.mp4`}},
+		},
+		{
+			name:    "Empty stage",
+			command: "-i a.mp4 - | | cat",
+			wantErr: true,
+		},
+		{
+			name:    "Unclosed quote",
+			command: `-i "a.mp4 | cat`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitCommand(tt.command)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitCommand() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
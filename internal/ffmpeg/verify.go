@@ -0,0 +1,156 @@
+package ffmpeg
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+// VerificationDefaults fills unset fields of the verification settings with sane defaults
+func VerificationDefaults(v *dto.NewVerification) {
+	if v.DurationTolerance == nil {
+		tolerance := 1.0
+		v.DurationTolerance = &tolerance
+	}
+}
+
+// ValidateVerification checks the verification settings
+func ValidateVerification(v *dto.NewVerification) error {
+	if v == nil {
+		return nil
+	}
+	VerificationDefaults(v)
+
+	if *v.DurationTolerance < 0 {
+		return fmt.Errorf("verification: durationTolerance must not be negative (got: %g)", *v.DurationTolerance)
+	}
+	if v.VideoStreams != nil && *v.VideoStreams < 0 {
+		return fmt.Errorf("verification: videoStreams must not be negative (got: %d)", *v.VideoStreams)
+	}
+	if v.AudioStreams != nil && *v.AudioStreams < 0 {
+		return fmt.Errorf("verification: audioStreams must not be negative (got: %d)", *v.AudioStreams)
+	}
+	return nil
+}
+
+// Verify probes the output and runs all configured checks, the returned error lists every failed check
+func Verify(ctx context.Context, v *dto.NewVerification, output string, expectedDuration float64) ([]dto.VerificationCheck, error) {
+	var checks []dto.VerificationCheck
+
+	info, err := os.Stat(output)
+	if err != nil || info.Size() == 0 {
+		actual := "missing"
+		if err == nil {
+			actual = "0 bytes"
+		}
+		checks = append(checks, dto.VerificationCheck{Name: "size", Passed: false, Expected: "> 0 bytes", Actual: actual})
+		return checks, verificationError(checks)
+	}
+	checks = append(checks, dto.VerificationCheck{Name: "size", Passed: true, Actual: fmt.Sprintf("%d bytes", info.Size())})
+
+	probe, err := Probe(ctx, output)
+	if err != nil {
+		checks = append(checks, dto.VerificationCheck{Name: "probe", Passed: false, Actual: err.Error()})
+		return checks, verificationError(checks)
+	}
+	checks = append(checks, verifyProbe(v, probe, expectedDuration)...)
+
+	if v.Decode {
+		check := dto.VerificationCheck{Name: "decode", Passed: true}
+		if out, err := ffmpegOutput(ctx, []string{"-hide_banner", "-nostats", "-v", "error", "-xerror", "-i", output, "-map", "0", "-f", "null", "-"}); err != nil {
+			check.Passed = false
+			check.Actual = strings.TrimSpace(out)
+			if check.Actual == "" {
+				check.Actual = err.Error()
+			}
+		}
+		checks = append(checks, check)
+	}
+
+	return checks, verificationError(checks)
+}
+
+// verifyProbe compares the probed output against the expected duration, stream counts and codecs
+func verifyProbe(v *dto.NewVerification, probe *ProbeResult, expectedDuration float64) []dto.VerificationCheck {
+	var checks []dto.VerificationCheck
+
+	var video, audio []ProbeStream
+	for _, s := range probe.Streams {
+		switch s.CodecType {
+		case "video":
+			video = append(video, s)
+		case "audio":
+			audio = append(audio, s)
+		}
+	}
+	checks = append(checks, dto.VerificationCheck{Name: "streams", Passed: len(probe.Streams) > 0, Expected: "> 0", Actual: strconv.Itoa(len(probe.Streams))})
+
+	if !v.SkipDuration && expectedDuration > 0 {
+		duration := probe.Duration()
+		checks = append(checks, dto.VerificationCheck{
+			Name:     "duration",
+			Passed:   math.Abs(duration-expectedDuration) <= *v.DurationTolerance,
+			Expected: fmt.Sprintf("%.3fs (±%gs)", expectedDuration, *v.DurationTolerance),
+			Actual:   fmt.Sprintf("%.3fs", duration),
+		})
+	}
+
+	if len(video) > 0 {
+		frames := probe.FrameCount()
+		checks = append(checks, dto.VerificationCheck{Name: "frames", Passed: frames > 0, Expected: "> 0", Actual: strconv.Itoa(frames)})
+	}
+
+	if v.VideoStreams != nil {
+		checks = append(checks, dto.VerificationCheck{Name: "videoStreams", Passed: len(video) == *v.VideoStreams, Expected: strconv.Itoa(*v.VideoStreams), Actual: strconv.Itoa(len(video))})
+	}
+	if v.AudioStreams != nil {
+		checks = append(checks, dto.VerificationCheck{Name: "audioStreams", Passed: len(audio) == *v.AudioStreams, Expected: strconv.Itoa(*v.AudioStreams), Actual: strconv.Itoa(len(audio))})
+	}
+	if v.VideoCodec != "" {
+		checks = append(checks, verifyCodec("videoCodec", v.VideoCodec, video))
+	}
+	if v.AudioCodec != "" {
+		checks = append(checks, verifyCodec("audioCodec", v.AudioCodec, audio))
+	}
+	return checks
+}
+
+// verifyCodec passes if every stream of the type uses the expected codec
+func verifyCodec(name string, expected string, streams []ProbeStream) dto.VerificationCheck {
+	var codecs []string
+	passed := len(streams) > 0
+	for _, s := range streams {
+		codecs = append(codecs, s.CodecName)
+		if !strings.EqualFold(s.CodecName, expected) {
+			passed = false
+		}
+	}
+	actual := strings.Join(codecs, ",")
+	if actual == "" {
+		actual = "none"
+	}
+	return dto.VerificationCheck{Name: name, Passed: passed, Expected: expected, Actual: actual}
+}
+
+func verificationError(checks []dto.VerificationCheck) error {
+	var failed []string
+	for _, c := range checks {
+		if c.Passed {
+			continue
+		}
+		if c.Expected != "" {
+			failed = append(failed, fmt.Sprintf("%s: expected %s, got %s", c.Name, c.Expected, c.Actual))
+		} else {
+			failed = append(failed, fmt.Sprintf("%s: %s", c.Name, c.Actual))
+		}
+	}
+	if len(failed) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s", strings.Join(failed, "; "))
+}
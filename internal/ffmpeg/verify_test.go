@@ -0,0 +1,98 @@
+package ffmpeg
+
+import (
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
+
+func TestVerifyProbe(t *testing.T) {
+	probe := &ProbeResult{
+		Format: ProbeFormat{Duration: "59.5"},
+		Streams: []ProbeStream{
+			{CodecType: "video", CodecName: "av1", NbFrames: "1428"},
+			{CodecType: "audio", CodecName: "opus"},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		v        *dto.NewVerification
+		expected float64
+		failed   []string
+	}{
+		{
+			name:     "Duration within tolerance",
+			v:        &dto.NewVerification{DurationTolerance: floatPtr(1)},
+			expected: 60,
+		},
+		{
+			name:     "Truncated output",
+			v:        &dto.NewVerification{DurationTolerance: floatPtr(1)},
+			expected: 120,
+			failed:   []string{"duration"},
+		},
+		{
+			name:     "Skip duration",
+			v:        &dto.NewVerification{DurationTolerance: floatPtr(1), SkipDuration: true},
+			expected: 120,
+		},
+		{
+			name:     "Exact duration",
+			v:        &dto.NewVerification{DurationTolerance: floatPtr(0)},
+			expected: 60,
+			failed:   []string{"duration"},
+		},
+		{
+			name:   "Codecs and streams",
+			v:      &dto.NewVerification{VideoCodec: "AV1", AudioCodec: "aac", VideoStreams: intPtr(1), AudioStreams: intPtr(2)},
+			failed: []string{"audioStreams", "audioCodec"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var failed []string
+			for _, c := range verifyProbe(tt.v, probe, tt.expected) {
+				if !c.Passed {
+					failed = append(failed, c.Name)
+				}
+			}
+			if len(failed) != len(tt.failed) {
+				t.Fatalf("verifyProbe() failed checks = %v, want %v", failed, tt.failed)
+			}
+			for i := range failed {
+				if failed[i] != tt.failed[i] {
+					t.Errorf("verifyProbe() failed checks = %v, want %v", failed, tt.failed)
+				}
+			}
+		})
+	}
+}
+
+func TestVerifyMissingOutput(t *testing.T) {
+	checks, err := Verify(t.Context(), &dto.NewVerification{}, "/nonexistent/output.mkv", 10)
+	if err == nil {
+		t.Fatal("Verify() succeeded for missing output")
+	}
+	if len(checks) != 1 || checks[0].Name != "size" || checks[0].Passed {
+		t.Errorf("Verify() checks = %+v", checks)
+	}
+}
+
+func TestVerificationDefaults(t *testing.T) {
+	v := &dto.NewVerification{}
+	VerificationDefaults(v)
+	if v.DurationTolerance == nil || *v.DurationTolerance != 1 {
+		t.Errorf("Expected omitted durationTolerance to default to 1, got %v", v.DurationTolerance)
+	}
+	v = &dto.NewVerification{DurationTolerance: floatPtr(0)}
+	VerificationDefaults(v)
+	if *v.DurationTolerance != 0 {
+		t.Errorf("Expected durationTolerance 0 to be kept, got %g", *v.DurationTolerance)
+	}
+}
@@ -31,6 +31,8 @@ func Init(s *sev.Sev, concurrentTasks uint, frontend embed.FS) {
 	(&repository.Webhook{DB: s.DB()}).Setup()
 	(&repository.Preset{DB: s.DB()}).Setup()
 	(&repository.Watchfolder{DB: s.DB()}).Setup()
+	(&repository.WatchfolderFile{DB: s.DB()}).Setup()
+	(&repository.Secret{DB: s.DB()}).Setup()
 
 	// setup metrics
 	metrics := &metrics.Metrics{}
@@ -60,6 +62,9 @@ func Init(s *sev.Sev, concurrentTasks uint, frontend embed.FS) {
 	s.RegisterController(&controller.WebsocketController{Prefix: prefix})
 	s.RegisterController(&controller.UmamiController{Prefix: prefix})
 	s.RegisterController(&controller.ClientController{Prefix: prefix})
+	s.RegisterController(&controller.QueueController{Prefix: prefix})
+	s.RegisterController(&controller.WildcardController{Prefix: prefix})
+	s.RegisterController(&controller.SecretController{Prefix: prefix})
 
 	// Initialize queue processor
 	(&queue.Queue{
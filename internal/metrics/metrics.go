@@ -27,6 +27,9 @@ var gauges = map[string]prometheus.Gauge{
 	"watchfolder.executed": prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "watchfolder_executed", Help: "Number of executed watchfolders"}),
 	"watchfolder.updated":  prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "watchfolder_updated", Help: "Number of updated watchfolder"}),
 	"watchfolder.deleted":  prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "watchfolder_deleted", Help: "Number of deleted watchfolders"}),
+	"watchfolder.failing":  prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "watchfolder_failing", Help: "Number of times a watchfolder started failing"}),
+
+	"queue.held": prometheus.NewGauge(prometheus.GaugeOpts{Namespace: namespace, Name: "queue_held", Help: "Number of times the queue was held"}),
 }
 
 var gaugesVec = map[string]*prometheus.GaugeVec{
@@ -0,0 +1,125 @@
+package presets
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+	"gopkg.in/yaml.v3"
+)
+
+// BundleVersion is the version of the bundle format written by Encode
+const BundleVersion = 1
+
+type Format string
+
+const (
+	FORMAT_JSON Format = "json"
+	FORMAT_YAML Format = "yaml"
+)
+
+// Encode writes the bundle as JSON or YAML, YAML uses the same field names as JSON
+func Encode(bundle *dto.PresetBundle, format Format) ([]byte, error) {
+	b, err := json.MarshalIndent(bundle, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case FORMAT_JSON, "":
+		return b, nil
+	case FORMAT_YAML:
+		var document any
+		if err := json.Unmarshal(b, &document); err != nil {
+			return nil, err
+		}
+		return yaml.Marshal(document)
+	}
+	return nil, fmt.Errorf("unsupported format '%s', use json or yaml", format)
+}
+
+// Decode reads a JSON or YAML bundle
+func Decode(b []byte) (*dto.PresetBundle, error) {
+	if !json.Valid(b) {
+		var document any
+		if err := yaml.Unmarshal(b, &document); err != nil {
+			return nil, fmt.Errorf("invalid bundle: %v", err)
+		}
+		var err error
+		if b, err = json.Marshal(document); err != nil {
+			return nil, fmt.Errorf("invalid bundle: %v", err)
+		}
+	}
+	bundle := &dto.PresetBundle{}
+	if err := json.Unmarshal(b, bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle: %v", err)
+	}
+	if bundle.Version > BundleVersion {
+		return nil, fmt.Errorf("unsupported bundle version %d, the newest supported version is %d", bundle.Version, BundleVersion)
+	}
+	return bundle, nil
+}
+
+// FromPreset returns the settings of an existing preset to create or update a preset with
+func FromPreset(preset *dto.Preset) *dto.NewPreset {
+	return &dto.NewPreset{
+		Command:             preset.Command,
+		Executor:            preset.Executor,
+		Encoder:             preset.Encoder,
+		QualitySearch:       preset.QualitySearch,
+		Chunking:            preset.Chunking,
+		Verification:        preset.Verification,
+		Secrets:             preset.Secrets,
+		Priority:            preset.Priority,
+		OutputFile:          preset.OutputFile,
+		AtomicOutput:        preset.AtomicOutput,
+		OutputPolicy:        preset.OutputPolicy,
+		PreProcessing:       preset.PreProcessing,
+		PostProcessing:      preset.PostProcessing,
+		Name:                preset.Name,
+		Description:         preset.Description,
+		GlobalPresetName:    preset.GlobalPresetName,
+		GlobalPresetVersion: preset.GlobalPresetVersion,
+	}
+}
+
+// Diff returns the fields that differ between two presets
+func Diff(old *dto.NewPreset, new *dto.NewPreset) ([]dto.FieldChange, error) {
+	oldFields, err := fields(old)
+	if err != nil {
+		return nil, err
+	}
+	newFields, err := fields(new)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []string{}
+	for key := range oldFields {
+		keys = append(keys, key)
+	}
+	for key := range newFields {
+		if _, ok := oldFields[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	changes := []dto.FieldChange{}
+	for _, key := range keys {
+		if !reflect.DeepEqual(oldFields[key], newFields[key]) {
+			changes = append(changes, dto.FieldChange{Field: key, Old: oldFields[key], New: newFields[key]})
+		}
+	}
+	return changes, nil
+}
+
+func fields(preset *dto.NewPreset) (map[string]any, error) {
+	b, err := json.Marshal(preset)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]any
+	return m, json.Unmarshal(b, &m)
+}
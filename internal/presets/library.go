@@ -0,0 +1,58 @@
+package presets
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+//go:embed library/*.json
+var library embed.FS
+
+// Library returns the presets of the built-in library sorted by name
+func Library() ([]dto.LibraryPreset, error) {
+	entries, err := library.ReadDir("library")
+	if err != nil {
+		return nil, err
+	}
+	presets := make([]dto.LibraryPreset, 0, len(entries))
+	for _, entry := range entries {
+		b, err := library.ReadFile(path.Join("library", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		var preset dto.LibraryPreset
+		if err := json.Unmarshal(b, &preset); err != nil {
+			return nil, fmt.Errorf("invalid library preset '%s': %v", entry.Name(), err)
+		}
+		presets = append(presets, preset)
+	}
+	sort.Slice(presets, func(i, j int) bool { return presets[i].Name < presets[j].Name })
+	return presets, nil
+}
+
+// Find returns the library preset with the given name
+func Find(name string) (*dto.LibraryPreset, error) {
+	presets, err := Library()
+	if err != nil {
+		return nil, err
+	}
+	for _, preset := range presets {
+		if preset.Name == name {
+			return &preset, nil
+		}
+	}
+	return nil, fmt.Errorf("library preset '%s' not found", name)
+}
+
+// NewPreset returns the library preset ready to be created, it keeps the name and version of the library preset
+func NewPreset(preset *dto.LibraryPreset) *dto.NewPreset {
+	newPreset := preset.Preset
+	newPreset.GlobalPresetName = preset.Name
+	newPreset.GlobalPresetVersion = preset.Version
+	return &newPreset
+}
@@ -0,0 +1,76 @@
+package presets
+
+import (
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+)
+
+func TestLibrary(t *testing.T) {
+	library, err := Library()
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := map[string]bool{}
+	for _, preset := range library {
+		if preset.Name == "" || preset.Version < 1 || preset.Preset.Command == "" || preset.Preset.OutputFile == "" {
+			t.Errorf("Incomplete library preset %+v", preset)
+		}
+		if names[preset.Name] {
+			t.Errorf("Duplicate library preset %s", preset.Name)
+		}
+		names[preset.Name] = true
+		if err := ffmpeg.ValidateEncoder(preset.Preset.Encoder, preset.Preset.Command); err != nil {
+			t.Errorf("Invalid encoder settings of %s: %v", preset.Name, err)
+		}
+	}
+
+	preset, err := Find("svtav1-1080p")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if newPreset := NewPreset(preset); newPreset.GlobalPresetName != "svtav1-1080p" || newPreset.GlobalPresetVersion != preset.Version {
+		t.Errorf("Unexpected new preset %+v", newPreset)
+	}
+	if _, err := Find("unknown"); err == nil {
+		t.Error("Expected unknown library preset to fail")
+	}
+}
+
+func TestBundle(t *testing.T) {
+	crf := 30
+	bundle := &dto.PresetBundle{Version: BundleVersion, Presets: []dto.Preset{
+		{Uuid: "a", Name: "AV1", Command: "-i ${INPUT_FILE} ${ENCODER_ARGS} ${OUTPUT_FILE}", Encoder: &dto.EncoderSettings{Crf: &crf}, Priority: 2},
+	}}
+	for _, format := range []Format{FORMAT_JSON, FORMAT_YAML} {
+		b, err := Encode(bundle, format)
+		if err != nil {
+			t.Fatalf("Encode(%s) failed: %v", format, err)
+		}
+		decoded, err := Decode(b)
+		if err != nil {
+			t.Fatalf("Decode(%s) failed: %v", format, err)
+		}
+		if len(decoded.Presets) != 1 || decoded.Presets[0].Uuid != "a" || *decoded.Presets[0].Encoder.Crf != 30 || decoded.Presets[0].Priority != 2 {
+			t.Errorf("Unexpected %s round trip %+v", format, decoded.Presets)
+		}
+	}
+
+	if _, err := Encode(bundle, "xml"); err == nil {
+		t.Error("Expected unsupported format to fail")
+	}
+	if _, err := Decode([]byte("version: 99\npresets: []")); err == nil {
+		t.Error("Expected newer bundle version to fail")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	changes, err := Diff(&dto.NewPreset{Name: "a", Command: "-i x", Priority: 1}, &dto.NewPreset{Name: "a", Command: "-i y", Priority: 1, AtomicOutput: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 || changes[0].Field != "atomicOutput" || changes[0].Old != nil || changes[1].Field != "command" || changes[1].New != "-i y" {
+		t.Errorf("Unexpected changes %+v", changes)
+	}
+}
@@ -0,0 +1,299 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/internal/storage"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+)
+
+// httpActionTimeout limits the request of a http action
+const httpActionTimeout = 30 * time.Second
+
+// runActions runs the built-in actions in order, the first failing action skips the remaining ones unless it may fail
+func (q *Queue) runActions(ctx context.Context, task *model.Task, processor *dto.PrePostProcessing, processorType string, secrets wildcards.Secrets) error {
+	var failed error
+	for i := range processor.Actions {
+		action := &processor.Actions[i]
+		if failed != nil {
+			action.Status = dto.ACTION_SKIPPED
+			continue
+		}
+
+		action.StartedAt = time.Now().UnixMilli()
+		output, err := q.runAction(ctx, task, action, processorType, secrets)
+		action.FinishedAt = time.Now().UnixMilli()
+		if err != nil {
+			action.Status = dto.ACTION_FAILED
+			action.Error = secrets.Mask(err.Error())
+			q.Sev.Logger().Errorf("%sProcessing action %d (%s) failed (uuid: %s): %s", processorType, i+1, action.Type, task.Uuid, action.Error)
+			if !action.ContinueOnError {
+				failed = fmt.Errorf("action %d (%s) failed: %s", i+1, action.Type, action.Error)
+			}
+		} else {
+			action.Status = dto.ACTION_SUCCESSFUL
+			action.Output = secrets.Mask(output)
+			debug.Debugf("%sProcessing action %d (%s) successful (uuid: %s)", processorType, i+1, action.Type, task.Uuid)
+		}
+		q.updateTask(task)
+	}
+	return failed
+}
+
+// runAction runs a single action and returns its output
+func (q *Queue) runAction(ctx context.Context, task *model.Task, action *dto.Action, processorType string, secrets wildcards.Secrets) (string, error) {
+	// the paths are not resolved before the preProcessing
+	inFile, outFile := task.InputFile.Resolved, task.OutputFile.Resolved
+	if processorType == "pre" {
+		inFile, outFile = task.InputFile.Raw, task.OutputFile.Raw
+	}
+	render := func(input string) (string, error) {
+		return wildcards.Render(input, inFile, outFile, task.Source, task.Metadata, service.TaskVariables(task), secrets.Variables())
+	}
+	output := task.OutputFile.Resolved
+
+	switch action.Type {
+	case dto.ACTION_HTTP:
+		return httpAction(ctx, task, action, render)
+	case dto.ACTION_DELETE_SOURCE:
+		input := task.InputFile.Resolved
+		if storage.IsRemote(input) {
+			return "", errors.New("remote inputs can not be deleted")
+		}
+		if err := os.Remove(input); err != nil {
+			return "", err
+		}
+		return input, nil
+	}
+
+	// all other actions work with the output, remote outputs are read from the local copy that was uploaded
+	local := localOutput(task)
+	var dest string
+	if action.Destination != "" {
+		var err error
+		if dest, err = render(action.Destination); err != nil {
+			return "", err
+		}
+	}
+
+	switch action.Type {
+	case dto.ACTION_COPY:
+		dest = destinationPath(dest, output)
+		if err := copyTo(ctx, local, dest); err != nil {
+			return "", err
+		}
+		return dest, nil
+	case dto.ACTION_MOVE:
+		if storage.IsRemote(output) {
+			return "", errors.New("remote outputs can not be moved")
+		}
+		dest = destinationPath(dest, output)
+		var err error
+		if !storage.IsRemote(dest) {
+			err = moveFile(output, dest)
+		}
+		// uploads and renames across file systems copy the output and remove it afterwards
+		if storage.IsRemote(dest) || err != nil {
+			if err := copyTo(ctx, output, dest); err != nil {
+				return "", err
+			}
+			if err := os.Remove(output); err != nil {
+				return "", err
+			}
+		}
+		// the following actions and the task refer to the new location
+		task.OutputFile.Resolved = dest
+		return dest, nil
+	case dto.ACTION_CHECKSUM:
+		algorithm := action.Algorithm
+		if algorithm == "" {
+			algorithm = "sha256"
+		}
+		if dest == "" {
+			dest = output + "." + algorithm
+		}
+		sum, err := checksum(local, algorithm)
+		if err != nil {
+			return "", err
+		}
+		err = produce(ctx, task, dest, func(path string) error {
+			return writeFile(path, []byte(fmt.Sprintf("%s  %s\n", sum, filepath.Base(output))))
+		})
+		if err != nil {
+			return "", err
+		}
+		return sum, nil
+	case dto.ACTION_THUMBNAIL:
+		if dest == "" {
+			dest = strings.TrimSuffix(output, filepath.Ext(output)) + ".jpg"
+		}
+		err := produce(ctx, task, dest, func(path string) error {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			return ffmpeg.Thumbnail(ctx, local, action.Time, action.Width, path)
+		})
+		if err != nil {
+			return "", err
+		}
+		return dest, nil
+	}
+	return "", fmt.Errorf("unknown action '%s'", action.Type)
+}
+
+// httpAction sends the request of the action, the task is sent as JSON if the action has no body
+func httpAction(ctx context.Context, task *model.Task, action *dto.Action, render func(string) (string, error)) (string, error) {
+	url, err := render(action.Url)
+	if err != nil {
+		return "", err
+	}
+	method := action.Method
+	if method == "" {
+		method = http.MethodPost
+	}
+	var body []byte
+	if action.Body != "" {
+		b, err := render(action.Body)
+		if err != nil {
+			return "", err
+		}
+		body = []byte(b)
+	} else if method != http.MethodGet {
+		if body, err = json.Marshal(task.ToDto()); err != nil {
+			return "", err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, httpActionTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for name, value := range action.Headers {
+		v, err := render(value)
+		if err != nil {
+			return "", err
+		}
+		req.Header.Set(name, v)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		b, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("unexpected status %d: %s", resp.StatusCode, strings.TrimSpace(string(b)))
+	}
+	return strconv.Itoa(resp.StatusCode), nil
+}
+
+// destinationPath returns the file the output is copied to, destinations ending with a separator or pointing to a directory keep the output's name
+func destinationPath(dest string, output string) string {
+	if strings.HasSuffix(dest, "/") || strings.HasSuffix(dest, string(os.PathSeparator)) {
+		return dest + filepath.Base(output)
+	}
+	if info, err := os.Stat(dest); err == nil && info.IsDir() {
+		return filepath.Join(dest, filepath.Base(output))
+	}
+	return dest
+}
+
+// copyTo copies the file to the destination, remote destinations are uploaded
+func copyTo(ctx context.Context, src string, dest string) error {
+	if storage.IsRemote(dest) {
+		return storage.Upload(ctx, src, dest, func(int64, int64) {})
+	}
+	return copyFile(src, dest)
+}
+
+// produce creates the file at the destination with write, files for remote destinations are written to the workspace and uploaded
+func produce(ctx context.Context, task *model.Task, dest string, write func(path string) error) error {
+	if !storage.IsRemote(dest) {
+		return write(dest)
+	}
+	path := filepath.Join(task.Workdir, "staging", "actions", storage.LocalName(dest))
+	defer os.Remove(path)
+	if err := write(path); err != nil {
+		return err
+	}
+	return storage.Upload(ctx, path, dest, func(int64, int64) {})
+}
+
+// moveFile renames the file, it fails across file systems
+func moveFile(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return os.Rename(src, dst)
+}
+
+func copyFile(src string, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func writeFile(path string, b []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+func checksum(path string, algorithm string) (string, error) {
+	var h hash.Hash
+	switch algorithm {
+	case "md5":
+		h = md5.New()
+	default:
+		h = sha256.New()
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,152 @@
+package queue
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+)
+
+func TestRunAction(t *testing.T) {
+	q := &Queue{}
+	newTask := func(t *testing.T) *model.Task {
+		dir := t.TempDir()
+		input := filepath.Join(dir, "in", "input.mov")
+		output := filepath.Join(dir, "out", "output.mp4")
+		for _, path := range []string{input, output} {
+			os.MkdirAll(filepath.Dir(path), 0755)
+			os.WriteFile(path, []byte("ffmate"), 0644)
+		}
+		return &model.Task{
+			Uuid:       "1a2b3c4d",
+			InputFile:  &dto.RawResolved{Raw: input, Resolved: input},
+			OutputFile: &dto.RawResolved{Raw: output, Resolved: output},
+			Metadata:   &dto.InterfaceMap{"show": "pilot"},
+		}
+	}
+	run := func(task *model.Task, action dto.NewAction) (string, error) {
+		return q.runAction(context.Background(), task, &dto.Action{NewAction: action}, "post", wildcards.Secrets{"token": "abc123"})
+	}
+
+	t.Run("Copy to directory", func(t *testing.T) {
+		task := newTask(t)
+		dest := filepath.Join(filepath.Dir(task.OutputFile.Resolved), "..", "${METADATA_show}") + string(os.PathSeparator)
+		got, err := run(task, dto.NewAction{Type: dto.ACTION_COPY, Destination: dest})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if filepath.Base(got) != "output.mp4" || filepath.Base(filepath.Dir(got)) != "pilot" {
+			t.Errorf("Unexpected destination %s", got)
+		}
+		if _, err := os.Stat(task.OutputFile.Resolved); err != nil {
+			t.Errorf("Expected output to be kept: %v", err)
+		}
+	})
+
+	t.Run("Move", func(t *testing.T) {
+		task := newTask(t)
+		output := task.OutputFile.Resolved
+		dest := filepath.Join(filepath.Dir(output), "archive", "moved.mp4")
+		if _, err := run(task, dto.NewAction{Type: dto.ACTION_MOVE, Destination: dest}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(output); !os.IsNotExist(err) {
+			t.Errorf("Expected output to be moved")
+		}
+		if task.OutputFile.Resolved != dest {
+			t.Errorf("Expected output to point to %s, got %s", dest, task.OutputFile.Resolved)
+		}
+	})
+
+	t.Run("Checksum", func(t *testing.T) {
+		task := newTask(t)
+		got, err := run(task, dto.NewAction{Type: dto.ACTION_CHECKSUM, Algorithm: "md5"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := os.ReadFile(task.OutputFile.Resolved + ".md5")
+		if got != "63316197c579920968bdac95cba0a697" || string(b) != got+"  output.mp4\n" {
+			t.Errorf("Unexpected checksum %s (sidecar: %q)", got, string(b))
+		}
+	})
+
+	t.Run("HTTP", func(t *testing.T) {
+		var received dto.Task
+		var auth string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			auth = r.Header.Get("Authorization")
+			b, _ := io.ReadAll(r.Body)
+			json.Unmarshal(b, &received)
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+
+		task := newTask(t)
+		got, err := run(task, dto.NewAction{Type: dto.ACTION_HTTP, Url: server.URL + "/${METADATA_show}", Headers: map[string]string{"Authorization": "Bearer ${SECRET_token}"}})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != "202" || auth != "Bearer abc123" || received.Uuid != task.Uuid {
+			t.Errorf("Unexpected request (status: %s, auth: %s, task: %s)", got, auth, received.Uuid)
+		}
+
+		server.Config.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "nope", http.StatusForbidden)
+		})
+		if _, err := run(task, dto.NewAction{Type: dto.ACTION_HTTP, Url: server.URL}); err == nil || err.Error() != "unexpected status 403: nope" {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("Delete source", func(t *testing.T) {
+		task := newTask(t)
+		if _, err := run(task, dto.NewAction{Type: dto.ACTION_DELETE_SOURCE}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := os.Stat(task.InputFile.Resolved); !os.IsNotExist(err) {
+			t.Errorf("Expected input to be deleted")
+		}
+	})
+
+	t.Run("Remote output", func(t *testing.T) {
+		uploads := map[string]string{}
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			b, _ := io.ReadAll(r.Body)
+			uploads[r.URL.Path] = string(b)
+		}))
+		defer server.Close()
+
+		// the output was uploaded, the actions read the local copy in the workspace
+		task := newTask(t)
+		task.Workdir = t.TempDir()
+		task.OutputFile.Local = task.OutputFile.Resolved
+		task.OutputFile.Resolved = "stage+" + server.URL + "/out/output.mp4"
+
+		if _, err := run(task, dto.NewAction{Type: dto.ACTION_CHECKSUM, Algorithm: "md5"}); err != nil {
+			t.Fatal(err)
+		}
+		if uploads["/out/output.mp4.md5"] != "63316197c579920968bdac95cba0a697  output.mp4\n" {
+			t.Errorf("Unexpected checksum upload %q", uploads["/out/output.mp4.md5"])
+		}
+
+		dest := filepath.Join(t.TempDir(), "copy.mp4")
+		if _, err := run(task, dto.NewAction{Type: dto.ACTION_COPY, Destination: dest}); err != nil {
+			t.Fatal(err)
+		}
+		if b, _ := os.ReadFile(dest); string(b) != "ffmate" {
+			t.Errorf("Unexpected copy %q", b)
+		}
+
+		if _, err := run(task, dto.NewAction{Type: dto.ACTION_MOVE, Destination: dest}); err == nil {
+			t.Error("Expected moving a remote output to fail")
+		}
+	})
+}
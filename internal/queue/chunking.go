@@ -0,0 +1,181 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+)
+
+// splitTask determines the chunk boundaries of the input and creates a task for every chunk, the parent waits in status CHUNKING until all chunks are done
+func (q *Queue) splitTask(task *model.Task, ctx context.Context, crf int) error {
+	chunking := task.Chunking
+	ffmpeg.ChunkingDefaults(&chunking.NewChunking)
+
+	task.Status = dto.RUNNING
+	task.Progress = 0
+	q.updateTask(task)
+	q.Sev.Logger().Infof("splitting task into chunks (uuid: %s)", task.Uuid)
+
+	probe, err := ffmpeg.Probe(ctx, localInput(task))
+	if err != nil {
+		return err
+	}
+	duration := probe.Duration()
+	if duration <= 0 {
+		return fmt.Errorf("failed to determine input duration for chunking")
+	}
+	var fps float64
+	if stream := probe.VideoStream(); stream != nil {
+		fps = stream.FrameRate()
+	}
+	if task.Executor == dto.EXECUTOR_SVTAV1 && fps <= 0 {
+		return fmt.Errorf("failed to determine input frame rate for chunking")
+	}
+
+	var points []float64
+	if chunking.Mode == dto.CHUNKING_MODE_SCENE {
+		points, err = ffmpeg.DetectScenes(ctx, localInput(task), chunking.SceneThreshold)
+		if err != nil {
+			return err
+		}
+	} else {
+		points = ffmpeg.IntervalPoints(duration, chunking.Interval)
+	}
+	ranges := ffmpeg.ChunkRanges(points, duration, chunking.MinChunkDuration)
+
+	dir := filepath.Join(task.Workdir, "chunks")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create chunk directory: %v", err)
+	}
+
+	if task.Batch == "" {
+		task.Batch = uuid.NewString()
+	}
+	chunking.Run = uuid.NewString()
+	chunking.Phase = dto.CHUNKING_PHASE_ENCODE
+	chunking.Chunks = len(ranges)
+	chunking.Directory = dir
+	task.Status = dto.CHUNKING
+	q.updateTask(task)
+
+	// chunks encode with the crf picked for the whole task and do not search again
+	command := task.Command.Raw
+	if crf != 0 {
+		command = strings.ReplaceAll(command, "${CRF}", strconv.Itoa(crf))
+	}
+	explicit := strings.Contains(command, "${CHUNK_")
+
+	ext := filepath.Ext(task.OutputFile.Resolved)
+	name := task.Name
+	if name == "" {
+		name = filepath.Base(task.InputFile.Resolved)
+	}
+	var created []string
+	for i, r := range ranges {
+		chunkCommand := command
+		if !explicit {
+			chunkCommand = ffmpeg.ChunkArgs(task.Executor, r, fps) + " " + command
+		}
+		chunk, err := service.TaskService().NewTask(&dto.NewTask{
+			Command:    chunkCommand,
+			InputFile:  localInput(task),
+			OutputFile: filepath.Join(dir, fmt.Sprintf("chunk_%04d%s", i, ext)),
+			Executor:   task.Executor,
+			Encoder:    encoderWithCrf(task.Encoder, crf),
+			Name:       fmt.Sprintf("%s (chunk %d/%d)", name, i+1, len(ranges)),
+			Priority:   &task.Priority,
+			Metadata:   task.Metadata,
+			Secrets:    task.Secrets,
+			Parent:     task.Uuid,
+			Chunk: &dto.Chunk{
+				Run:      chunking.Run,
+				Index:    i,
+				Start:    r.Start,
+				Duration: r.Duration,
+			},
+		}, task.Batch, task.Source)
+		if err != nil {
+			for _, u := range created {
+				service.TaskService().CancelTask(u)
+			}
+			return fmt.Errorf("failed to create chunk %d: %v", i, err)
+		}
+		created = append(created, chunk.Uuid)
+	}
+
+	q.Sev.Logger().Infof("split task into %d chunks (uuid: %s)", len(ranges), task.Uuid)
+	return nil
+}
+
+// concatChunks joins the encoded chunks of the current run into the task's output file
+func (q *Queue) concatChunks(task *model.Task, ctx context.Context) error {
+	chunking := task.Chunking
+	chunks, err := q.TaskRepository.ByParent(task.Uuid)
+	if err != nil {
+		return err
+	}
+	var files []*model.Task
+	for i := range *chunks {
+		c := &(*chunks)[i]
+		if c.Chunk != nil && c.Chunk.Run == chunking.Run {
+			files = append(files, c)
+		}
+	}
+	if len(files) != chunking.Chunks {
+		return fmt.Errorf("expected %d chunks, found %d", chunking.Chunks, len(files))
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Chunk.Index < files[j].Chunk.Index })
+
+	var list strings.Builder
+	for _, c := range files {
+		path, err := filepath.Abs(c.OutputFile.Resolved)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(&list, "file '%s'\n", strings.ReplaceAll(path, "'", `'\''`))
+	}
+	listFile := filepath.Join(chunking.Directory, "chunks.txt")
+	if err := os.WriteFile(listFile, []byte(list.String()), 0644); err != nil {
+		return fmt.Errorf("failed to write chunk list: %v", err)
+	}
+
+	secrets := q.secrets(task)
+	command := wildcards.Replace(chunking.ConcatCommand, localInput(task), task.OutputFile.Resolved, task.Source, task.Metadata, service.TaskVariables(task), secrets.Variables(), wildcards.Variables{
+		"CHUNK_LIST":       fmt.Sprintf("\"%s\"", listFile),
+		"OUTPUT_FILE":      fmt.Sprintf("\"%s\"", encodeOutput(task)),
+		"OUTPUT_FILE_TEMP": fmt.Sprintf("\"%s\"", encodeOutput(task)),
+	})
+	task.Command.Resolved = secrets.Mask(command)
+	task.Progress = 0
+	task.Status = dto.RUNNING
+	q.updateTask(task)
+
+	q.Sev.Logger().Infof("concatenating %d chunks (uuid: %s)", len(files), task.Uuid)
+	return ffmpeg.Execute(
+		&ffmpeg.ExecutionRequest{
+			Task:     task,
+			Command:  command,
+			Executor: dto.EXECUTOR_FFMPEG,
+			Logger:   q.Sev.Logger(),
+			Ctx:      ctx,
+			Dir:      task.Workdir,
+			Env:      taskEnv(task),
+			UpdateFunc: func(progress float64, remaining float64) {
+				task.Progress = progress
+				task.Remaining = remaining
+				q.updateTask(task)
+			},
+		},
+	)
+}
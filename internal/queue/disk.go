@@ -0,0 +1,76 @@
+package queue
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/storage"
+	"github.com/welovemedia/ffmate/internal/utils"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+	"github.com/welovemedia/ffmate/internal/workspace"
+)
+
+// checkDiskSpace makes sure the output and workspace volumes have enough free space to process the task, it returns the reason to hold the queue or an empty string
+func (q *Queue) checkDiskSpace(task *model.Task) string {
+	config.Config().Mutex.RLock()
+	outputMinFree := uint64(config.Config().OutputMinFree) << 20
+	workspaceMinFree := uint64(config.Config().WorkspaceMinFree) << 20
+	factor := config.Config().FreeSpaceFactor
+	config.Config().Mutex.RUnlock()
+
+	inFile := wildcards.Replace(task.InputFile.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source, task.Metadata)
+	outFile := wildcards.Replace(task.OutputFile.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source, task.Metadata)
+
+	// estimate the space the task needs from the size of a local input
+	var estimate uint64
+	if factor > 0 && !storage.IsRemote(inFile) {
+		if info, err := os.Stat(inFile); err == nil {
+			estimate = uint64(float64(info.Size()) * factor)
+		}
+	}
+
+	if outFile != "" && outFile != "-" && !storage.IsRemote(outFile) {
+		if reason := lowDiskSpace("output", filepath.Dir(outFile), max(outputMinFree, estimate)); reason != "" {
+			return reason
+		}
+	}
+	return lowDiskSpace("workspace", workspace.Root(), max(workspaceMinFree, estimate))
+}
+
+// lowDiskSpace returns a reason if the volume of the path has less than the required bytes available
+func lowDiskSpace(volume string, path string, required uint64) string {
+	if required == 0 {
+		return ""
+	}
+	dir := existingDir(path)
+	free, err := utils.FreeDiskSpace(dir)
+	if err != nil {
+		debug.Debugf("failed to determine free disk space of '%s': %v", dir, err)
+		return ""
+	}
+	if free < required {
+		return fmt.Sprintf("not enough free disk space on %s volume '%s' (free: %d MB, required: %d MB)", volume, dir, free>>20, required>>20)
+	}
+	return ""
+}
+
+// existingDir returns the path or its closest existing parent, output directories are created just before encoding
+func existingDir(path string) string {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return path
+	}
+	for {
+		if info, err := os.Stat(path); err == nil && info.IsDir() {
+			return path
+		}
+		parent := filepath.Dir(path)
+		if parent == path {
+			return path
+		}
+		path = parent
+	}
+}
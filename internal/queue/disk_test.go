@@ -0,0 +1,29 @@
+package queue
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestExistingDir(t *testing.T) {
+	dir := t.TempDir()
+	if got := existingDir(filepath.Join(dir, "a", "b")); got != dir {
+		t.Errorf("existingDir() = %s, want %s", got, dir)
+	}
+	if got := existingDir(dir); got != dir {
+		t.Errorf("existingDir() = %s, want %s", got, dir)
+	}
+}
+
+func TestLowDiskSpace(t *testing.T) {
+	dir := t.TempDir()
+	if reason := lowDiskSpace("output", dir, 1); reason != "" {
+		t.Errorf("lowDiskSpace() held for 1 byte: %s", reason)
+	}
+	if reason := lowDiskSpace("output", dir, ^uint64(0)); reason == "" {
+		t.Error("lowDiskSpace() did not hold for an impossible requirement")
+	}
+	if reason := lowDiskSpace("output", dir, 0); reason != "" {
+		t.Errorf("lowDiskSpace() held without a requirement: %s", reason)
+	}
+}
@@ -0,0 +1,41 @@
+package queue
+
+import (
+	"os"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+)
+
+// taskEnv returns the environment of the processes started for the task, the server environment extended by the task context
+func taskEnv(task *model.Task) []string {
+	inFile, outFile := task.InputFile.Resolved, task.OutputFile.Resolved
+	// the paths are not resolved before the preProcessing
+	if inFile == "" {
+		inFile = task.InputFile.Raw
+	}
+	if outFile == "" {
+		outFile = task.OutputFile.Raw
+	}
+	return append(os.Environ(),
+		"FFMATE_TASK_UUID="+task.Uuid,
+		"FFMATE_TASK_NAME="+task.Name,
+		"FFMATE_TASK_WORKDIR="+task.Workdir,
+		"FFMATE_BATCH_UUID="+task.Batch,
+		"FFMATE_SOURCE="+task.Source,
+		"FFMATE_STATUS="+string(task.Status),
+		"FFMATE_ERROR="+task.Error,
+		"FFMATE_INPUT_FILE="+inFile,
+		"FFMATE_OUTPUT_FILE="+outFile,
+	)
+}
+
+// secrets returns the secrets resolved in the commands and scripts of a task, only the ones the task references
+func (q *Queue) secrets(task *model.Task) wildcards.Secrets {
+	secrets, err := service.SecretService().Secrets(task.Secrets)
+	if err != nil {
+		q.Sev.Logger().Warnf("failed to load secrets: %v", err)
+	}
+	return secrets
+}
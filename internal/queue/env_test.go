@@ -0,0 +1,25 @@
+package queue
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestTaskEnv(t *testing.T) {
+	task := &model.Task{
+		Uuid:       "1a2b3c4d",
+		Batch:      "batch",
+		Status:     dto.PRE_PROCESSING,
+		InputFile:  &dto.RawResolved{Raw: "/in/${INPUT}.mp4"},
+		OutputFile: &dto.RawResolved{Raw: "/out/raw.mp4", Resolved: "/out/resolved.mp4"},
+	}
+	env := taskEnv(task)
+	for _, want := range []string{"FFMATE_TASK_UUID=1a2b3c4d", "FFMATE_BATCH_UUID=batch", "FFMATE_STATUS=PRE_PROCESSING", "FFMATE_INPUT_FILE=/in/${INPUT}.mp4", "FFMATE_OUTPUT_FILE=/out/resolved.mp4", "FFMATE_ERROR="} {
+		if !slices.Contains(env, want) {
+			t.Errorf("Expected %s in environment", want)
+		}
+	}
+}
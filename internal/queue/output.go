@@ -0,0 +1,123 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/storage"
+)
+
+// prepareOutput enforces the output policy and sets up the temporary or staged output path, it returns false if the task must not be encoded
+func (q *Queue) prepareOutput(task *model.Task, ctx context.Context) bool {
+	task.TempOutputFile = ""
+	task.OutputFile.Local = ""
+	output := task.OutputFile.Resolved
+	remote := storage.IsRemote(output)
+
+	exists := func(p string) bool {
+		if remote {
+			ok, err := storage.Exists(ctx, p)
+			if err != nil {
+				q.Sev.Logger().Warnf("failed to check if output file exists (uuid: %s): %v", task.Uuid, err)
+			}
+			return ok
+		}
+		info, err := os.Stat(p)
+		return err == nil && info.Mode().IsRegular()
+	}
+
+	if task.OutputPolicy != "" && task.OutputPolicy != dto.OUTPUT_POLICY_OVERWRITE && exists(output) {
+		switch task.OutputPolicy {
+		case dto.OUTPUT_POLICY_SKIP:
+			task.FinishedAt = time.Now().UnixMilli()
+			task.Progress = 100
+			task.Remaining = -1
+			task.Status = dto.DONE_SUCCESSFUL
+			q.cleanupWorkspace(task, false)
+			q.updateTask(task)
+			q.Sev.Logger().Infof("output file '%s' already exists, skipped task (uuid: %s)", output, task.Uuid)
+			return false
+		case dto.OUTPUT_POLICY_FAIL:
+			q.failTask(task, fmt.Errorf("output file '%s' already exists", output))
+			return false
+		case dto.OUTPUT_POLICY_SUFFIX:
+			task.OutputFile.Resolved = suffixedOutputPath(output, exists)
+			debug.Debugf("output file '%s' already exists, using '%s' (uuid: %s)", output, task.OutputFile.Resolved, task.Uuid)
+		}
+	}
+
+	// remote outputs are written to the staging directory and uploaded afterwards, which is atomic by itself
+	if remote {
+		task.OutputFile.Local = filepath.Join(task.Workdir, "staging", "output", storage.LocalName(task.OutputFile.Resolved))
+		return true
+	}
+
+	if task.AtomicOutput && output != "" && output != "-" {
+		task.TempOutputFile = tempOutputPath(task.OutputFile.Resolved, task.Uuid)
+	}
+	return true
+}
+
+// encodeOutput returns the path ffmpeg writes to
+func encodeOutput(task *model.Task) string {
+	if task.TempOutputFile != "" {
+		return task.TempOutputFile
+	}
+	return localOutput(task)
+}
+
+// createOutputDir creates the directory ffmpeg writes to if it does not exist (recursive)
+func createOutputDir(task *model.Task) error {
+	if err := os.MkdirAll(filepath.Dir(encodeOutput(task)), 0755); err != nil {
+		return fmt.Errorf("failed to create non-existing output directory: %v", err)
+	}
+	return nil
+}
+
+// commitOutput moves the temporary output into place
+func (q *Queue) commitOutput(task *model.Task) error {
+	if task.TempOutputFile == "" {
+		return nil
+	}
+	if err := os.Rename(task.TempOutputFile, task.OutputFile.Resolved); err != nil {
+		return fmt.Errorf("failed to move temporary output into place: %v", err)
+	}
+	debug.Debugf("moved temporary output '%s' into place (uuid: %s)", task.TempOutputFile, task.Uuid)
+	task.TempOutputFile = ""
+	return nil
+}
+
+// discardOutput deletes a partially written temporary output
+func (q *Queue) discardOutput(task *model.Task) {
+	if task.TempOutputFile == "" {
+		return
+	}
+	if err := os.Remove(task.TempOutputFile); err != nil && !os.IsNotExist(err) {
+		q.Sev.Logger().Warnf("failed to delete temporary output '%s' (uuid: %s): %v", task.TempOutputFile, task.Uuid, err)
+	}
+}
+
+// tempOutputPath returns a hidden sibling of the output that keeps its extension so the muxer can still be guessed
+func tempOutputPath(output string, uuid string) string {
+	ext := filepath.Ext(output)
+	name := strings.TrimSuffix(filepath.Base(output), ext)
+	return filepath.Join(filepath.Dir(output), fmt.Sprintf(".%s.ffmate-%s%s", name, uuid[:8], ext))
+}
+
+// suffixedOutputPath returns the first non-existing path of the form name_1.ext, name_2.ext, ...
+func suffixedOutputPath(output string, exists func(string) bool) string {
+	ext := filepath.Ext(output)
+	base := strings.TrimSuffix(output, ext)
+	for i := 1; ; i++ {
+		candidate := fmt.Sprintf("%s_%d%s", base, i, ext)
+		if !exists(candidate) {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,45 @@
+package queue
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestTempOutputPath(t *testing.T) {
+	got := tempOutputPath("/out/movie.final.mp4", "1a2b3c4d-0000-0000-0000-000000000000")
+	if got != "/out/.movie.final.ffmate-1a2b3c4d.mp4" {
+		t.Errorf("tempOutputPath() = %s", got)
+	}
+}
+
+func TestSuffixedOutputPath(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "movie.mp4")
+	for _, name := range []string{"movie.mp4", "movie_1.mp4"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	exists := func(p string) bool {
+		_, err := os.Stat(p)
+		return err == nil
+	}
+	if got := suffixedOutputPath(output, exists); got != filepath.Join(dir, "movie_2.mp4") {
+		t.Errorf("suffixedOutputPath() = %s", got)
+	}
+}
+
+func TestCreateOutputDir(t *testing.T) {
+	dir := t.TempDir()
+	task := &model.Task{Workdir: dir, OutputFile: &dto.RawResolved{Resolved: "s3://bucket/out/movie.mp4", Local: filepath.Join(dir, "staging", "output", "movie.mp4")}}
+	if err := createOutputDir(task); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "staging", "output")); err != nil {
+		t.Errorf("Expected staging directory of the remote output to exist: %v", err)
+	}
+}
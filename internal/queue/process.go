@@ -1,19 +1,16 @@
 package queue
 
 import (
-	"bytes"
 	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"sync"
 	"time"
 
-	"github.com/mattn/go-shellwords"
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
@@ -32,8 +29,13 @@ type Queue struct {
 
 var debug = debugo.New("queue")
 
+// runningTask wraps the cancel func of a task so a re-queued task (e.g. a retried chunk) is not removed by the previous run
+type runningTask struct {
+	cancel context.CancelCauseFunc
+}
+
 var (
-	taskCtx = make(map[string]context.CancelCauseFunc)
+	taskCtx = make(map[string]*runningTask)
 	taskMu  = &sync.Mutex{}
 )
 
@@ -47,13 +49,20 @@ func (q *Queue) Init() {
 					q.Sev.Logger().Errorf("failed to receive queued task from db: %v", err)
 				} else if task == nil {
 					debug.Debug("no queued tasks found")
+					service.QueueService().Resume()
+				} else if reason := q.checkDiskSpace(task); reason != "" {
+					service.QueueService().Hold(reason)
 				} else {
+					service.QueueService().Resume()
 					ctx, cancelTask := context.WithCancelCause(context.Background())
-					taskCtx[task.Uuid] = cancelTask
+					running := &runningTask{cancel: cancelTask}
+					taskCtx[task.Uuid] = running
 					go q.processTask(task, ctx, func() {
 						taskMu.Lock()
 						defer taskMu.Unlock()
-						delete(taskCtx, task.Uuid)
+						if taskCtx[task.Uuid] == running {
+							delete(taskCtx, task.Uuid)
+						}
 					})
 				}
 			} else {
@@ -66,8 +75,8 @@ func (q *Queue) Init() {
 	go func() {
 		for t := range service.TaskService().GetTaskUpdates() {
 			taskMu.Lock()
-			if fn, ok := taskCtx[t.Uuid]; ok {
-				fn(errors.New("task canceled by user"))
+			if running, ok := taskCtx[t.Uuid]; ok {
+				running.cancel(errors.New("task canceled by user"))
 			} else {
 				q.Sev.Logger().Warnf("task not found to cancel (uuid: %s)", t.Uuid)
 			}
@@ -79,38 +88,149 @@ func (q *Queue) Init() {
 func (q *Queue) processTask(task *model.Task, ctx context.Context, doneFunc func()) {
 	defer doneFunc()
 
+	// all chunks of a chunked task are done, only the concatenation is left
+	if task.Chunking != nil && task.Chunking.Phase == dto.CHUNKING_PHASE_CONCAT {
+		if err := q.createWorkspace(task); err != nil {
+			q.failTask(task, fmt.Errorf("Creating workspace failed: %v", err))
+			return
+		}
+		if err := createOutputDir(task); err != nil {
+			q.failTask(task, err)
+			return
+		}
+		err := q.concatChunks(task, ctx)
+		task.Progress = 100
+		task.Remaining = -1
+		if err != nil {
+			q.Sev.Logger().Errorf("finished concatenation with error (uuid: %s): %v", task.Uuid, err)
+			if context.Cause(ctx) != nil {
+				q.cancelTask(task, context.Cause(ctx))
+				return
+			}
+			q.failTask(task, fmt.Errorf("Concatenation failed: %v", err))
+			return
+		}
+		os.RemoveAll(task.Chunking.Directory)
+		q.finishTask(task, ctx)
+		return
+	}
+
 	task.StartedAt = time.Now().UnixMilli()
 	q.Sev.Logger().Infof("processing task (uuid: %s)", task.Uuid)
 
-	err := q.prePostProcessTask(task, task.PreProcessing, "pre")
+	// every task gets its own working directory for intermediate files
+	err := q.createWorkspace(task)
 	if err != nil {
+		q.failTask(task, fmt.Errorf("Creating workspace failed: %v", err))
+		return
+	}
+
+	err = q.prePostProcessTask(task, ctx, task.PreProcessing, "pre")
+	if err != nil {
+		if context.Cause(ctx) != nil {
+			q.cancelTask(task, context.Cause(ctx))
+			return
+		}
 		q.failTask(task, fmt.Errorf("PreProcessing failed: %v", err))
 		return
 	}
 
 	// resolve wildcards
-	inFile := wildcards.Replace(task.InputFile.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source, task.Metadata)
-	outFile := wildcards.Replace(task.OutputFile.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source, task.Metadata)
-	task.InputFile.Resolved = inFile
-	task.OutputFile.Resolved = outFile
-	task.Command.Resolved = wildcards.Replace(task.Command.Raw, inFile, outFile, task.Source, task.Metadata)
+	inFile, err := wildcards.Render(task.InputFile.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source, task.Metadata, service.TaskVariables(task))
+	if err != nil {
+		q.failTask(task, fmt.Errorf("Resolving input file failed: %v", err))
+		return
+	}
+	outFile, err := wildcards.Render(task.OutputFile.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source, task.Metadata, service.TaskVariables(task))
+	if err != nil {
+		q.failTask(task, fmt.Errorf("Resolving output file failed: %v", err))
+		return
+	}
+	task.InputFile.Resolved = absolutePath(inFile)
+	task.OutputFile.Resolved = absolutePath(outFile)
+
+	// enforce the output policy before anything is encoded
+	if !q.prepareOutput(task, ctx) {
+		return
+	}
+	outFile = task.OutputFile.Resolved
+
+	// download remote inputs to the staging directory
+	err = q.stageInput(task, ctx)
+	if err != nil {
+		if context.Cause(ctx) != nil {
+			q.cancelTask(task, context.Cause(ctx))
+			return
+		}
+		q.failTask(task, fmt.Errorf("Staging input failed: %v", err))
+		return
+	}
+	inFile = localInput(task)
+
+	// run the per-title quality search to determine the crf of the full encode
+	var crf int
+	if task.QualitySearch != nil {
+		crf, err = q.searchQuality(task, ctx)
+		if err != nil {
+			if context.Cause(ctx) != nil {
+				q.cancelTask(task, context.Cause(ctx))
+				return
+			}
+			q.failTask(task, fmt.Errorf("QualitySearch failed: %v", err))
+			return
+		}
+	}
+
+	encoderArgs, err := ffmpeg.EncoderArgs(encoderWithCrf(task.Encoder, crf), task.Executor)
+	if err != nil {
+		q.failTask(task, err)
+		return
+	}
+
+	// chunked tasks are split into chunk tasks instead of being processed directly
+	if task.Chunking != nil {
+		err = q.splitTask(task, ctx, crf)
+		if err != nil {
+			if context.Cause(ctx) != nil {
+				q.cancelTask(task, context.Cause(ctx))
+				return
+			}
+			q.failTask(task, fmt.Errorf("Chunking failed: %v", err))
+		}
+		return
+	}
+
+	variables := service.CommandVariables(task, encoderArgs, encodeOutput(task))
+	if task.QualitySearch != nil {
+		variables["CRF"] = strconv.Itoa(crf)
+	}
+	secrets := q.secrets(task)
+	command, err := wildcards.Render(task.Command.Raw, inFile, outFile, task.Source, task.Metadata, variables, secrets.Variables())
+	if err != nil {
+		q.failTask(task, fmt.Errorf("Resolving command failed: %v", err))
+		return
+	}
+	// the stored command never contains the values of secrets
+	task.Command.Resolved = secrets.Mask(command)
 	task.Status = dto.RUNNING
 	q.updateTask(task)
 
-	// create output directory if it does not exist (recursive)
-	err = os.MkdirAll(filepath.Dir(task.OutputFile.Resolved), 0755)
+	err = createOutputDir(task)
 	if err != nil {
-		q.failTask(task, fmt.Errorf("failed to create non-existing output directory: %v", err))
+		q.failTask(task, err)
 		return
 	}
 
 	q.Sev.Logger().Infof("starting processing (uuid: %s)", task.Uuid)
 	err = ffmpeg.Execute(
 		&ffmpeg.ExecutionRequest{
-			Task:    task,
-			Command: task.Command.Resolved,
-			Logger:  q.Sev.Logger(),
-			Ctx:     ctx,
+			Task:     task,
+			Command:  command,
+			Executor: task.Executor,
+			Logger:   q.Sev.Logger(),
+			Ctx:      ctx,
+			Dir:      task.Workdir,
+			Env:      taskEnv(task),
 			UpdateFunc: func(progress float64, remaining float64) {
 				task.Progress = progress
 				task.Remaining = remaining
@@ -124,7 +244,7 @@ func (q *Queue) processTask(task *model.Task, ctx context.Context, doneFunc func
 	task.Remaining = -1
 
 	if err != nil {
-		q.Sev.Logger().Errorf("finished processing with error (uuid: %s): %v", task.Uuid, err)
+		q.Sev.Logger().Errorf("finished processing with error (uuid: %s): %s", task.Uuid, secrets.Mask(err.Error()))
 		if context.Cause(ctx) != nil {
 			q.cancelTask(task, context.Cause(ctx))
 			return
@@ -134,21 +254,55 @@ func (q *Queue) processTask(task *model.Task, ctx context.Context, doneFunc func
 	}
 
 	q.Sev.Logger().Infof("finished processing (uuid: %s)", task.Uuid)
+	q.finishTask(task, ctx)
+}
+
+// finishTask verifies the output, runs the post-processing and marks the task as successful
+func (q *Queue) finishTask(task *model.Task, ctx context.Context) {
+	if task.Verification != nil {
+		if err := q.verifyTask(task, ctx); err != nil {
+			if context.Cause(ctx) != nil {
+				q.cancelTask(task, context.Cause(ctx))
+				return
+			}
+			q.failTask(task, fmt.Errorf("Verification failed: %v", err))
+			return
+		}
+	}
+
+	if err := q.commitOutput(task); err != nil {
+		q.failTask(task, err)
+		return
+	}
+
+	if err := q.uploadOutput(task, ctx); err != nil {
+		if context.Cause(ctx) != nil {
+			q.cancelTask(task, context.Cause(ctx))
+			return
+		}
+		q.failTask(task, fmt.Errorf("Uploading output failed: %v", err))
+		return
+	}
 
-	err = q.prePostProcessTask(task, task.PostProcessing, "post")
+	err := q.prePostProcessTask(task, ctx, task.PostProcessing, "post")
 	if err != nil {
+		if context.Cause(ctx) != nil {
+			q.cancelTask(task, context.Cause(ctx))
+			return
+		}
 		q.failTask(task, fmt.Errorf("PostProcessing failed: %v", err))
 		return
 	}
 
+	q.cleanupWorkspace(task, false)
 	task.FinishedAt = time.Now().UnixMilli()
 	task.Status = dto.DONE_SUCCESSFUL
 	q.updateTask(task)
 	q.Sev.Logger().Infof("task successful (uuid: %s)", task.Uuid)
 }
 
-func (q *Queue) prePostProcessTask(task *model.Task, processor *dto.PrePostProcessing, processorType string) error {
-	if processor != nil && (processor.SidecarPath != nil || processor.ScriptPath != nil) {
+func (q *Queue) prePostProcessTask(task *model.Task, ctx context.Context, processor *dto.PrePostProcessing, processorType string) error {
+	if processor != nil && (processor.SidecarPath != nil || processor.ScriptPath != nil || len(processor.Actions) > 0) {
 		if processorType == "pre" {
 			q.Sev.Metrics().GaugeVec("task.preProcessing").WithLabelValues(strconv.FormatBool(processor.SidecarPath != nil && processor.SidecarPath.Raw == ""), strconv.FormatBool(processor.ScriptPath != nil && processor.ScriptPath.Raw == "")).Inc()
 		} else {
@@ -162,15 +316,17 @@ func (q *Queue) prePostProcessTask(task *model.Task, processor *dto.PrePostProce
 			task.Status = dto.POST_PROCESSING
 		}
 		q.updateTask(task)
+		// the written sidecar, a re-imported sidecar may only differ from it in the allowed fields
+		var sidecar []byte
 		if processor.SidecarPath != nil && processor.SidecarPath.Raw != "" {
 			b, err := json.Marshal(task.ToDto())
 			if err != nil {
 				q.Sev.Logger().Errorf("failed to marshal task to write sidecar file: %v", err)
 			} else {
 				if processorType == "pre" {
-					processor.SidecarPath.Resolved = wildcards.Replace(processor.SidecarPath.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source, task.Metadata)
+					processor.SidecarPath.Resolved = wildcards.Replace(processor.SidecarPath.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source, task.Metadata, service.TaskVariables(task))
 				} else {
-					processor.SidecarPath.Resolved = wildcards.Replace(processor.SidecarPath.Raw, task.InputFile.Resolved, task.OutputFile.Resolved, task.Source, task.Metadata)
+					processor.SidecarPath.Resolved = wildcards.Replace(processor.SidecarPath.Raw, task.InputFile.Resolved, task.OutputFile.Resolved, task.Source, task.Metadata, service.TaskVariables(task))
 				}
 				q.updateTask(task)
 
@@ -185,53 +341,54 @@ func (q *Queue) prePostProcessTask(task *model.Task, processor *dto.PrePostProce
 					processor.Error = fmt.Errorf("failed to write sidecar: %v", err).Error()
 					q.Sev.Logger().Errorf("failed to write sidecar file: %v", err)
 				} else {
+					sidecar = b
 					debug.Debugf("wrote sidecar file (uuid: %s)", task.Uuid)
 				}
 			}
 		}
 
 		if processor.Error == "" && processor.ScriptPath != nil && processor.ScriptPath.Raw != "" {
+			secrets := q.secrets(task)
+			var script string
+			var err error
 			if processorType == "pre" {
-				processor.ScriptPath.Resolved = wildcards.Replace(processor.ScriptPath.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source, task.Metadata)
+				script, err = wildcards.Render(processor.ScriptPath.Raw, task.InputFile.Raw, task.OutputFile.Raw, task.Source, task.Metadata, service.TaskVariables(task), secrets.Variables())
 			} else {
-				processor.ScriptPath.Resolved = wildcards.Replace(processor.ScriptPath.Raw, task.InputFile.Resolved, task.OutputFile.Resolved, task.Source, task.Metadata)
+				script, err = wildcards.Render(processor.ScriptPath.Raw, task.InputFile.Resolved, task.OutputFile.Resolved, task.Source, task.Metadata, service.TaskVariables(task), secrets.Variables())
 			}
+			processor.ScriptPath.Resolved = secrets.Mask(script)
 			q.updateTask(task)
-			args, err := shellwords.NewParser().Parse(processor.ScriptPath.Resolved)
+			if err == nil {
+				err = q.runScript(ctx, task, processor, processorType, script, secrets)
+			}
 			if err != nil {
-				processor.Error = err.Error()
-				q.Sev.Logger().Errorf("failed to parse %sProcessing script (uuid: %s): %v", processorType, task.Uuid, err)
-			} else {
-				cmd := exec.Command(args[0], args[1:]...)
-				debug.Debugf("triggered %sProcessing script (uuid: %s)", processorType, task.Uuid)
-
-				var stderr bytes.Buffer
-				cmd.Stderr = &stderr
-
-				if err := cmd.Start(); err != nil {
-					processor.Error = fmt.Sprintf("%s (exit code: %d)", stderr.String(), cmd.ProcessState.ExitCode())
-					q.Sev.Logger().Errorf("failed to start %sProcessing script with exit code %d (uuid: %s): stderr: %s", processorType, cmd.ProcessState.ExitCode(), task.Uuid, stderr.String())
-				} else {
-					if err := cmd.Wait(); err != nil {
-						processor.Error = fmt.Sprintf("%s (exit code: %d)", stderr.String(), cmd.ProcessState.ExitCode())
-						q.Sev.Logger().Errorf("failed %sProcessing script with exit code %d (uuid: %s): stderr: %s", processorType, cmd.ProcessState.ExitCode(), task.Uuid, stderr.String())
-					}
-				}
+				processor.Error = secrets.Mask(err.Error())
+				q.Sev.Logger().Errorf("failed %sProcessing script (uuid: %s): %s", processorType, task.Uuid, processor.Error)
 			}
 		}
 
-		// re-import the sidecar file and unmarshal into task
+		// re-import the sidecar file and apply its changes to the task
 		// enabled modifying the task from within a preProcess script by modifying the sideCar file before re-importing it
-		if processorType == "pre" && processor.SidecarPath != nil && processor.SidecarPath.Raw != "" && processor.ImportSidecar {
+		if processor.Error == "" && processorType == "pre" && sidecar != nil && processor.ImportSidecar {
 			b, err := os.ReadFile(processor.SidecarPath.Resolved)
 			if err != nil {
 				return err
 			}
-			err = json.Unmarshal(b, task)
+			changes, err := applyTaskChanges(task, sidecar, b)
 			if err != nil {
-				return err
+				processor.Error = fmt.Sprintf("rejected sidecar: %v", err)
+				q.Sev.Logger().Errorf("rejected sidecar file (uuid: %s): %v", task.Uuid, err)
+			} else {
+				processor.Changes = append(processor.Changes, changes...)
+				debug.Debugf("re-imported sidecar file with %d changes (uuid: %s)", len(changes), task.Uuid)
+			}
+		}
+
+		// the built-in actions run after the script and the re-imported sidecar
+		if processor.Error == "" && len(processor.Actions) > 0 {
+			if err := q.runActions(ctx, task, processor, processorType, q.secrets(task)); err != nil {
+				processor.Error = err.Error()
 			}
-			debug.Debugf("re-imported sidecar file (uuid: %s)", task.Uuid)
 		}
 
 		processor.FinishedAt = time.Now().UnixMilli()
@@ -245,6 +402,8 @@ func (q *Queue) prePostProcessTask(task *model.Task, processor *dto.PrePostProce
 }
 
 func (q *Queue) cancelTask(task *model.Task, err error) {
+	q.discardOutput(task)
+	q.cleanupWorkspace(task, false)
 	task.FinishedAt = time.Now().UnixMilli()
 	task.Progress = 100
 	task.Status = dto.DONE_CANCELED
@@ -254,12 +413,15 @@ func (q *Queue) cancelTask(task *model.Task, err error) {
 }
 
 func (q *Queue) failTask(task *model.Task, err error) {
+	q.discardOutput(task)
+	q.cleanupWorkspace(task, true)
 	task.FinishedAt = time.Now().UnixMilli()
 	task.Progress = 100
 	task.Status = dto.DONE_ERROR
-	task.Error = err.Error()
+	// errors may contain the output of commands that received secrets
+	task.Error = service.SecretService().Mask(err.Error())
 	q.updateTask(task)
-	q.Sev.Logger().Warnf("task failed (uuid: %s):\n%v", task.Uuid, err)
+	q.Sev.Logger().Warnf("task failed (uuid: %s):\n%s", task.Uuid, task.Error)
 }
 
 func (q *Queue) updateTask(task *model.Task) {
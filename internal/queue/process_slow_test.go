@@ -3,6 +3,9 @@
 package queue
 
 import (
+	"os"
+	"path/filepath"
+	"runtime"
 	"testing"
 	"time"
 
@@ -24,7 +27,7 @@ func setupTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Task{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Task{}, &model.Webhook{}, &model.Secret{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
@@ -91,4 +94,46 @@ func TestQueue(t *testing.T) {
 			t.Error("Expected error message to be set")
 		}
 	})
+	t.Run("Relative output file", func(t *testing.T) {
+		if runtime.GOOS == "windows" {
+			t.Skip("fake ffmpeg uses sh")
+		}
+		// ffmpeg runs inside the task's workspace, relative paths must still resolve against the server's working directory
+		dir := t.TempDir()
+		ffmpeg := filepath.Join(dir, "ffmpeg")
+		os.WriteFile(ffmpeg, []byte("#!/bin/sh\nfor arg; do case $arg in *.mp4) out=$arg;; esac; done\necho encoded > \"$out\"\n"), 0755)
+		os.WriteFile(filepath.Join(dir, "input.mp4"), []byte("input"), 0644)
+		viper.Set("ffmpeg", ffmpeg)
+		config.Init()
+		t.Chdir(dir)
+
+		task := &model.Task{
+			Uuid:         "5f1c7a2e-relative",
+			InputFile:    &dto.RawResolved{Raw: "input.mp4"},
+			OutputFile:   &dto.RawResolved{Raw: "out/output.mp4"},
+			Command:      &dto.RawResolved{Raw: "-i ${INPUT_FILE} ${OUTPUT_FILE}"},
+			AtomicOutput: true,
+			Status:       dto.QUEUED,
+		}
+		db.Create(task)
+
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			db.First(task, task.ID)
+			if task.Status == dto.DONE_SUCCESSFUL || task.Status == dto.DONE_ERROR {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+
+		if task.Status != dto.DONE_SUCCESSFUL {
+			t.Fatalf("Expected task status %s, got %s: %s", dto.DONE_SUCCESSFUL, task.Status, task.Error)
+		}
+		if task.OutputFile.Resolved != filepath.Join(dir, "out", "output.mp4") {
+			t.Errorf("Expected absolute output file, got %s", task.OutputFile.Resolved)
+		}
+		if b, err := os.ReadFile(filepath.Join(dir, "out", "output.mp4")); err != nil || string(b) != "encoded\n" {
+			t.Errorf("Expected output next to the input, got %q (err: %v)", b, err)
+		}
+	})
 }
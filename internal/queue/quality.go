@@ -0,0 +1,200 @@
+package queue
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+)
+
+// searchQuality encodes sample segments of the input at several CRF values and returns the highest CRF whose average score reaches the target
+func (q *Queue) searchQuality(task *model.Task, ctx context.Context) (int, error) {
+	search := task.QualitySearch
+	ffmpeg.QualitySearchDefaults(&search.NewQualitySearch)
+	search.Results = nil
+	search.Crf = 0
+	search.Error = ""
+	search.StartedAt = time.Now().UnixMilli()
+	search.FinishedAt = 0
+
+	task.Status = dto.QUALITY_SEARCH
+	task.Progress = 0
+	q.updateTask(task)
+	q.Sev.Logger().Infof("starting quality search (uuid: %s)", task.Uuid)
+
+	crf, err := q.runQualitySearch(task, ctx)
+	search.FinishedAt = time.Now().UnixMilli()
+	if err != nil {
+		search.Error = err.Error()
+		q.Sev.Logger().Infof("finished quality search with error (uuid: %s)", task.Uuid)
+		return 0, err
+	}
+	search.Crf = crf
+	task.Progress = 0
+	q.updateTask(task)
+	q.Sev.Logger().Infof("finished quality search, picked crf %d (uuid: %s)", crf, task.Uuid)
+	return crf, nil
+}
+
+func (q *Queue) runQualitySearch(task *model.Task, ctx context.Context) (int, error) {
+	search := task.QualitySearch
+
+	dir := filepath.Join(task.Workdir, "quality")
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create quality search directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	probe, err := ffmpeg.Probe(ctx, localInput(task))
+	if err != nil {
+		return 0, err
+	}
+	duration := probe.Duration()
+	if duration <= 0 {
+		return 0, fmt.Errorf("failed to determine input duration for quality search")
+	}
+
+	// binary search needs at most log2(range) iterations, each encodes and measures all samples
+	iterations := int(math.Ceil(math.Log2(float64(search.MaxCrf - search.MinCrf + 2))))
+	steps := float64(search.Samples + iterations*search.Samples)
+	done := 0.0
+	step := func() {
+		done++
+		task.Progress = math.Min(100, math.Round(done/steps*100*100)/100)
+		q.updateTask(task)
+	}
+
+	// extract evenly distributed samples, the executor decides the reference format
+	y4m := task.Executor == dto.EXECUTOR_SVTAV1
+	ext := ".mkv"
+	if y4m {
+		ext = ".y4m"
+	}
+	sampleDuration := math.Min(search.SampleDuration, duration)
+	samples := make([]string, search.Samples)
+	for i := range samples {
+		offset := math.Max(0, duration*float64(i+1)/float64(search.Samples+1)-sampleDuration/2)
+		samples[i] = filepath.Join(dir, fmt.Sprintf("sample_%d%s", i, ext))
+		if err := ffmpeg.ExtractSample(ctx, localInput(task), offset, sampleDuration, samples[i], y4m); err != nil {
+			return 0, fmt.Errorf("failed to extract sample %d: %v", i+1, err)
+		}
+		step()
+	}
+
+	outExt := sampleExtension(task)
+
+	secrets := q.secrets(task)
+	lo, hi, best := search.MinCrf, search.MaxCrf, -1
+	for lo <= hi {
+		crf := (lo + hi) / 2
+		result := dto.QualitySample{Crf: crf}
+		for i, sample := range samples {
+			encoded := filepath.Join(dir, fmt.Sprintf("sample_%d_crf%d%s", i, crf, outExt))
+			command, err := sampleCommand(task, sample, encoded, crf, secrets)
+			if err != nil {
+				return 0, err
+			}
+			err = ffmpeg.Execute(&ffmpeg.ExecutionRequest{
+				Task:       task,
+				Command:    command,
+				Executor:   task.Executor,
+				Logger:     q.Sev.Logger(),
+				Ctx:        ctx,
+				Dir:        task.Workdir,
+				Env:        taskEnv(task),
+				UpdateFunc: func(progress float64, remaining float64) {},
+			})
+			if err != nil {
+				return 0, fmt.Errorf("failed to encode sample %d at crf %d: %v", i+1, crf, err)
+			}
+			score, err := ffmpeg.MeasureQuality(ctx, search.Metric, encoded, sample)
+			if err != nil {
+				return 0, fmt.Errorf("failed to measure sample %d at crf %d: %v", i+1, crf, err)
+			}
+			if info, err := os.Stat(encoded); err == nil {
+				result.Size += info.Size()
+			}
+			result.Scores = append(result.Scores, score)
+			result.Score += score / float64(len(samples))
+			step()
+		}
+		result.Score = math.Round(result.Score*10000) / 10000
+		search.Results = append(search.Results, result)
+		debug.Debugf("quality search crf %d scored %f (target: %f) (uuid: %s)", crf, result.Score, search.Target, task.Uuid)
+
+		if result.Score >= search.Target {
+			best = crf
+			lo = crf + 1
+		} else {
+			hi = crf - 1
+		}
+	}
+
+	// no crf reached the target, fall back to the best quality of the allowed range
+	if best == -1 {
+		q.Sev.Logger().Warnf("quality search did not reach target %g, using minCrf %d (uuid: %s)", search.Target, search.MinCrf, task.Uuid)
+		best = search.MinCrf
+	}
+	return best, nil
+}
+
+// usesEncoderArgs reports whether the sample encodes are built from the encoder settings instead of the task's command
+func usesEncoderArgs(task *model.Task) bool {
+	return task.Encoder != nil && strings.Contains(task.Command.Raw, "${ENCODER_ARGS}")
+}
+
+// sampleExtension returns the container of the encoded samples
+func sampleExtension(task *model.Task) string {
+	if usesEncoderArgs(task) {
+		if task.Executor == dto.EXECUTOR_SVTAV1 {
+			return ".ivf"
+		}
+		return ".mkv"
+	}
+	if ext := filepath.Ext(task.OutputFile.Resolved); ext != "" {
+		return ext
+	}
+	return ".mkv"
+}
+
+// sampleCommand resolves the command for a single sample encode at the given crf.
+// Samples are video-only and keep the input's resolution, so with encoder settings only the encoder arguments are applied,
+// otherwise the task's command is used (ValidateQualitySearch rejects commands mapping other streams or filtering the video)
+func sampleCommand(task *model.Task, input string, output string, crf int, secrets wildcards.Secrets) (string, error) {
+	encoderArgs, err := ffmpeg.EncoderArgs(encoderWithCrf(task.Encoder, crf), task.Executor)
+	if err != nil {
+		return "", err
+	}
+	command := task.Command.Raw
+	if usesEncoderArgs(task) {
+		command = "-y -i ${INPUT_FILE} ${ENCODER_ARGS} ${OUTPUT_FILE}"
+		if task.Executor == dto.EXECUTOR_SVTAV1 {
+			command = "-i ${INPUT_FILE} ${ENCODER_ARGS} -b ${OUTPUT_FILE}"
+		}
+	}
+	return wildcards.Replace(command, input, output, task.Source, task.Metadata, service.TaskVariables(task), secrets.Variables(), wildcards.Variables{
+		"CRF":          strconv.Itoa(crf),
+		"ENCODER_ARGS": encoderArgs,
+	}), nil
+}
+
+// encoderWithCrf returns a copy of the encoder settings with the given crf, a crf of 0 leaves the settings untouched
+func encoderWithCrf(encoder *dto.EncoderSettings, crf int) *dto.EncoderSettings {
+	if encoder == nil || crf == 0 {
+		return encoder
+	}
+	e := *encoder
+	e.Crf = &crf
+	return &e
+}
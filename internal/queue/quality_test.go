@@ -0,0 +1,44 @@
+package queue
+
+import (
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestSampleCommand(t *testing.T) {
+	preset := 8
+	task := &model.Task{
+		Command:    &dto.RawResolved{Raw: "-i ${INPUT_FILE} -map 0:v -map 0:a -vf scale=1280:-2 ${ENCODER_ARGS} -c:a copy ${OUTPUT_FILE}"},
+		OutputFile: &dto.RawResolved{Resolved: "/out/file.mp4"},
+		Encoder:    &dto.EncoderSettings{Preset: &preset},
+	}
+	command, err := sampleCommand(task, "/tmp/sample.mkv", "/tmp/encoded.mkv", 32, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `-y -i "/tmp/sample.mkv" -c:v libsvtav1 -preset 8 -crf 32 "/tmp/encoded.mkv"`; command != want {
+		t.Errorf("Expected '%s', got: '%s'", want, command)
+	}
+
+	task.Executor = dto.EXECUTOR_SVTAV1
+	command, err = sampleCommand(task, "/tmp/sample.y4m", "/tmp/encoded.ivf", 32, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `-i "/tmp/sample.y4m" --preset 8 --crf 32 -b "/tmp/encoded.ivf"`; command != want {
+		t.Errorf("Expected '%s', got: '%s'", want, command)
+	}
+
+	task.Executor = dto.EXECUTOR_FFMPEG
+	task.Encoder = nil
+	task.Command.Raw = "-i ${INPUT_FILE} -c:v libx264 -crf ${CRF} ${OUTPUT_FILE}"
+	command, err = sampleCommand(task, "/tmp/sample.mkv", "/tmp/encoded.mp4", 32, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `-i "/tmp/sample.mkv" -c:v libx264 -crf 32 "/tmp/encoded.mp4"`; command != want {
+		t.Errorf("Expected '%s', got: '%s'", want, command)
+	}
+}
@@ -0,0 +1,131 @@
+package queue
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/mattn/go-shellwords"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+)
+
+// maxScriptOutput is the number of bytes of stdout and stderr kept per script
+const maxScriptOutput = 64 * 1024
+
+// runScript runs the pre- or postProcessing script of the task and keeps its output in the processor
+func (q *Queue) runScript(ctx context.Context, task *model.Task, processor *dto.PrePostProcessing, processorType string, script string, secrets wildcards.Secrets) error {
+	args, err := shellwords.NewParser().Parse(script)
+	if err != nil {
+		return err
+	}
+	if len(args) == 0 {
+		return errors.New("empty script")
+	}
+	// scripts given by path resolve against the server's working directory, not the workspace they run in
+	if strings.ContainsAny(args[0], `/\`) {
+		args[0] = absolutePath(args[0])
+	}
+
+	if processor.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, time.Duration(processor.Timeout)*time.Second)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	cmd.Dir = task.Workdir
+	cmd.Env = taskEnv(task)
+	// children of a killed script may still hold its output open
+	cmd.WaitDelay = 5 * time.Second
+	stdout, stderr := &tailBuffer{}, &tailBuffer{}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	debug.Debugf("triggered %sProcessing script (uuid: %s)", processorType, task.Uuid)
+
+	err = cmd.Run()
+	processor.Stdout = secrets.Mask(stdout.String())
+	processor.Stderr = secrets.Mask(stderr.String())
+	if processor.Stdout != "" {
+		debug.Debugf("%sProcessing script stdout (uuid: %s): %s", processorType, task.Uuid, processor.Stdout)
+	}
+	switch {
+	case err == nil:
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return fmt.Errorf("script timed out after %ds", processor.Timeout)
+	case cmd.ProcessState == nil:
+		// the script never started, there is no exit code
+		return fmt.Errorf("failed to start script: %s", secrets.Mask(err.Error()))
+	default:
+		return fmt.Errorf("%s (exit code: %d)", processor.Stderr, cmd.ProcessState.ExitCode())
+	}
+
+	if processor.ImportOutput {
+		if stdout.truncated {
+			return fmt.Errorf("failed to import script output: output exceeds %d bytes", maxScriptOutput)
+		}
+		if err := importOutput(task, processor, processorType, stdout.Bytes()); err != nil {
+			return fmt.Errorf("failed to import script output: %v", err)
+		}
+		debug.Debugf("imported %sProcessing script output (uuid: %s)", processorType, task.Uuid)
+	}
+	return nil
+}
+
+// importOutput applies the JSON document a script printed on stdout, it changes the allowed task fields before and is merged into the metadata after processing
+func importOutput(task *model.Task, processor *dto.PrePostProcessing, processorType string, output []byte) error {
+	output = bytes.TrimSpace(output)
+	if len(output) == 0 {
+		return nil
+	}
+	if processorType == "pre" {
+		base, err := json.Marshal(task.ToDto())
+		if err != nil {
+			return err
+		}
+		changes, err := applyTaskChanges(task, base, output)
+		if err != nil {
+			return err
+		}
+		processor.Changes = append(processor.Changes, changes...)
+		return nil
+	}
+
+	metadata := dto.InterfaceMap{}
+	if err := json.Unmarshal(output, &metadata); err != nil {
+		return err
+	}
+	if task.Metadata == nil {
+		task.Metadata = &dto.InterfaceMap{}
+	}
+	maps.Copy(*task.Metadata, metadata)
+	return nil
+}
+
+// tailBuffer keeps the last maxScriptOutput bytes written to it
+type tailBuffer struct {
+	bytes.Buffer
+	truncated bool
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	n := len(p)
+	if len(p) > maxScriptOutput {
+		p = p[len(p)-maxScriptOutput:]
+	}
+	if over := b.Len() + len(p) - maxScriptOutput; over > 0 {
+		b.Next(over)
+		b.truncated = true
+	}
+	b.Buffer.Write(p)
+	if n > len(p) {
+		b.truncated = true
+	}
+	return n, nil
+}
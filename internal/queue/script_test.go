@@ -0,0 +1,123 @@
+package queue
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+)
+
+func TestRunScript(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("scripts use sh")
+	}
+	q := &Queue{}
+	newTask := func() *model.Task {
+		return &model.Task{
+			Uuid:       "1a2b3c4d",
+			Name:       "before",
+			InputFile:  &dto.RawResolved{Raw: "in.mp4"},
+			OutputFile: &dto.RawResolved{Raw: "out.mp4"},
+			Metadata:   &dto.InterfaceMap{"kept": "yes"},
+			Workdir:    t.TempDir(),
+		}
+	}
+
+	t.Run("Capture output", func(t *testing.T) {
+		processor := &dto.PrePostProcessing{}
+		err := q.runScript(context.Background(), newTask(), processor, "post", `sh -c "echo out; echo err $FFMATE_TASK_UUID >&2"`, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if processor.Stdout != "out\n" || processor.Stderr != "err 1a2b3c4d\n" {
+			t.Errorf("Unexpected output %q %q", processor.Stdout, processor.Stderr)
+		}
+	})
+
+	t.Run("Exit code", func(t *testing.T) {
+		err := q.runScript(context.Background(), newTask(), &dto.PrePostProcessing{}, "post", `sh -c "echo broken >&2; exit 3"`, nil)
+		if err == nil || err.Error() != "broken\n (exit code: 3)" {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("Start failure", func(t *testing.T) {
+		err := q.runScript(context.Background(), newTask(), &dto.PrePostProcessing{}, "post", "/does/not/exist", nil)
+		if err == nil || !strings.HasPrefix(err.Error(), "failed to start script") {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("Timeout", func(t *testing.T) {
+		err := q.runScript(context.Background(), newTask(), &dto.PrePostProcessing{Timeout: 1}, "post", "sleep 5", nil)
+		if err == nil || err.Error() != "script timed out after 1s" {
+			t.Errorf("Unexpected error %v", err)
+		}
+	})
+
+	t.Run("Relative script path", func(t *testing.T) {
+		dir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(dir, "post.sh"), []byte("#!/bin/sh\necho relative\n"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		t.Chdir(dir)
+		processor := &dto.PrePostProcessing{}
+		if err := q.runScript(context.Background(), newTask(), processor, "post", "./post.sh", nil); err != nil {
+			t.Fatal(err)
+		}
+		if processor.Stdout != "relative\n" {
+			t.Errorf("Unexpected output %q", processor.Stdout)
+		}
+	})
+
+	t.Run("Mask secrets", func(t *testing.T) {
+		processor := &dto.PrePostProcessing{}
+		q.runScript(context.Background(), newTask(), processor, "post", `sh -c "echo token=abc123"`, wildcards.Secrets{"token": "abc123"})
+		if processor.Stdout != "token="+wildcards.SecretMask+"\n" {
+			t.Errorf("Unexpected output %q", processor.Stdout)
+		}
+	})
+
+	t.Run("Import post output", func(t *testing.T) {
+		task := newTask()
+		err := q.runScript(context.Background(), task, &dto.PrePostProcessing{ImportOutput: true}, "post", `sh -c 'echo "{\"url\": \"https://cdn/out.mp4\"}"'`, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (*task.Metadata)["url"] != "https://cdn/out.mp4" || (*task.Metadata)["kept"] != "yes" {
+			t.Errorf("Unexpected metadata %v", *task.Metadata)
+		}
+	})
+
+	t.Run("Import pre output", func(t *testing.T) {
+		task := newTask()
+		err := q.runScript(context.Background(), task, &dto.PrePostProcessing{ImportOutput: true}, "pre", `sh -c 'echo "{\"name\": \"after\"}"'`, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.Name != "after" {
+			t.Errorf("Unexpected name %s", task.Name)
+		}
+		if err := q.runScript(context.Background(), task, &dto.PrePostProcessing{ImportOutput: true}, "pre", "echo no json", nil); err == nil {
+			t.Error("Expected invalid output to fail")
+		}
+	})
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{}
+	b.Write([]byte(strings.Repeat("a", maxScriptOutput-1)))
+	if b.truncated {
+		t.Fatal("Expected buffer not to be truncated")
+	}
+	b.Write([]byte("bc"))
+	if !b.truncated || b.Len() != maxScriptOutput || !strings.HasSuffix(b.String(), "abc") {
+		t.Errorf("Unexpected buffer (len: %d, truncated: %v)", b.Len(), b.truncated)
+	}
+}
@@ -0,0 +1,111 @@
+package queue
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+// sidecarFields are the task fields a re-imported sidecar or the output of a preProcessing script may change
+var sidecarFields = []string{"name", "command", "outputFile", "metadata", "priority"}
+
+// applyTaskChanges validates a task document written by a preProcessing script against the document it is based on
+// and applies the changes of the allowed fields, a document changing any other field is rejected as a whole
+func applyTaskChanges(task *model.Task, base []byte, document []byte) ([]dto.FieldChange, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(document, &fields); err != nil {
+		return nil, fmt.Errorf("invalid JSON: %v", err)
+	}
+	var current map[string]any
+	if err := json.Unmarshal(base, &current); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	changes := []dto.FieldChange{}
+	for _, key := range keys {
+		var value any
+		json.Unmarshal(fields[key], &value)
+		old, ok := current[key]
+		// fields omitted as empty are unchanged if they are still empty
+		if (ok && reflect.DeepEqual(old, value)) || (!ok && isEmpty(value)) {
+			continue
+		}
+		if !slices.Contains(sidecarFields, key) {
+			return nil, fmt.Errorf("field '%s' must not be changed, only %s are allowed", key, strings.Join(sidecarFields, ", "))
+		}
+		changes = append(changes, dto.FieldChange{Field: key, Old: old, New: value})
+	}
+
+	// all changes are validated before the first one is applied
+	apply := make([]func(), 0, len(changes))
+	for _, change := range changes {
+		raw := fields[change.Field]
+		switch change.Field {
+		case "name":
+			var name string
+			if err := json.Unmarshal(raw, &name); err != nil {
+				return nil, fmt.Errorf("field 'name' must be a string")
+			}
+			apply = append(apply, func() { task.Name = name })
+		case "command", "outputFile":
+			var value dto.RawResolved
+			if err := json.Unmarshal(raw, &value); err != nil || value.Raw == "" {
+				return nil, fmt.Errorf("field '%s' must be an object with a non-empty raw value", change.Field)
+			}
+			if change.Field == "command" {
+				apply = append(apply, func() { task.Command = &dto.RawResolved{Raw: value.Raw} })
+			} else {
+				apply = append(apply, func() { task.OutputFile = &dto.RawResolved{Raw: value.Raw} })
+			}
+		case "metadata":
+			var metadata dto.InterfaceMap
+			if err := json.Unmarshal(raw, &metadata); err != nil {
+				return nil, fmt.Errorf("field 'metadata' must be an object")
+			}
+			if metadata == nil {
+				metadata = dto.InterfaceMap{}
+			}
+			apply = append(apply, func() { task.Metadata = &metadata })
+		case "priority":
+			var priority uint
+			if err := json.Unmarshal(raw, &priority); err != nil {
+				return nil, fmt.Errorf("field 'priority' must be a non-negative integer")
+			}
+			apply = append(apply, func() { task.Priority = priority })
+		}
+	}
+	for _, fn := range apply {
+		fn()
+	}
+	return changes, nil
+}
+
+func isEmpty(value any) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
@@ -0,0 +1,89 @@
+package queue
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestApplyTaskChanges(t *testing.T) {
+	newTask := func() (*model.Task, []byte, map[string]any) {
+		task := &model.Task{
+			Uuid:       "1a2b3c4d",
+			Name:       "before",
+			Status:     dto.PRE_PROCESSING,
+			Command:    &dto.RawResolved{Raw: "-i ${INPUT_FILE} ${OUTPUT_FILE}"},
+			InputFile:  &dto.RawResolved{Raw: "in.mp4"},
+			OutputFile: &dto.RawResolved{Raw: "out.mp4"},
+			Metadata:   &dto.InterfaceMap{"show": "pilot"},
+			Priority:   5,
+		}
+		base, _ := json.Marshal(task.ToDto())
+		var document map[string]any
+		json.Unmarshal(base, &document)
+		return task, base, document
+	}
+	apply := func(task *model.Task, base []byte, document map[string]any) ([]dto.FieldChange, error) {
+		b, _ := json.Marshal(document)
+		return applyTaskChanges(task, base, b)
+	}
+
+	t.Run("Unchanged", func(t *testing.T) {
+		task, base, document := newTask()
+		document["batch"] = ""
+		changes, err := apply(task, base, document)
+		if err != nil || len(changes) != 0 {
+			t.Errorf("Expected no changes, got %v (err: %v)", changes, err)
+		}
+	})
+
+	t.Run("Allowed changes", func(t *testing.T) {
+		task, base, document := newTask()
+		document["name"] = "after"
+		document["priority"] = 10
+		document["outputFile"] = map[string]any{"raw": "/out/${METADATA_show}.mp4"}
+		document["metadata"] = map[string]any{"show": "pilot", "season": 1}
+		changes, err := apply(task, base, document)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(changes) != 4 || changes[0].Field != "metadata" || changes[1].Field != "name" || changes[1].Old != "before" || changes[1].New != "after" {
+			t.Errorf("Unexpected changes %+v", changes)
+		}
+		if task.Name != "after" || task.Priority != 10 || task.OutputFile.Raw != "/out/${METADATA_show}.mp4" || (*task.Metadata)["season"] != float64(1) {
+			t.Errorf("Changes not applied %+v", task)
+		}
+	})
+
+	t.Run("Rejected fields", func(t *testing.T) {
+		for field, value := range map[string]any{"uuid": "other", "status": "DONE_SUCCESSFUL", "inputFile": map[string]any{"raw": "/etc/passwd"}, "session": "x"} {
+			task, base, document := newTask()
+			document["name"] = "after"
+			document[field] = value
+			_, err := apply(task, base, document)
+			if err == nil || !strings.Contains(err.Error(), "'"+field+"' must not be changed") {
+				t.Errorf("Expected change of %s to be rejected, got %v", field, err)
+			}
+			if task.Name != "before" {
+				t.Errorf("Rejected document changed the task")
+			}
+		}
+	})
+
+	t.Run("Invalid values", func(t *testing.T) {
+		for field, value := range map[string]any{"name": 1, "priority": -1, "metadata": "x", "command": map[string]any{"raw": ""}} {
+			task, base, document := newTask()
+			document[field] = value
+			if _, err := apply(task, base, document); err == nil {
+				t.Errorf("Expected invalid %s to be rejected", field)
+			}
+		}
+		task, base, _ := newTask()
+		if _, err := applyTaskChanges(task, base, []byte("not json")); err == nil {
+			t.Error("Expected invalid JSON to be rejected")
+		}
+	})
+}
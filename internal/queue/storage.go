@@ -0,0 +1,84 @@
+package queue
+
+import (
+	"context"
+	"math"
+	"path/filepath"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/storage"
+)
+
+// stageInput downloads a remote input into the task's staging directory
+func (q *Queue) stageInput(task *model.Task, ctx context.Context) error {
+	task.InputFile.Local = ""
+	if !storage.IsRemote(task.InputFile.Resolved) {
+		return nil
+	}
+
+	local := filepath.Join(task.Workdir, "staging", "input", storage.LocalName(task.InputFile.Resolved))
+	task.Status = dto.DOWNLOADING
+	task.Progress = 0
+	q.updateTask(task)
+	q.Sev.Logger().Infof("downloading input (uuid: %s)", task.Uuid)
+
+	if err := storage.Download(ctx, task.InputFile.Resolved, local, q.transferProgress(task)); err != nil {
+		return err
+	}
+	task.InputFile.Local = local
+	task.Progress = 0
+	q.updateTask(task)
+	q.Sev.Logger().Infof("downloaded input (uuid: %s)", task.Uuid)
+	return nil
+}
+
+// uploadOutput uploads the locally encoded output to its remote location
+func (q *Queue) uploadOutput(task *model.Task, ctx context.Context) error {
+	if task.OutputFile.Local == "" {
+		return nil
+	}
+
+	task.Status = dto.UPLOADING
+	task.Progress = 0
+	q.updateTask(task)
+	q.Sev.Logger().Infof("uploading output (uuid: %s)", task.Uuid)
+
+	if err := storage.Upload(ctx, task.OutputFile.Local, task.OutputFile.Resolved, q.transferProgress(task)); err != nil {
+		return err
+	}
+	// the local copy stays in the workspace for the postProcessing actions and is removed with it
+	task.Progress = 100
+	q.Sev.Logger().Infof("uploaded output (uuid: %s)", task.Uuid)
+	return nil
+}
+
+// transferProgress updates the task's progress at most once per second
+func (q *Queue) transferProgress(task *model.Task) storage.Progress {
+	var last time.Time
+	return func(done int64, total int64) {
+		if total <= 0 || time.Since(last) < time.Second {
+			return
+		}
+		last = time.Now()
+		task.Progress = math.Round(float64(done)/float64(total)*100*100) / 100
+		q.updateTask(task)
+	}
+}
+
+// localInput returns the path the input can be read from locally
+func localInput(task *model.Task) string {
+	if task.InputFile.Local != "" {
+		return task.InputFile.Local
+	}
+	return task.InputFile.Resolved
+}
+
+// localOutput returns the path the output is written to locally before it gets uploaded
+func localOutput(task *model.Task) string {
+	if task.OutputFile.Local != "" {
+		return task.OutputFile.Local
+	}
+	return task.OutputFile.Resolved
+}
@@ -0,0 +1,48 @@
+package queue
+
+import (
+	"context"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+)
+
+// verifyTask checks the output of a finished encode and stores the results on the task
+func (q *Queue) verifyTask(task *model.Task, ctx context.Context) error {
+	verification := task.Verification
+	ffmpeg.VerificationDefaults(&verification.NewVerification)
+	verification.Checks = nil
+	verification.Error = ""
+	verification.StartedAt = time.Now().UnixMilli()
+	verification.FinishedAt = 0
+
+	task.Status = dto.VERIFYING
+	q.updateTask(task)
+	q.Sev.Logger().Infof("starting verification (uuid: %s)", task.Uuid)
+
+	// chunks only cover a part of the input
+	var expected float64
+	if !verification.SkipDuration {
+		if task.Chunk != nil {
+			expected = task.Chunk.Duration
+		} else if probe, err := ffmpeg.Probe(ctx, localInput(task)); err == nil {
+			expected = probe.Duration()
+		} else {
+			debug.Debugf("skipping duration verification, failed to probe input (uuid: %s): %v", task.Uuid, err)
+		}
+	}
+
+	checks, err := ffmpeg.Verify(ctx, &verification.NewVerification, encodeOutput(task), expected)
+	verification.Checks = checks
+	verification.FinishedAt = time.Now().UnixMilli()
+	if err != nil {
+		verification.Error = err.Error()
+		q.Sev.Logger().Infof("finished verification with error (uuid: %s)", task.Uuid)
+		return err
+	}
+	q.updateTask(task)
+	q.Sev.Logger().Infof("finished verification (uuid: %s)", task.Uuid)
+	return nil
+}
@@ -0,0 +1,43 @@
+package queue
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/workspace"
+)
+
+// createWorkspace creates the task's isolated working directory
+func (q *Queue) createWorkspace(task *model.Task) error {
+	dir, err := workspace.Create(task.Uuid)
+	if err != nil {
+		return err
+	}
+	task.Workdir = dir
+	debug.Debugf("created workspace '%s' (uuid: %s)", dir, task.Uuid)
+	return nil
+}
+
+// cleanupWorkspace removes the task's working directory, it is kept for failed tasks if configured
+func (q *Queue) cleanupWorkspace(task *model.Task, failed bool) {
+	if task.Workdir == "" {
+		return
+	}
+	if err := workspace.Cleanup(task.Uuid, failed); err != nil {
+		q.Sev.Logger().Warnf("failed to remove workspace (uuid: %s): %v", task.Uuid, err)
+	}
+}
+
+// absolutePath resolves a relative local path against the server's working directory,
+// processes run inside the task's workspace and would resolve it there instead
+func absolutePath(p string) string {
+	if p == "" || p == "-" || filepath.IsAbs(p) || strings.Contains(p, "://") || strings.HasPrefix(p, "pipe:") {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	return abs
+}
@@ -2,11 +2,18 @@ package service
 
 import (
 	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
 
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+	"github.com/welovemedia/ffmate/internal/presets"
+	"github.com/welovemedia/ffmate/internal/storage"
 	"github.com/welovemedia/ffmate/sev"
+	"gorm.io/gorm"
 )
 
 type presetSvc struct {
@@ -58,7 +65,27 @@ func (s *presetSvc) DeletePreset(uuid string) error {
 }
 
 func (s *presetSvc) NewPreset(newPreset *dto.NewPreset) (*model.Preset, error) {
-	w, err := s.presetRepository.Create(newPreset)
+	return s.createPreset(newPreset, uuid.NewString())
+}
+
+// createPreset creates the preset with the given uuid, imported presets keep the uuid they were exported with
+func (s *presetSvc) createPreset(newPreset *dto.NewPreset, uuid string) (*model.Preset, error) {
+	if err := validatePreset(newPreset); err != nil {
+		return nil, err
+	}
+
+	w, err := s.presetRepository.Create(newPreset, uuid)
+	if err != nil {
+		s.sev.Logger().Warnf("failed to create preset: %+v", err)
+		return nil, err
+	}
+	s.presetCreated(w, newPreset)
+
+	return w, err
+}
+
+// presetCreated logs and announces a created preset
+func (s *presetSvc) presetCreated(w *model.Preset, newPreset *dto.NewPreset) {
 	s.sev.Logger().Infof("created new preset (uuid: %s)", w.Uuid)
 
 	if newPreset.GlobalPresetName != "" {
@@ -68,8 +95,6 @@ func (s *presetSvc) NewPreset(newPreset *dto.NewPreset) (*model.Preset, error) {
 	s.sev.Metrics().Gauge("preset.created").Inc()
 	WebhookService().Fire(dto.PRESET_CREATED, w.ToDto())
 	WebsocketService().Broadcast(PRESET_CREATED, w.ToDto())
-
-	return w, err
 }
 
 func (s *presetSvc) UpdatePreset(presetUuid string, newPreset *dto.NewPreset) (*model.Preset, error) {
@@ -78,20 +103,23 @@ func (s *presetSvc) UpdatePreset(presetUuid string, newPreset *dto.NewPreset) (*
 		return nil, err
 	}
 
-	p.Name = newPreset.Name
-	p.Description = newPreset.Description
-	p.Command = newPreset.Command
-	p.PreProcessing = newPreset.PreProcessing
-	p.PostProcessing = newPreset.PostProcessing
-	p.OutputFile = newPreset.OutputFile
-	p.Priority = newPreset.Priority
+	if err := validatePreset(newPreset); err != nil {
+		return nil, err
+	}
 
+	applyPreset(p, newPreset)
 	err = s.presetRepository.Update(p)
 	if err != nil {
 		s.sev.Logger().Warnf("failed to update preset (uuid: %s): %+v", p.Uuid, err)
 		return nil, err
 	}
+	s.presetUpdated(p, newPreset)
+
+	return p, err
+}
 
+// presetUpdated announces an updated preset
+func (s *presetSvc) presetUpdated(p *model.Preset, newPreset *dto.NewPreset) {
 	if newPreset.GlobalPresetName != "" {
 		s.sev.Metrics().GaugeVec("preset.global").WithLabelValues(newPreset.GlobalPresetName).Inc()
 	}
@@ -99,6 +127,201 @@ func (s *presetSvc) UpdatePreset(presetUuid string, newPreset *dto.NewPreset) (*
 	s.sev.Metrics().Gauge("preset.updated").Inc()
 	WebhookService().Fire(dto.PRESET_UPDATED, p.ToDto())
 	WebsocketService().Broadcast(PRESET_UPDATED, p.ToDto())
+}
 
-	return p, err
+// validatePreset checks the settings the struct tags of the preset can not express
+func validatePreset(newPreset *dto.NewPreset) error {
+	if err := ffmpeg.ValidateEncoder(newPreset.Encoder, newPreset.Command); err != nil {
+		return err
+	}
+	if err := ffmpeg.ValidateQualitySearch(newPreset.QualitySearch, newPreset.Command, newPreset.Encoder); err != nil {
+		return err
+	}
+	if err := ffmpeg.ValidateChunking(newPreset.Chunking); err != nil {
+		return err
+	}
+	if err := ffmpeg.ValidateVerification(newPreset.Verification); err != nil {
+		return err
+	}
+	if err := newPreset.PreProcessing.ValidateActions("pre", storage.IsRemote(newPreset.OutputFile)); err != nil {
+		return err
+	}
+	return newPreset.PostProcessing.ValidateActions("post", storage.IsRemote(newPreset.OutputFile))
+}
+
+// applyPreset copies the settings of the new preset to the stored one
+func applyPreset(p *model.Preset, newPreset *dto.NewPreset) {
+	p.Name = newPreset.Name
+	p.Description = newPreset.Description
+	p.Command = newPreset.Command
+	p.Executor = newPreset.Executor
+	p.Encoder = newPreset.Encoder
+	p.QualitySearch = newPreset.QualitySearch
+	p.Chunking = newPreset.Chunking
+	p.Verification = newPreset.Verification
+	p.Secrets = newPreset.Secrets
+	p.PreProcessing = newPreset.PreProcessing
+	p.PostProcessing = newPreset.PostProcessing
+	p.OutputFile = newPreset.OutputFile
+	p.AtomicOutput = newPreset.AtomicOutput
+	p.OutputPolicy = newPreset.OutputPolicy
+	p.Priority = newPreset.Priority
+	if newPreset.GlobalPresetName != "" {
+		p.GlobalPresetName = newPreset.GlobalPresetName
+		p.GlobalPresetVersion = newPreset.GlobalPresetVersion
+	}
+}
+
+// ExportPresets returns a bundle of all presets
+func (s *presetSvc) ExportPresets() (*dto.PresetBundle, error) {
+	ps, err := s.presetRepository.All()
+	if err != nil {
+		return nil, err
+	}
+	bundle := &dto.PresetBundle{Version: presets.BundleVersion, Presets: []dto.Preset{}}
+	for _, p := range *ps {
+		bundle.Presets = append(bundle.Presets, *p.ToDto())
+	}
+	return bundle, nil
+}
+
+// ImportPresets creates the presets of the bundle, existing presets with the same uuid are skipped unless overwrite is set.
+// Deleted presets with the same uuid are restored, the bundle is imported as a whole or not at all
+func (s *presetSvc) ImportPresets(bundle *dto.PresetBundle, overwrite bool) (*dto.PresetImportResult, error) {
+	result := &dto.PresetImportResult{Created: []string{}, Updated: []string{}, Skipped: []string{}}
+	type imported struct {
+		preset    *model.Preset
+		newPreset *dto.NewPreset
+	}
+	var created, updated []imported
+
+	err := s.presetRepository.DB.Transaction(func(tx *gorm.DB) error {
+		presetRepository := &repository.Preset{DB: tx}
+		for _, preset := range bundle.Presets {
+			newPreset := presets.FromPreset(&preset)
+			if preset.Uuid == "" {
+				preset.Uuid = uuid.NewString()
+			}
+			if err := validatePreset(newPreset); err != nil {
+				return fmt.Errorf("failed to import preset '%s': %v", preset.Name, err)
+			}
+
+			existing, err := presetRepository.FindByUuidUnscoped(preset.Uuid)
+			if err != nil {
+				return err
+			}
+			switch {
+			case existing.Uuid == "":
+				p, err := presetRepository.Create(newPreset, preset.Uuid)
+				if err != nil {
+					return fmt.Errorf("failed to import preset '%s': %v", preset.Name, err)
+				}
+				created = append(created, imported{p, newPreset})
+			case existing.DeletedAt.Valid:
+				applyPreset(existing, newPreset)
+				if err := presetRepository.Restore(existing); err != nil {
+					return fmt.Errorf("failed to import preset '%s': %v", preset.Name, err)
+				}
+				created = append(created, imported{existing, newPreset})
+			case overwrite:
+				applyPreset(existing, newPreset)
+				if err := presetRepository.Update(existing); err != nil {
+					return fmt.Errorf("failed to import preset '%s': %v", preset.Name, err)
+				}
+				updated = append(updated, imported{existing, newPreset})
+			default:
+				result.Skipped = append(result.Skipped, preset.Uuid)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		s.sev.Logger().Warnf("failed to import presets: %+v", err)
+		return &dto.PresetImportResult{Created: []string{}, Updated: []string{}, Skipped: []string{}}, err
+	}
+
+	// announce the presets once they are committed
+	for _, i := range created {
+		s.presetCreated(i.preset, i.newPreset)
+		result.Created = append(result.Created, i.preset.Uuid)
+	}
+	for _, i := range updated {
+		s.presetUpdated(i.preset, i.newPreset)
+		result.Updated = append(result.Updated, i.preset.Uuid)
+	}
+	s.sev.Logger().Infof("imported presets (created: %d, updated: %d, skipped: %d)", len(result.Created), len(result.Updated), len(result.Skipped))
+	return result, nil
+}
+
+// Library returns the presets of the built-in library and whether they are installed
+func (s *presetSvc) Library() ([]dto.LibraryPresetStatus, error) {
+	library, err := presets.Library()
+	if err != nil {
+		return nil, err
+	}
+	statuses := []dto.LibraryPresetStatus{}
+	for _, preset := range library {
+		status := dto.LibraryPresetStatus{LibraryPreset: preset}
+		if installed, err := s.installedLibraryPreset(preset.Name); err == nil {
+			status.Installed = installed.Uuid
+			status.InstalledVersion = installed.GlobalPresetVersion
+			status.UpdateAvailable = installed.GlobalPresetVersion < preset.Version
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// InstallLibraryPreset creates a preset from the library preset with the given name
+func (s *presetSvc) InstallLibraryPreset(name string) (*model.Preset, error) {
+	preset, err := presets.Find(name)
+	if err != nil {
+		return nil, err
+	}
+	if installed, err := s.installedLibraryPreset(name); err == nil {
+		return nil, fmt.Errorf("library preset '%s' is already installed (uuid: %s)", name, installed.Uuid)
+	}
+	return s.NewPreset(presets.NewPreset(preset))
+}
+
+// DiffLibraryPreset returns the changes upgrading the installed preset to the library preset would make
+func (s *presetSvc) DiffLibraryPreset(name string) ([]dto.FieldChange, error) {
+	preset, err := presets.Find(name)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := s.installedLibraryPreset(name)
+	if err != nil {
+		return nil, err
+	}
+	return presets.Diff(presets.FromPreset(installed.ToDto()), presets.NewPreset(preset))
+}
+
+// UpgradeLibraryPreset replaces the installed preset with the current library preset, the uuid is kept so tasks and watchfolders keep using it
+func (s *presetSvc) UpgradeLibraryPreset(name string) (*model.Preset, error) {
+	preset, err := presets.Find(name)
+	if err != nil {
+		return nil, err
+	}
+	installed, err := s.installedLibraryPreset(name)
+	if err != nil {
+		return nil, err
+	}
+	p, err := s.UpdatePreset(installed.Uuid, presets.NewPreset(preset))
+	if err != nil {
+		return nil, err
+	}
+	s.sev.Logger().Infof("upgraded library preset '%s' from version %d to %d (uuid: %s)", name, installed.GlobalPresetVersion, preset.Version, p.Uuid)
+	return p, nil
+}
+
+func (s *presetSvc) installedLibraryPreset(name string) (*model.Preset, error) {
+	ps, err := s.presetRepository.ByGlobalPresetName(name)
+	if err != nil {
+		return nil, err
+	}
+	if len(*ps) == 0 {
+		return nil, fmt.Errorf("library preset '%s' is not installed", name)
+	}
+	return &(*ps)[0], nil
 }
@@ -58,6 +58,65 @@ func TestPresetService(t *testing.T) {
 		}
 	})
 
+	t.Run("Reject invalid encoder settings", func(t *testing.T) {
+		crf := 30
+		bitrate := 3000
+		newPreset := &dto.NewPreset{
+			Name:    "Invalid Encoder",
+			Command: "-i ${INPUT_FILE} ${ENCODER_ARGS} ${OUTPUT_FILE}",
+			Encoder: &dto.EncoderSettings{Crf: &crf, TargetBitrate: &bitrate},
+		}
+
+		_, err := PresetService().NewPreset(newPreset)
+		if err == nil {
+			t.Error("Expected error when creating preset with crf and targetBitrate")
+		}
+	})
+
+	t.Run("Reject encoder settings without encoder args", func(t *testing.T) {
+		crf := 30
+		newPreset := &dto.NewPreset{
+			Name:    "Ignored Encoder",
+			Command: "-i ${INPUT_FILE} -c:v libx264 ${OUTPUT_FILE}",
+			Encoder: &dto.EncoderSettings{Crf: &crf},
+		}
+
+		_, err := PresetService().NewPreset(newPreset)
+		if err == nil {
+			t.Error("Expected error when creating preset with encoder settings but without ${ENCODER_ARGS}")
+		}
+	})
+
+	t.Run("Reject invalid actions", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			outputFile string
+			pre        []dto.NewAction
+			post       []dto.NewAction
+			valid      bool
+		}{
+			{"Post actions", "/out/output.mp4", nil, []dto.NewAction{{Type: dto.ACTION_CHECKSUM}, {Type: dto.ACTION_MOVE, Destination: "/archive/"}, {Type: dto.ACTION_HTTP, Url: "https://example.com"}, {Type: dto.ACTION_DELETE_SOURCE}}, true},
+			{"Missing destination", "/out/output.mp4", nil, []dto.NewAction{{Type: dto.ACTION_COPY}}, false},
+			{"Missing url", "/out/output.mp4", nil, []dto.NewAction{{Type: dto.ACTION_HTTP}}, false},
+			{"Pre http", "/out/output.mp4", []dto.NewAction{{Type: dto.ACTION_HTTP, Url: "https://example.com"}}, nil, true},
+			{"Pre delete source", "/out/output.mp4", []dto.NewAction{{Type: dto.ACTION_DELETE_SOURCE}}, nil, false},
+			{"Copy remote output", "s3://bucket/output.mp4", nil, []dto.NewAction{{Type: dto.ACTION_COPY, Destination: "/archive/"}}, true},
+			{"Move remote output", "s3://bucket/output.mp4", nil, []dto.NewAction{{Type: dto.ACTION_MOVE, Destination: "/archive/"}}, false},
+		}
+		for _, tt := range tests {
+			_, err := PresetService().NewPreset(&dto.NewPreset{
+				Name:           tt.name,
+				Command:        "-i ${INPUT_FILE} ${OUTPUT_FILE}",
+				OutputFile:     tt.outputFile,
+				PreProcessing:  &dto.NewPrePostProcessing{Actions: tt.pre},
+				PostProcessing: &dto.NewPrePostProcessing{Actions: tt.post},
+			})
+			if (err == nil) != tt.valid {
+				t.Errorf("%s: NewPreset() = %v, want valid %v", tt.name, err, tt.valid)
+			}
+		}
+	})
+
 	t.Run("List presets", func(t *testing.T) {
 		presets, total, err := PresetService().ListPresets(0, 10)
 		if err != nil {
@@ -0,0 +1,61 @@
+package service
+
+import (
+	"sync"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/sev"
+)
+
+type queueSvc struct {
+	service
+	sev *sev.Sev
+
+	mu     sync.RWMutex
+	status dto.QueueStatus
+}
+
+// Status returns whether the queue is currently held and why
+func (s *queueSvc) Status() dto.QueueStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+// Hold stops the queue from starting new tasks, an alert is only emitted when the reason changes
+func (s *queueSvc) Hold(reason string) {
+	s.mu.Lock()
+	if s.status.Held && s.status.Reason == reason {
+		s.mu.Unlock()
+		return
+	}
+	if !s.status.Held {
+		s.status.HeldSince = time.Now().UnixMilli()
+	}
+	s.status.Held = true
+	s.status.Reason = reason
+	status := s.status
+	s.mu.Unlock()
+
+	s.sev.Logger().Warnf("queue held: %s", reason)
+	s.sev.Metrics().Gauge("queue.held").Inc()
+	WebhookService().Fire(dto.QUEUE_HELD, status)
+	WebsocketService().Broadcast(QUEUE_HELD, status)
+}
+
+// Resume lets the queue start new tasks again
+func (s *queueSvc) Resume() {
+	s.mu.Lock()
+	if !s.status.Held {
+		s.mu.Unlock()
+		return
+	}
+	s.status = dto.QueueStatus{}
+	status := s.status
+	s.mu.Unlock()
+
+	s.sev.Logger().Info("queue resumed")
+	WebhookService().Fire(dto.QUEUE_RESUMED, status)
+	WebsocketService().Broadcast(QUEUE_RESUMED, status)
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/database/repository"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+	"github.com/welovemedia/ffmate/sev"
+	"gorm.io/gorm"
+)
+
+type secretSvc struct {
+	service
+	sev              *sev.Sev
+	secretRepository *repository.Secret
+}
+
+var secretName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+func (s *secretSvc) ListSecrets(page int, perPage int) (*[]model.Secret, int64, error) {
+	return s.secretRepository.List(page, perPage)
+}
+
+func (s *secretSvc) GetSecretByName(name string) (*model.Secret, error) {
+	secret, err := s.secretRepository.First(name)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("secret '%s' not found", name)
+	}
+	return secret, err
+}
+
+func (s *secretSvc) NewSecret(newSecret *dto.NewSecret) (*model.Secret, error) {
+	if !secretName.MatchString(newSecret.Name) {
+		return nil, fmt.Errorf("invalid secret name '%s', only letters, digits and underscores are allowed", newSecret.Name)
+	}
+	if _, err := s.secretRepository.First(newSecret.Name); err == nil {
+		return nil, fmt.Errorf("secret '%s' already exists", newSecret.Name)
+	}
+
+	secret, err := s.secretRepository.Create(newSecret.Name, newSecret.Value, newSecret.Description)
+	if err != nil {
+		return nil, err
+	}
+	s.sev.Logger().Infof("created new secret '%s'", secret.Name)
+	return secret, nil
+}
+
+// UpdateSecret replaces the value and description of the secret, it cannot be renamed
+func (s *secretSvc) UpdateSecret(name string, newSecret *dto.NewSecret) (*model.Secret, error) {
+	if newSecret.Name != name {
+		return nil, errors.New("secrets cannot be renamed")
+	}
+	secret, err := s.GetSecretByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	secret.Value = newSecret.Value
+	secret.Description = newSecret.Description
+	secret, err = s.secretRepository.Update(secret)
+	if err != nil {
+		return nil, err
+	}
+	s.sev.Logger().Infof("updated secret '%s'", secret.Name)
+	return secret, nil
+}
+
+func (s *secretSvc) DeleteSecret(name string) error {
+	secret, err := s.GetSecretByName(name)
+	if err != nil {
+		return err
+	}
+	if err := s.secretRepository.Delete(secret); err != nil {
+		s.sev.Logger().Warnf("failed to delete secret '%s': %+v", secret.Name, err)
+		return err
+	}
+	s.sev.Logger().Infof("deleted secret '%s'", secret.Name)
+	return nil
+}
+
+// Secrets returns the values of the referenced secrets to resolve ${SECRET_<name>} wildcards
+func (s *secretSvc) Secrets(references dto.SecretReferences) (wildcards.Secrets, error) {
+	secrets, err := s.allSecrets()
+	if err != nil {
+		return wildcards.Secrets{}, err
+	}
+	return secrets.Only(references), nil
+}
+
+// Mask replaces the values of all secrets in the input
+func (s *secretSvc) Mask(input string) string {
+	secrets, err := s.allSecrets()
+	if err != nil {
+		s.sev.Logger().Warnf("failed to load secrets for masking: %v", err)
+	}
+	return secrets.Mask(input)
+}
+
+func (s *secretSvc) allSecrets() (wildcards.Secrets, error) {
+	list, err := s.secretRepository.All()
+	if err != nil {
+		return wildcards.Secrets{}, err
+	}
+	secrets := wildcards.Secrets{}
+	for _, secret := range *list {
+		secrets[secret.Name] = secret.Value
+	}
+	return secrets, nil
+}
@@ -11,6 +11,9 @@ type service struct {
 	watchfolder *watchfolderSvc
 	webhook     *webhookSvc
 	websocket   *websocketSvc
+	queue       *queueSvc
+	wildcard    *wildcardSvc
+	secret      *secretSvc
 }
 
 var services *service
@@ -19,9 +22,12 @@ func Init(s *sev.Sev) {
 	services = &service{
 		preset:      &presetSvc{sev: s, presetRepository: &repository.Preset{DB: s.DB()}},
 		task:        &taskSvc{sev: s, taskRepository: &repository.Task{DB: s.DB()}},
-		watchfolder: &watchfolderSvc{sev: s, watchfolderRepository: &repository.Watchfolder{DB: s.DB()}},
+		watchfolder: &watchfolderSvc{sev: s, watchfolderRepository: &repository.Watchfolder{DB: s.DB()}, watchfolderFileRepository: &repository.WatchfolderFile{DB: s.DB()}},
 		webhook:     &webhookSvc{sev: s, webhookRepository: &repository.Webhook{DB: s.DB()}},
 		websocket:   &websocketSvc{},
+		queue:       &queueSvc{sev: s},
+		wildcard:    &wildcardSvc{sev: s},
+		secret:      &secretSvc{sev: s, secretRepository: &repository.Secret{DB: s.DB()}},
 	}
 }
 
@@ -45,3 +51,15 @@ func WebhookService() *webhookSvc {
 func WebsocketService() *websocketSvc {
 	return services.websocket
 }
+
+func QueueService() *queueSvc {
+	return services.queue
+}
+
+func WildcardService() *wildcardSvc {
+	return services.wildcard
+}
+
+func SecretService() *secretSvc {
+	return services.secret
+}
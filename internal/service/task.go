@@ -2,12 +2,18 @@ package service
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+	"github.com/welovemedia/ffmate/internal/storage"
+	"github.com/welovemedia/ffmate/internal/workspace"
 	"github.com/welovemedia/ffmate/sev"
 )
 
@@ -19,6 +25,9 @@ type taskSvc struct {
 
 var taskUpdates = make(chan *model.Task, 100)
 
+// chunkMu serializes updates of chunked parent tasks caused by their chunks
+var chunkMu = &sync.Mutex{}
+
 func (s *taskSvc) CountAllStatus(session bool) (queued, running, doneSuccessful, doneError, doneCanceled int, err error) {
 	if session {
 		return s.taskRepository.CountAllStatus(s.sev.Session())
@@ -48,6 +57,19 @@ func (s *taskSvc) UpdateTask(task *model.Task) (*model.Task, error) {
 	s.sev.Metrics().Gauge("task.updated").Inc()
 	WebhookService().Fire(dto.TASK_UPDATED, task.ToDto())
 
+	if task.Parent != "" && task.Chunk != nil {
+		s.updateChunkParent(task)
+	}
+
+	// chunks share the source of their parent but post actions only apply to the parent,
+	// batched tasks run them once the whole batch is finished
+	if task.Source == "watchfolder" && task.Parent == "" && task.Batch == "" {
+		switch task.Status {
+		case dto.DONE_SUCCESSFUL, dto.DONE_ERROR, dto.DONE_CANCELED:
+			WatchfolderService().taskFinished(task)
+		}
+	}
+
 	if task.Batch != "" {
 		switch task.Status {
 		case dto.DONE_SUCCESSFUL, dto.DONE_ERROR, dto.DONE_CANCELED:
@@ -56,6 +78,9 @@ func (s *taskSvc) UpdateTask(task *model.Task) (*model.Task, error) {
 				WebsocketService().Broadcast(BATCH_FINISHED, task.ToDto())
 				s.sev.Metrics().Gauge("batch.finished").Inc()
 				WebhookService().Fire(dto.BATCH_FINISHED, task.ToDto())
+				if task.Source == "watchfolder" {
+					s.batchFinished(task.Batch)
+				}
 			}
 		}
 	}
@@ -73,7 +98,7 @@ func (s *taskSvc) DeleteTask(uuid string) error {
 		return errors.New("task for given uuid not found")
 	}
 
-	if w.Status == dto.RUNNING {
+	if w.Status.IsActive() {
 		return errors.New("running tasks can not be deleted, cancel first")
 	}
 
@@ -107,6 +132,10 @@ func (s *taskSvc) RestartTask(uuid string) (*model.Task, error) {
 	t.FinishedAt = 0
 	t.Error = ""
 	t.Status = dto.QUEUED
+	if t.Chunking != nil {
+		// a restarted chunked task is split again, chunks of the previous run are ignored
+		t.Chunking = &dto.Chunking{NewChunking: t.Chunking.NewChunking}
+	}
 	s.sev.Metrics().Gauge("task.restarted").Inc()
 	return s.UpdateTask(t)
 }
@@ -117,20 +146,30 @@ func (s *taskSvc) CancelTask(uuid string) (*model.Task, error) {
 		return nil, err
 	}
 
-	if t.Status != dto.QUEUED && t.Status != dto.RUNNING {
+	switch {
+	case t.Status == dto.QUEUED || t.Status == dto.CHUNKING:
+	case t.Status.IsActive():
+		// the queue cancels the context of the task's worker
+		taskUpdates <- t
+	default:
 		return nil, errors.New("failed to cancel task, task in unsupported state")
 	}
 
-	if t.Status == dto.RUNNING {
-		taskUpdates <- t
+	chunking := t.Status == dto.CHUNKING
+	if chunking {
+		chunkMu.Lock()
 	}
-
 	t.Progress = 100
 	t.Remaining = -1
 	t.FinishedAt = time.Now().UnixMilli()
 	t.Status = dto.DONE_CANCELED
 	s.sev.Metrics().Gauge("task.canceled").Inc()
-	return s.UpdateTask(t)
+	t, err = s.UpdateTask(t)
+	if chunking {
+		chunkMu.Unlock()
+		s.cancelChunks(t)
+	}
+	return t, err
 }
 
 func (s *taskSvc) NewTask(task *dto.NewTask, batch string, source string) (*model.Task, error) {
@@ -140,19 +179,65 @@ func (s *taskSvc) NewTask(task *dto.NewTask, batch string, source string) (*mode
 			return nil, err
 		}
 		task.Command = preset.Command
+		if task.Executor == "" {
+			task.Executor = preset.Executor
+		}
+		if task.Encoder == nil {
+			task.Encoder = preset.Encoder
+		}
+		if task.QualitySearch == nil && preset.QualitySearch != nil {
+			qualitySearch := *preset.QualitySearch
+			task.QualitySearch = &qualitySearch
+		}
+		if task.Chunking == nil && preset.Chunking != nil {
+			chunking := *preset.Chunking
+			task.Chunking = &chunking
+		}
+		if task.Verification == nil && preset.Verification != nil {
+			verification := *preset.Verification
+			task.Verification = &verification
+		}
+		if task.Secrets == nil {
+			task.Secrets = preset.Secrets
+		}
 		if task.OutputFile == "" {
 			task.OutputFile = preset.OutputFile
 		}
-		if task.Priority == 0 {
-			task.Priority = preset.Priority
+		if !task.AtomicOutput {
+			task.AtomicOutput = preset.AtomicOutput
+		}
+		if task.OutputPolicy == "" {
+			task.OutputPolicy = preset.OutputPolicy
+		}
+		if task.Priority == nil {
+			task.Priority = &preset.Priority
 		}
 		if preset.PreProcessing != nil && task.PreProcessing == nil {
-			task.PreProcessing = &dto.NewPrePostProcessing{ScriptPath: preset.PreProcessing.ScriptPath, SidecarPath: preset.PreProcessing.SidecarPath, ImportSidecar: preset.PreProcessing.ImportSidecar}
+			task.PreProcessing = &dto.NewPrePostProcessing{ScriptPath: preset.PreProcessing.ScriptPath, SidecarPath: preset.PreProcessing.SidecarPath, ImportSidecar: preset.PreProcessing.ImportSidecar, ImportOutput: preset.PreProcessing.ImportOutput, Timeout: preset.PreProcessing.Timeout, Actions: preset.PreProcessing.Actions}
 		}
 		if preset.PostProcessing != nil && task.PostProcessing == nil {
-			task.PostProcessing = &dto.NewPrePostProcessing{ScriptPath: preset.PostProcessing.ScriptPath, SidecarPath: preset.PostProcessing.SidecarPath}
+			task.PostProcessing = &dto.NewPrePostProcessing{ScriptPath: preset.PostProcessing.ScriptPath, SidecarPath: preset.PostProcessing.SidecarPath, ImportOutput: preset.PostProcessing.ImportOutput, Timeout: preset.PostProcessing.Timeout, Actions: preset.PostProcessing.Actions}
 		}
 	}
+	if err := ffmpeg.ValidateEncoder(task.Encoder, task.Command); err != nil {
+		return nil, err
+	}
+	if err := ffmpeg.ValidateQualitySearch(task.QualitySearch, task.Command, task.Encoder); err != nil {
+		return nil, err
+	}
+	if err := ffmpeg.ValidateChunking(task.Chunking); err != nil {
+		return nil, err
+	}
+	if err := ffmpeg.ValidateVerification(task.Verification); err != nil {
+		return nil, err
+	}
+	if err := task.PreProcessing.ValidateActions("pre", storage.IsRemote(task.OutputFile)); err != nil {
+		return nil, err
+	}
+	if err := task.PostProcessing.ValidateActions("post", storage.IsRemote(task.OutputFile)); err != nil {
+		return nil, err
+	}
+
 	t, err := s.taskRepository.Create(task, batch, source, s.sev.Session())
 	if err != nil {
 		return nil, err
@@ -167,10 +252,15 @@ func (s *taskSvc) NewTask(task *dto.NewTask, batch string, source string) (*mode
 }
 
 func (s *taskSvc) NewTasks(tasks *[]dto.NewTask) (*[]model.Task, error) {
+	return s.NewBatch(tasks, "api")
+}
+
+// NewBatch creates all tasks in a new batch and fires batch.created once every task was added
+func (s *taskSvc) NewBatch(tasks *[]dto.NewTask, source string) (*[]model.Task, error) {
 	batch := uuid.NewString()
 	newTasks := []model.Task{}
 	for _, task := range *tasks {
-		t, err := s.NewTask(&task, batch, "api")
+		t, err := s.NewTask(&task, batch, source)
 		if err != nil {
 			return nil, err
 		}
@@ -188,3 +278,128 @@ func (s *taskSvc) NewTasks(tasks *[]dto.NewTask) (*[]model.Task, error) {
 	WebhookService().Fire(dto.BATCH_CREATED, taskDTOs)
 	return &newTasks, nil
 }
+
+// batchFinished hands the finished batch of a watchfolder to its post actions, the batch failed if any
+// of its tasks failed and is canceled if any was canceled
+func (s *taskSvc) batchFinished(batch string) {
+	tasks, err := s.taskRepository.RootsByBatchId(batch)
+	if err != nil || len(*tasks) == 0 {
+		s.sev.Logger().Warnf("failed to load tasks of finished batch, post actions are not applied (batch: %s): %v", batch, err)
+		return
+	}
+	task := (*tasks)[0]
+	for _, t := range *tasks {
+		if t.Status == dto.DONE_ERROR || (t.Status == dto.DONE_CANCELED && task.Status != dto.DONE_ERROR) {
+			task.Status = t.Status
+		}
+	}
+	WatchfolderService().taskFinished(&task)
+}
+
+// updateChunkParent aggregates the progress of all chunks into their parent, retries failed chunks and
+// re-queues the parent for concatenation once every chunk succeeded
+func (s *taskSvc) updateChunkParent(chunk *model.Task) {
+	chunkMu.Lock()
+	parent, retry, cancel := s.aggregateChunks(chunk)
+	chunkMu.Unlock()
+
+	if retry {
+		chunk.Chunk.Attempts++
+		s.sev.Logger().Warnf("retrying chunk %d (attempt %d/%d) (uuid: %s)", chunk.Chunk.Index, chunk.Chunk.Attempts+1, parent.Chunking.MaxAttempts, chunk.Uuid)
+		chunk.Progress = 0
+		chunk.Remaining = 0
+		chunk.StartedAt = 0
+		chunk.FinishedAt = 0
+		chunk.Status = dto.QUEUED
+		s.UpdateTask(chunk)
+	}
+	if cancel {
+		s.cancelChunks(parent)
+	}
+}
+
+func (s *taskSvc) aggregateChunks(chunk *model.Task) (parent *model.Task, retry bool, cancel bool) {
+	parent, err := s.taskRepository.First(chunk.Parent)
+	if err != nil || parent.Status != dto.CHUNKING || parent.Chunking == nil || parent.Chunking.Run != chunk.Chunk.Run {
+		return parent, false, false
+	}
+
+	if chunk.Status == dto.DONE_ERROR {
+		if chunk.Chunk.Attempts+1 < parent.Chunking.MaxAttempts {
+			return parent, true, false
+		}
+		parent.Progress = 100
+		parent.Remaining = -1
+		parent.FinishedAt = time.Now().UnixMilli()
+		parent.Status = dto.DONE_ERROR
+		parent.Error = fmt.Sprintf("chunk %d failed after %d attempts: %s", chunk.Chunk.Index, chunk.Chunk.Attempts+1, chunk.Error)
+		s.UpdateTask(parent)
+		s.sev.Logger().Warnf("chunked task failed (uuid: %s): %s", parent.Uuid, parent.Error)
+		return parent, false, true
+	}
+	if chunk.Status == dto.DONE_CANCELED {
+		parent.Progress = 100
+		parent.Remaining = -1
+		parent.FinishedAt = time.Now().UnixMilli()
+		parent.Status = dto.DONE_CANCELED
+		parent.Error = fmt.Sprintf("chunk %d was canceled", chunk.Chunk.Index)
+		s.UpdateTask(parent)
+		return parent, false, true
+	}
+
+	chunks, err := s.taskRepository.ByParent(parent.Uuid)
+	if err != nil {
+		return parent, false, false
+	}
+	var total, done float64
+	succeeded := 0
+	for _, c := range *chunks {
+		if c.Chunk == nil || c.Chunk.Run != parent.Chunking.Run {
+			continue
+		}
+		if c.Uuid == chunk.Uuid {
+			c = *chunk
+		}
+		total += c.Chunk.Duration
+		if c.Status == dto.DONE_SUCCESSFUL {
+			succeeded++
+			done += c.Chunk.Duration
+		} else {
+			done += c.Chunk.Duration * c.Progress / 100
+		}
+	}
+	if total > 0 {
+		parent.Progress = math.Round(done/total*100*100) / 100
+	}
+	parent.Remaining = -1
+
+	if succeeded == parent.Chunking.Chunks {
+		parent.Chunking.Phase = dto.CHUNKING_PHASE_CONCAT
+		parent.Status = dto.QUEUED
+		s.sev.Logger().Infof("all %d chunks finished, queued concatenation (uuid: %s)", succeeded, parent.Uuid)
+	}
+	s.UpdateTask(parent)
+	return parent, false, false
+}
+
+// cancelChunks cancels all unfinished chunks of the parent's current run and removes the parent's workspace
+func (s *taskSvc) cancelChunks(parent *model.Task) {
+	if parent == nil || parent.Chunking == nil {
+		return
+	}
+	if err := workspace.Cleanup(parent.Uuid, parent.Status == dto.DONE_ERROR); err != nil {
+		s.sev.Logger().Warnf("failed to remove workspace (uuid: %s): %v", parent.Uuid, err)
+	}
+	chunks, err := s.taskRepository.ByParent(parent.Uuid)
+	if err != nil {
+		return
+	}
+	for _, c := range *chunks {
+		if c.Chunk == nil || c.Chunk.Run != parent.Chunking.Run || c.Status.IsFinal() {
+			continue
+		}
+		if _, err := s.CancelTask(c.Uuid); err != nil {
+			s.sev.Logger().Warnf("failed to cancel chunk (uuid: %s): %v", c.Uuid, err)
+		}
+	}
+}
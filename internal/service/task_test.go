@@ -1,6 +1,7 @@
 package service
 
 import (
+	"fmt"
 	"testing"
 
 	"github.com/welovemedia/ffmate/internal/database/model"
@@ -37,11 +38,12 @@ func TestTaskService(t *testing.T) {
 	defer sqlDB.Close()
 
 	t.Run("Create and find task", func(t *testing.T) {
+		priority := uint(1)
 		newTask := &dto.NewTask{
 			InputFile:  "/test/input.mp4",
 			OutputFile: "/test/output.mp4",
 			Command:    "ffmpeg -i ${INPUT_FILE} ${OUTPUT_FILE}",
-			Priority:   1,
+			Priority:   &priority,
 		}
 
 		task, err := TaskService().NewTask(newTask, "", "test")
@@ -118,4 +120,48 @@ func TestTaskService(t *testing.T) {
 			t.Error("Expected error when finding deleted task")
 		}
 	})
+	t.Run("Delete active task", func(t *testing.T) {
+		for _, status := range []dto.TaskStatus{dto.RUNNING, dto.QUALITY_SEARCH, dto.CHUNKING, dto.VERIFYING, dto.UPLOADING, dto.POST_PROCESSING} {
+			task, err := TaskService().NewTask(&dto.NewTask{InputFile: "/test/input.mp4", OutputFile: "/test/output.mp4", Command: "test"}, "", "test")
+			if err != nil {
+				t.Fatalf("Failed to create task: %v", err)
+			}
+			db.Model(&model.Task{}).Where("uuid = ?", task.Uuid).Update("status", status)
+
+			if err := TaskService().DeleteTask(task.Uuid); err == nil {
+				t.Errorf("Expected deleting a task in status %s to fail", status)
+			}
+		}
+	})
+	t.Run("Cancel chunked task", func(t *testing.T) {
+		parent := &model.Task{Uuid: "chunked-parent", Status: dto.CHUNKING, Chunking: &dto.Chunking{Run: "run-1"}}
+		db.Create(parent)
+		statuses := []dto.TaskStatus{dto.QUEUED, dto.RUNNING, dto.PRE_PROCESSING, dto.POST_PROCESSING, dto.DONE_SUCCESSFUL}
+		for i, status := range statuses {
+			db.Create(&model.Task{Uuid: fmt.Sprintf("chunk-%d", i), Parent: parent.Uuid, Status: status, Chunk: &dto.Chunk{Run: "run-1", Index: i}})
+		}
+
+		if _, err := TaskService().CancelTask(parent.Uuid); err != nil {
+			t.Fatalf("Failed to cancel task: %v", err)
+		}
+
+		// active chunks are handed to the queue to stop their workers
+		canceled := map[string]bool{}
+		for len(TaskService().GetTaskUpdates()) > 0 {
+			canceled[(<-TaskService().GetTaskUpdates()).Uuid] = true
+		}
+		for i, status := range statuses {
+			chunk, _ := TaskService().GetTaskByUuid(fmt.Sprintf("chunk-%d", i))
+			want := dto.DONE_CANCELED
+			if status == dto.DONE_SUCCESSFUL {
+				want = status
+			}
+			if chunk.Status != want {
+				t.Errorf("Expected chunk in status %s to be %s, got %s", status, want, chunk.Status)
+			}
+			if status.IsActive() != canceled[chunk.Uuid] {
+				t.Errorf("Expected the worker of the chunk in status %s to be canceled: %v", status, status.IsActive())
+			}
+		}
+	})
 }
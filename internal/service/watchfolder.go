@@ -1,18 +1,26 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/welovemedia/ffmate/internal/database/model"
 	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+	"github.com/welovemedia/ffmate/internal/watchfolder/filter"
 	"github.com/welovemedia/ffmate/sev"
 )
 
 type watchfolderSvc struct {
 	service
-	sev                   *sev.Sev
-	watchfolderRepository *repository.Watchfolder
+	sev                       *sev.Sev
+	watchfolderRepository     *repository.Watchfolder
+	watchfolderFileRepository *repository.WatchfolderFile
 }
 
 var watchfolderUpdates = make(chan *model.Watchfolder, 100)
@@ -21,6 +29,22 @@ func (s *watchfolderSvc) GetWatchfolderUpdates() chan *model.Watchfolder {
 	return watchfolderUpdates
 }
 
+var finishedWatchfolderTasks = make(chan *model.Task, 1000)
+
+// GetFinishedTasks returns the tasks created by watchfolders that reached a final state
+func (s *watchfolderSvc) GetFinishedTasks() chan *model.Task {
+	return finishedWatchfolderTasks
+}
+
+func (s *watchfolderSvc) taskFinished(task *model.Task) {
+	t := *task
+	select {
+	case finishedWatchfolderTasks <- &t:
+	default:
+		s.sev.Logger().Warnf("dropped finished watchfolder task, post actions are not applied (uuid: %s)", task.Uuid)
+	}
+}
+
 func (s *watchfolderSvc) ListWatchfolders(page int, perPage int) (*[]model.Watchfolder, int64, error) {
 	return s.watchfolderRepository.List(page, perPage)
 }
@@ -38,6 +62,21 @@ func (s *watchfolderSvc) UpdateWatchfolderInternal(watchfolder *model.Watchfolde
 	return w, err
 }
 
+// WatchfolderFailing notifies about a watchfolder that failed too many times in a row
+func (s *watchfolderSvc) WatchfolderFailing(watchfolder *model.Watchfolder) {
+	s.sev.Metrics().Gauge("watchfolder.failing").Inc()
+	WebhookService().Fire(dto.WATCHFOLDER_FAILING, watchfolder.ToDto())
+	WebsocketService().Broadcast(WATCHFOLDER_FAILING, watchfolder.ToDto())
+}
+
+func (s *watchfolderSvc) GetWatchfolderStats(uuid string) (*dto.WatchfolderStats, error) {
+	w, err := s.watchfolderRepository.First(uuid)
+	if err != nil {
+		return nil, err
+	}
+	return w.ToStatsDto(), nil
+}
+
 func (s *watchfolderSvc) DeleteWatchfolder(uuid string) error {
 	w, err := s.watchfolderRepository.First(uuid)
 	if err != nil {
@@ -54,6 +93,10 @@ func (s *watchfolderSvc) DeleteWatchfolder(uuid string) error {
 		return err
 	}
 
+	if _, err := s.watchfolderFileRepository.DeleteByWatchfolder(w.Uuid); err != nil {
+		s.sev.Logger().Warnf("failed to delete processed files of watchfolder (uuid: %s): %+v", w.Uuid, err)
+	}
+
 	s.sev.Logger().Infof("deleted watchfolder (uuid: %s)", w.Uuid)
 	watchfolderUpdates <- w
 
@@ -64,11 +107,34 @@ func (s *watchfolderSvc) DeleteWatchfolder(uuid string) error {
 	return nil
 }
 
+// validatePresets makes sure the watchfolder has at least one preset and all presets of the watchfolder and its rules exist
+func validatePresets(newWatchfolder *dto.NewWatchfolder) error {
+	presets := newWatchfolder.Presets
+	if len(presets) == 0 {
+		presets = []string{newWatchfolder.Preset}
+	}
+	for _, rule := range newWatchfolder.Rules {
+		presets = append(presets, rule.Presets...)
+	}
+	for _, preset := range presets {
+		if _, err := PresetService().FindByUuid(preset); err != nil {
+			return fmt.Errorf("preset '%s' not found: %v", preset, err)
+		}
+	}
+	return nil
+}
+
 func (s *watchfolderSvc) NewWatchfolder(newWatchfolder *dto.NewWatchfolder) (*model.Watchfolder, error) {
-	_, err := PresetService().FindByUuid(newWatchfolder.Preset)
+	err := validatePresets(newWatchfolder)
 	if err != nil {
 		return nil, err
 	}
+	if _, err := filter.Compile(newWatchfolder.Filter); err != nil {
+		return nil, err
+	}
+	if _, err := filter.CompileRules(newWatchfolder.Rules); err != nil {
+		return nil, err
+	}
 	w, err := s.watchfolderRepository.Create(newWatchfolder)
 
 	s.sev.Logger().Infof("created new watchfolder (uuid: %s)", w.Uuid)
@@ -86,15 +152,38 @@ func (s *watchfolderSvc) UpdateWatchfolder(watchfolderUuid string, newWatchfolde
 	if err != nil {
 		return nil, err
 	}
+	if err := validatePresets(newWatchfolder); err != nil {
+		return nil, err
+	}
+	if _, err := filter.Compile(newWatchfolder.Filter); err != nil {
+		return nil, err
+	}
+	if _, err := filter.CompileRules(newWatchfolder.Rules); err != nil {
+		return nil, err
+	}
 
 	w.Name = newWatchfolder.Name
 	w.Description = newWatchfolder.Description
 	w.Path = newWatchfolder.Path
 	w.Preset = newWatchfolder.Preset
+	w.Presets = newWatchfolder.Presets
+	w.Rules = newWatchfolder.Rules
 	w.GrowthChecks = newWatchfolder.GrowthChecks
 	w.Interval = newWatchfolder.Interval
 	w.Filter = newWatchfolder.Filter
+	// resuming a watchfolder gives it a fresh start
+	if w.Suspended && !newWatchfolder.Suspended {
+		w.ConsecutiveErrors = 0
+		w.Health = dto.WATCHFOLDER_HEALTH_OK
+	}
 	w.Suspended = newWatchfolder.Suspended
+	w.SuspendAfter = newWatchfolder.SuspendAfter
+	w.Mode = newWatchfolder.Mode
+	w.ReconcileInterval = newWatchfolder.ReconcileInterval
+	w.Unit = newWatchfolder.Unit
+	w.HashFiles = newWatchfolder.HashFiles
+	w.OnSuccess = newWatchfolder.OnSuccess
+	w.OnError = newWatchfolder.OnError
 
 	watchfolderUpdates <- w
 
@@ -102,3 +191,98 @@ func (s *watchfolderSvc) UpdateWatchfolder(watchfolderUuid string, newWatchfolde
 
 	return s.UpdateWatchfolderInternal(w)
 }
+
+func (s *watchfolderSvc) ListWatchfolderFiles(uuid string, page int, perPage int) (*[]model.WatchfolderFile, int64, error) {
+	if _, err := s.watchfolderRepository.First(uuid); err != nil {
+		return nil, 0, err
+	}
+	return s.watchfolderFileRepository.List(uuid, page, perPage)
+}
+
+// ResetWatchfolderFiles forgets all processed files of the watchfolder and restarts it, so every file in the folder is processed again
+func (s *watchfolderSvc) ResetWatchfolderFiles(uuid string) (int64, error) {
+	w, err := s.watchfolderRepository.First(uuid)
+	if err != nil {
+		return 0, err
+	}
+
+	count, err := s.watchfolderFileRepository.DeleteByWatchfolder(w.Uuid)
+	if err != nil {
+		s.sev.Logger().Warnf("failed to reset processed files of watchfolder (uuid: %s): %+v", w.Uuid, err)
+		return 0, err
+	}
+
+	s.sev.Logger().Infof("reset %d processed files of watchfolder (uuid: %s)", count, w.Uuid)
+	watchfolderUpdates <- w
+
+	return count, nil
+}
+
+// ProcessedWatchfolderFiles returns all files the watchfolder already created tasks for
+func (s *watchfolderSvc) ProcessedWatchfolderFiles(uuid string) (*[]model.WatchfolderFile, error) {
+	files, _, err := s.watchfolderFileRepository.List(uuid, -1, -1)
+	return files, err
+}
+
+func (s *watchfolderSvc) SaveWatchfolderFile(file *model.WatchfolderFile) (*model.WatchfolderFile, error) {
+	return s.watchfolderFileRepository.Save(file)
+}
+
+// DryRunWatchfolder returns the route a file would take through the rules of the watchfolder without creating tasks
+func (s *watchfolderSvc) DryRunWatchfolder(uuid string, path string) (*dto.WatchfolderRoute, error) {
+	w, err := s.watchfolderRepository.First(uuid)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(w.Path, path)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	return s.RouteFile(w, path, info)
+}
+
+// RouteFile evaluates the rules of the watchfolder for the file and returns the presets, priority and metadata its tasks are created with
+func (s *watchfolderSvc) RouteFile(w *model.Watchfolder, path string, info os.FileInfo) (*dto.WatchfolderRoute, error) {
+	route := &dto.WatchfolderRoute{Path: path, Rule: -1, Presets: w.AllPresets()}
+	if len(w.Rules) == 0 {
+		return route, nil
+	}
+
+	rules, err := filter.CompileRules(w.Rules)
+	if err != nil {
+		return nil, err
+	}
+	rel, err := filepath.Rel(w.Path, path)
+	if err != nil {
+		return nil, err
+	}
+
+	i, rule := rules.Route(rel, info, func() (int, int, error) {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		probe, err := ffmpeg.Probe(ctx, path)
+		if err != nil {
+			return 0, 0, err
+		}
+		v := probe.VideoStream()
+		if v == nil {
+			return 0, 0, fmt.Errorf("no video stream in '%s'", path)
+		}
+		return v.Width, v.Height, nil
+	})
+	if rule == nil {
+		return route, nil
+	}
+
+	route.Rule = i
+	route.RuleName = rule.Name
+	route.Priority = rule.Priority
+	route.Metadata = rule.Metadata
+	if len(rule.Presets) > 0 {
+		route.Presets = rule.Presets
+	}
+	return route, nil
+}
@@ -16,7 +16,7 @@ func setupWebhookTestDB(t *testing.T) (*gorm.DB, *sev.Sev) {
 		t.Fatalf("Failed to open test database: %v", err)
 	}
 
-	err = db.AutoMigrate(&model.Watchfolder{}, &model.Preset{}, &model.Webhook{})
+	err = db.AutoMigrate(&model.Watchfolder{}, &model.WatchfolderFile{}, &model.Preset{}, &model.Webhook{})
 	if err != nil {
 		t.Fatalf("Failed to migrate test database: %v", err)
 	}
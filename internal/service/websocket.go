@@ -25,6 +25,7 @@ const (
 	WATCHFOLDER_CREATED Subject = "watchfolder:created"
 	WATCHFOLDER_UPDATED Subject = "watchfolder:updated"
 	WATCHFOLDER_DELETED Subject = "watchfolder:deleted"
+	WATCHFOLDER_FAILING Subject = "watchfolder:failing"
 
 	WEBHOOK_CREATED Subject = "webhook:created"
 	WEBHOOK_UPDATED Subject = "webhook:updated"
@@ -33,6 +34,9 @@ const (
 	BATCH_CREATED  Subject = "batch:created"
 	BATCH_FINISHED Subject = "batch:finished"
 
+	QUEUE_HELD    Subject = "queue:held"
+	QUEUE_RESUMED Subject = "queue:resumed"
+
 	LOG Subject = "log"
 )
 
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/ffmpeg"
+	"github.com/welovemedia/ffmate/internal/utils/wildcards"
+	"github.com/welovemedia/ffmate/internal/workspace"
+	"github.com/welovemedia/ffmate/sev"
+)
+
+// TaskVariables returns the task specific wildcards available to all commands, paths and scripts of the task
+func TaskVariables(task *model.Task) wildcards.Variables {
+	return wildcards.Variables{
+		"TASK_WORKDIR": task.Workdir,
+		"BATCH_UUID":   task.Batch,
+	}
+}
+
+// CommandVariables returns the wildcards of the task's main command, output is the local file the command encodes to
+func CommandVariables(task *model.Task, encoderArgs string, output string) wildcards.Variables {
+	variables := TaskVariables(task)
+	variables["ENCODER_ARGS"] = encoderArgs
+	variables["OUTPUT_FILE"] = fmt.Sprintf("\"%s\"", output)
+	variables["OUTPUT_FILE_TEMP"] = variables["OUTPUT_FILE"]
+	// chunk boundaries for commands that place them explicitly
+	if task.Chunk != nil {
+		variables["CHUNK_INDEX"] = strconv.Itoa(task.Chunk.Index)
+		variables["CHUNK_START"] = strconv.FormatFloat(task.Chunk.Start, 'f', 6, 64)
+		variables["CHUNK_DURATION"] = strconv.FormatFloat(task.Chunk.Duration, 'f', 6, 64)
+	}
+	return variables
+}
+
+type wildcardSvc struct {
+	service
+	sev *sev.Sev
+}
+
+// Preview resolves the templates like a task would be processed without queueing anything
+func (s *wildcardSvc) Preview(preview *dto.WildcardPreview) (*dto.WildcardPreviewResult, error) {
+	source := preview.Source
+	if source == "" {
+		source = "api"
+	}
+	encoderArgs, err := ffmpeg.EncoderArgs(preview.Encoder, preview.Executor)
+	if err != nil {
+		return nil, err
+	}
+
+	config.Config().Mutex.RLock()
+	result := &dto.WildcardPreviewResult{Strict: config.Config().StrictWildcards, Unknown: []string{}, Empty: []string{}}
+	config.Config().Mutex.RUnlock()
+
+	// the same variables the queue passes to a task, the workspace does not exist for previews
+	task := &model.Task{Workdir: workspace.Dir("preview")}
+	variables := TaskVariables(task)
+	addReport := func(report *wildcards.Report) {
+		for _, name := range report.Unknown {
+			if !slices.Contains(result.Unknown, name) {
+				result.Unknown = append(result.Unknown, name)
+			}
+		}
+		for _, name := range report.Empty {
+			if !slices.Contains(result.Empty, name) {
+				result.Empty = append(result.Empty, name)
+			}
+		}
+	}
+
+	var report *wildcards.Report
+	result.InputFile, report, err = wildcards.Preview(preview.InputFile, preview.InputFile, preview.OutputFile, source, preview.Metadata, variables)
+	if err != nil {
+		return nil, err
+	}
+	addReport(report)
+	result.OutputFile, report, err = wildcards.Preview(preview.OutputFile, preview.InputFile, preview.OutputFile, source, preview.Metadata, variables)
+	if err != nil {
+		return nil, err
+	}
+	addReport(report)
+
+	// secrets resolve in commands only and are never revealed by a preview
+	secrets, err := SecretService().Secrets(preview.Secrets)
+	if err != nil {
+		return nil, err
+	}
+	result.Command, report, err = wildcards.Preview(preview.Command, result.InputFile, result.OutputFile, source, preview.Metadata, CommandVariables(task, encoderArgs, result.OutputFile), secrets.Masked())
+	if err != nil {
+		return nil, err
+	}
+	addReport(report)
+
+	result.Args, err = ffmpeg.CommandArgs(result.Command, preview.Executor)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// PreviewPreset resolves the templates of the preset, the preview may override its output file, executor and encoder
+func (s *wildcardSvc) PreviewPreset(uuid string, preview *dto.WildcardPreview) (*dto.WildcardPreviewResult, error) {
+	preset, err := PresetService().FindByUuid(uuid)
+	if err != nil {
+		return nil, err
+	}
+	p := *preview
+	p.Command = preset.Command
+	if p.OutputFile == "" {
+		p.OutputFile = preset.OutputFile
+	}
+	if p.Executor == "" {
+		p.Executor = preset.Executor
+	}
+	if p.Encoder == nil {
+		p.Encoder = preset.Encoder
+	}
+	if p.Secrets == nil {
+		p.Secrets = preset.Secrets
+	}
+	return s.Preview(&p)
+}
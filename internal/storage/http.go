@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// httpBackend downloads with GET and uploads with PUT, it handles stage+http:// and stage+https:// urls
+type httpBackend struct{}
+
+// requestUrl returns the http(s) url of a stage+http(s) url
+func requestUrl(u *url.URL) string {
+	r := *u
+	r.Scheme = strings.TrimPrefix(strings.ToLower(r.Scheme), "stage+")
+	return r.String()
+}
+
+func (b *httpBackend) Download(ctx context.Context, u *url.URL, dst string, progress Progress) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestUrl(u), nil)
+	if err != nil {
+		return err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := statusError(res); err != nil {
+		return err
+	}
+	return copyFile(dst, res.Body, res.ContentLength, progress)
+}
+
+func (b *httpBackend) Upload(ctx context.Context, src string, u *url.URL, progress Progress) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return &permanentError{err}
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, requestUrl(u), &progressReader{reader: f, total: info.Size(), progress: progress})
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	return statusError(res)
+}
+
+func (b *httpBackend) Exists(ctx context.Context, u *url.URL) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, requestUrl(u), nil)
+	if err != nil {
+		return false, err
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return res.StatusCode < 300, statusError(res)
+}
+
+// statusError turns unsuccessful responses into errors, client errors except timeouts and throttling are not retried
+func statusError(res *http.Response) error {
+	if res.StatusCode < 300 {
+		return nil
+	}
+	err := fmt.Errorf("unexpected status %s", res.Status)
+	if res.StatusCode >= 400 && res.StatusCode < 500 && res.StatusCode != http.StatusRequestTimeout && res.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHttpBackend(t *testing.T) {
+	var uploaded string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			uploaded = string(body)
+		default:
+			w.Write([]byte("master"))
+		}
+	}))
+	defer server.Close()
+
+	ctx := context.Background()
+	dir := t.TempDir()
+	remote := "stage+" + server.URL + "/masters/source.mp4"
+
+	dst := filepath.Join(dir, "source.mp4")
+	if err := Download(ctx, remote, dst, nil); err != nil {
+		t.Fatalf("Download() failed: %v", err)
+	}
+	if b, _ := os.ReadFile(dst); string(b) != "master" {
+		t.Errorf("Download() wrote %q", b)
+	}
+
+	if err := Upload(ctx, dst, remote, nil); err != nil || uploaded != "master" {
+		t.Errorf("Upload() failed: %v (uploaded: %q)", err, uploaded)
+	}
+
+	if _, _, err := backend(strings.TrimPrefix(remote, "stage+")); err == nil {
+		t.Error("Expected plain http urls not to be staged")
+	}
+}
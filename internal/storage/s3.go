@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/config"
+)
+
+const (
+	emptyPayloadHash    = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	unsignedPayloadHash = "UNSIGNED-PAYLOAD"
+)
+
+// PartSize is the size of a single part of a multipart upload, smaller files are uploaded with a single PUT
+var PartSize int64 = 64 << 20
+
+// s3Backend talks to S3 compatible object storages using signature version 4
+type s3Backend struct{}
+
+type s3Credentials struct {
+	endpoint  string
+	region    string
+	accessKey string
+	secretKey string
+}
+
+func s3Config() s3Credentials {
+	config.Config().Mutex.RLock()
+	defer config.Config().Mutex.RUnlock()
+	c := s3Credentials{
+		endpoint:  config.Config().S3Endpoint,
+		region:    config.Config().S3Region,
+		accessKey: config.Config().S3AccessKey,
+		secretKey: config.Config().S3SecretKey,
+	}
+	if c.region == "" {
+		c.region = "us-east-1"
+	}
+	return c
+}
+
+// objectUrl builds the request url for s3://bucket/key, custom endpoints (e.g. MinIO) use path-style addressing
+func (c s3Credentials) objectUrl(u *url.URL, query url.Values) string {
+	key := strings.TrimPrefix(u.Path, "/")
+	var base string
+	if c.endpoint != "" {
+		base = strings.TrimSuffix(c.endpoint, "/") + "/" + uriEncode(u.Host, false) + "/" + uriEncode(key, false)
+	} else {
+		base = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", u.Host, c.region, uriEncode(key, false))
+	}
+	if len(query) > 0 {
+		base += "?" + query.Encode()
+	}
+	return base
+}
+
+func (b *s3Backend) do(ctx context.Context, method string, target string, body io.Reader, size int64, payloadHash string) (*http.Response, error) {
+	c := s3Config()
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.ContentLength = size
+	}
+	if c.accessKey != "" {
+		signV4(req, payloadHash, c.accessKey, c.secretKey, c.region, time.Now())
+	}
+	return http.DefaultClient.Do(req)
+}
+
+func (b *s3Backend) Download(ctx context.Context, u *url.URL, dst string, progress Progress) error {
+	res, err := b.do(ctx, http.MethodGet, s3Config().objectUrl(u, nil), nil, 0, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if err := statusError(res); err != nil {
+		return err
+	}
+	return copyFile(dst, res.Body, res.ContentLength, progress)
+}
+
+func (b *s3Backend) Exists(ctx context.Context, u *url.URL) (bool, error) {
+	res, err := b.do(ctx, http.MethodHead, s3Config().objectUrl(u, nil), nil, 0, emptyPayloadHash)
+	if err != nil {
+		return false, err
+	}
+	res.Body.Close()
+	if res.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return res.StatusCode < 300, statusError(res)
+}
+
+func (b *s3Backend) Upload(ctx context.Context, src string, u *url.URL, progress Progress) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return &permanentError{err}
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size := info.Size()
+
+	if size <= PartSize {
+		res, err := b.do(ctx, http.MethodPut, s3Config().objectUrl(u, nil), &progressReader{reader: f, total: size, progress: progress}, size, unsignedPayloadHash)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		return statusError(res)
+	}
+	return b.uploadMultipart(ctx, f, size, u, progress)
+}
+
+type s3InitiateMultipartUploadResult struct {
+	UploadId string `xml:"UploadId"`
+}
+
+type s3CompletedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type s3CompleteMultipartUpload struct {
+	XMLName xml.Name          `xml:"CompleteMultipartUpload"`
+	Parts   []s3CompletedPart `xml:"Part"`
+}
+
+func (b *s3Backend) uploadMultipart(ctx context.Context, f *os.File, size int64, u *url.URL, progress Progress) error {
+	c := s3Config()
+	res, err := b.do(ctx, http.MethodPost, c.objectUrl(u, url.Values{"uploads": {""}}), nil, 0, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	body, _ := io.ReadAll(res.Body)
+	res.Body.Close()
+	if err := statusError(res); err != nil {
+		return err
+	}
+	var initiated s3InitiateMultipartUploadResult
+	if err := xml.Unmarshal(body, &initiated); err != nil || initiated.UploadId == "" {
+		return fmt.Errorf("failed to initiate multipart upload: %s", string(body))
+	}
+
+	abort := func() {
+		res, err := b.do(context.Background(), http.MethodDelete, c.objectUrl(u, url.Values{"uploadId": {initiated.UploadId}}), nil, 0, emptyPayloadHash)
+		if err == nil {
+			res.Body.Close()
+		}
+	}
+
+	var parts []s3CompletedPart
+	var done int64
+	for offset, number := int64(0), 1; offset < size; offset, number = offset+PartSize, number+1 {
+		length := min(PartSize, size-offset)
+		base := done
+		reader := &progressReader{reader: io.NewSectionReader(f, offset, length), total: size, progress: func(d int64, total int64) {
+			if progress != nil {
+				progress(base+d, total)
+			}
+		}}
+		query := url.Values{"partNumber": {strconv.Itoa(number)}, "uploadId": {initiated.UploadId}}
+		res, err := b.do(ctx, http.MethodPut, c.objectUrl(u, query), reader, length, unsignedPayloadHash)
+		if err != nil {
+			abort()
+			return err
+		}
+		res.Body.Close()
+		if err := statusError(res); err != nil {
+			abort()
+			return err
+		}
+		parts = append(parts, s3CompletedPart{PartNumber: number, ETag: res.Header.Get("ETag")})
+		done += length
+	}
+
+	payload, err := xml.Marshal(s3CompleteMultipartUpload{Parts: parts})
+	if err != nil {
+		abort()
+		return err
+	}
+	hash := sha256.Sum256(payload)
+	res, err = b.do(ctx, http.MethodPost, c.objectUrl(u, url.Values{"uploadId": {initiated.UploadId}}), bytes.NewReader(payload), int64(len(payload)), hex.EncodeToString(hash[:]))
+	if err != nil {
+		abort()
+		return err
+	}
+	body, _ = io.ReadAll(res.Body)
+	res.Body.Close()
+	if err := statusError(res); err != nil {
+		abort()
+		return err
+	}
+	// S3 may report errors of the completion with status 200
+	if bytes.Contains(body, []byte("<Error>")) {
+		abort()
+		return fmt.Errorf("failed to complete multipart upload: %s", string(body))
+	}
+	return nil
+}
+
+// signV4 adds an AWS signature version 4 authorization header covering the host and all headers of the request
+func signV4(req *http.Request, payloadHash string, accessKey string, secretKey string, region string, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("x-amz-date", amzDate)
+	req.Header.Set("x-amz-content-sha256", payloadHash)
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	query := req.URL.Query()
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var canonicalQuery []string
+	for _, key := range keys {
+		for _, value := range query[key] {
+			canonicalQuery = append(canonicalQuery, uriEncode(key, true)+"="+uriEncode(value, true))
+		}
+	}
+
+	canonicalUri := req.URL.EscapedPath()
+	if canonicalUri == "" {
+		canonicalUri = "/"
+	}
+	canonicalRequest := strings.Join([]string{req.Method, canonicalUri, strings.Join(canonicalQuery, "&"), canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+
+	scope := date + "/" + region + "/s3/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	key := hmacSha256([]byte("AWS4"+secretKey), date)
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, "s3")
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", accessKey, scope, signedHeaders, signature))
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// uriEncode percent-encodes everything but unreserved characters as required by signature version 4
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') || c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
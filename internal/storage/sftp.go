@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"path/filepath"
+
+	"github.com/pkg/sftp"
+	"github.com/welovemedia/ffmate/internal/config"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpBackend authenticates with the password of the url or the configured private key, host keys are checked against known_hosts
+type sftpBackend struct{}
+
+func (b *sftpBackend) connect(ctx context.Context, u *url.URL) (*sftp.Client, func(), error) {
+	config.Config().Mutex.RLock()
+	keyFile := config.Config().SftpKeyFile
+	knownHostsFile := config.Config().SftpKnownHosts
+	config.Config().Mutex.RUnlock()
+
+	home, _ := os.UserHomeDir()
+	if knownHostsFile == "" {
+		knownHostsFile = filepath.Join(home, ".ssh", "known_hosts")
+	}
+	hostKeyCallback, err := knownhosts.New(knownHostsFile)
+	if err != nil {
+		return nil, nil, &permanentError{fmt.Errorf("failed to load known hosts: %v", err)}
+	}
+
+	var auth []ssh.AuthMethod
+	if password, ok := u.User.Password(); ok {
+		auth = append(auth, ssh.Password(password))
+	}
+	if keyFile == "" {
+		for _, name := range []string{"id_ed25519", "id_ecdsa", "id_rsa"} {
+			if _, err := os.Stat(filepath.Join(home, ".ssh", name)); err == nil {
+				keyFile = filepath.Join(home, ".ssh", name)
+				break
+			}
+		}
+	}
+	if keyFile != "" {
+		key, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, nil, &permanentError{fmt.Errorf("failed to read private key: %v", err)}
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, nil, &permanentError{fmt.Errorf("failed to parse private key: %v", err)}
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+
+	username := u.User.Username()
+	if username == "" {
+		if current, err := user.Current(); err == nil {
+			username = current.Username
+		}
+	}
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), "22")
+	}
+
+	dialer := &net.Dialer{}
+	conn, err := dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, nil, err
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, host, &ssh.ClientConfig{
+		User:            username,
+		Auth:            auth,
+		HostKeyCallback: hostKeyCallback,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, nil, err
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, nil, err
+	}
+
+	// abort running transfers when the task gets canceled
+	stop := context.AfterFunc(ctx, func() { sshClient.Close() })
+	return client, func() {
+		stop()
+		client.Close()
+		sshClient.Close()
+	}, nil
+}
+
+func (b *sftpBackend) Download(ctx context.Context, u *url.URL, dst string, progress Progress) error {
+	client, closeFn, err := b.connect(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	f, err := client.Open(u.Path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return &permanentError{err}
+		}
+		return err
+	}
+	defer f.Close()
+	var total int64 = -1
+	if info, err := f.Stat(); err == nil {
+		total = info.Size()
+	}
+	return copyFile(dst, f, total, progress)
+}
+
+func (b *sftpBackend) Upload(ctx context.Context, src string, u *url.URL, progress Progress) error {
+	client, closeFn, err := b.connect(ctx, u)
+	if err != nil {
+		return err
+	}
+	defer closeFn()
+
+	local, err := os.Open(src)
+	if err != nil {
+		return &permanentError{err}
+	}
+	defer local.Close()
+	info, err := local.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := client.MkdirAll(path.Dir(u.Path)); err != nil {
+		return err
+	}
+	f, err := client.Create(u.Path)
+	if err != nil {
+		return err
+	}
+	_, err = f.ReadFrom(&progressReader{reader: local, total: info.Size(), progress: progress})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+func (b *sftpBackend) Exists(ctx context.Context, u *url.URL) (bool, error) {
+	client, closeFn, err := b.connect(ctx, u)
+	if err != nil {
+		return false, err
+	}
+	defer closeFn()
+
+	if _, err := client.Stat(u.Path); err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
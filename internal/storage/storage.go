@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yosev/debugo"
+)
+
+var debug = debugo.New("storage")
+
+// Progress is called with the number of transferred bytes and the total size (-1 if unknown)
+type Progress func(done int64, total int64)
+
+// Backend transfers files between a remote location and the local filesystem
+type Backend interface {
+	Download(ctx context.Context, u *url.URL, dst string, progress Progress) error
+	Upload(ctx context.Context, src string, u *url.URL, progress Progress) error
+	Exists(ctx context.Context, u *url.URL) (bool, error)
+}
+
+// plain http(s) urls are handed to ffmpeg as they are (e.g. HLS or DASH streams), staging them is opt-in with the stage+ prefix
+var backends = map[string]Backend{
+	"s3":          &s3Backend{},
+	"sftp":        &sftpBackend{},
+	"stage+http":  &httpBackend{},
+	"stage+https": &httpBackend{},
+}
+
+// Attempts is the number of times a failed transfer is tried
+var Attempts = 3
+
+// permanentError marks errors that will not go away by retrying (e.g. missing objects or denied access)
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e *permanentError) Unwrap() error {
+	return e.err
+}
+
+// IsRemote reports whether the path is an url handled by one of the storage backends
+func IsRemote(p string) bool {
+	_, _, err := backend(p)
+	return err == nil
+}
+
+func backend(p string) (Backend, *url.URL, error) {
+	if !strings.Contains(p, "://") {
+		return nil, nil, fmt.Errorf("'%s' is not a remote url", p)
+	}
+	u, err := url.Parse(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, ok := backends[strings.ToLower(u.Scheme)]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported storage scheme '%s'", u.Scheme)
+	}
+	return b, u, nil
+}
+
+// LocalName returns the file name a remote file is staged as
+func LocalName(remote string) string {
+	if u, err := url.Parse(remote); err == nil {
+		if name := path.Base(u.Path); name != "/" && name != "." {
+			return name
+		}
+	}
+	return "file"
+}
+
+// Download fetches the remote file to dst, failed transfers are retried
+func Download(ctx context.Context, remote string, dst string, progress Progress) error {
+	b, u, err := backend(remote)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return retry(ctx, "download "+redact(u), func() error {
+		return b.Download(ctx, u, dst, progress)
+	})
+}
+
+// Upload stores src at the remote location, failed transfers are retried
+func Upload(ctx context.Context, src string, remote string, progress Progress) error {
+	b, u, err := backend(remote)
+	if err != nil {
+		return err
+	}
+	return retry(ctx, "upload "+redact(u), func() error {
+		return b.Upload(ctx, src, u, progress)
+	})
+}
+
+// Exists checks whether the remote file exists
+func Exists(ctx context.Context, remote string) (bool, error) {
+	b, u, err := backend(remote)
+	if err != nil {
+		return false, err
+	}
+	return b.Exists(ctx, u)
+}
+
+func retry(ctx context.Context, name string, fn func() error) error {
+	var err error
+	for attempt := 1; attempt <= Attempts; attempt++ {
+		if err = fn(); err == nil {
+			return nil
+		}
+		var permanent *permanentError
+		if errors.As(err, &permanent) || ctx.Err() != nil || attempt == Attempts {
+			break
+		}
+		debug.Debugf("%s failed (attempt %d/%d): %v", name, attempt, Attempts, err)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Duration(attempt) * 2 * time.Second):
+		}
+	}
+	return fmt.Errorf("failed to %s: %v", name, err)
+}
+
+// redact removes credentials from an url before it is logged
+func redact(u *url.URL) string {
+	r := *u
+	r.User = nil
+	return r.String()
+}
+
+// copyFile streams src into a newly created dst and reports the progress
+func copyFile(dst string, src io.Reader, total int64, progress Progress) error {
+	f, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, &progressReader{reader: src, total: total, progress: progress})
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+type progressReader struct {
+	reader   io.Reader
+	done     int64
+	total    int64
+	progress Progress
+}
+
+func (r *progressReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.done += int64(n)
+	if r.progress != nil && n > 0 {
+		r.progress(r.done, r.total)
+	}
+	return n, err
+}
@@ -0,0 +1,18 @@
+//go:build windows
+
+package utils
+
+import "golang.org/x/sys/windows"
+
+// FreeDiskSpace returns the number of bytes available to the current user on the volume of the path
+func FreeDiskSpace(path string) (uint64, error) {
+	p, err := windows.UTF16PtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var free uint64
+	if err := windows.GetDiskFreeSpaceEx(p, &free, nil, nil); err != nil {
+		return 0, err
+	}
+	return free, nil
+}
@@ -0,0 +1,98 @@
+package wildcards
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type function struct {
+	minArgs int
+	maxArgs int
+	fn      func(value string, args []string) (string, error)
+}
+
+// functions can be applied to a wildcard with ${NAME | function:arg}
+var functions = map[string]function{
+	"lower": {0, 0, func(value string, _ []string) (string, error) { return strings.ToLower(value), nil }},
+	"upper": {0, 0, func(value string, _ []string) (string, error) { return strings.ToUpper(value), nil }},
+	"trim":  {0, 0, func(value string, _ []string) (string, error) { return strings.TrimSpace(value), nil }},
+	// replace:old:new replaces all occurrences of old
+	"replace": {2, 2, func(value string, args []string) (string, error) {
+		return strings.ReplaceAll(value, args[0], args[1]), nil
+	}},
+	// pad:width[:char] left pads the value, with zeros by default
+	"pad": {1, 2, func(value string, args []string) (string, error) {
+		width, err := strconv.Atoi(args[0])
+		if err != nil {
+			return "", fmt.Errorf("invalid pad width '%s'", args[0])
+		}
+		char := "0"
+		if len(args) == 2 && args[1] != "" {
+			char = args[1]
+		}
+		for len([]rune(value)) < width {
+			value = char + value
+		}
+		return value, nil
+	}},
+	// sanitize replaces everything but letters, digits, dots, dashes and underscores so the value is safe to use in file names
+	"sanitize": {0, 0, func(value string, _ []string) (string, error) {
+		return unsafeChars.ReplaceAllString(value, "_"), nil
+	}},
+	// date:layout formats a unix timestamp (seconds or milliseconds) or RFC 3339 date, an empty value is the current time.
+	// The layout is either a Go layout (2006-01-02) or uses strftime directives (%Y-%m-%d)
+	"date": {1, 1, func(value string, args []string) (string, error) {
+		t, err := parseTime(value)
+		if err != nil {
+			return "", err
+		}
+		return t.Format(goLayout(args[0])), nil
+	}},
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func parseTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Now(), nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		if len(value) >= 13 {
+			return time.UnixMilli(n), nil
+		}
+		return time.Unix(n, 0), nil
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateTime, time.DateOnly} {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid date '%s'", value)
+}
+
+var strftime = map[byte]string{
+	'Y': "2006", 'y': "06", 'm': "01", 'd': "02", 'H': "15", 'I': "03", 'M': "04", 'S': "05", 'p': "PM",
+	'j': "002", 'b': "Jan", 'B': "January", 'a': "Mon", 'A': "Monday", 'z': "-0700", 'Z': "MST", '%': "%",
+}
+
+// goLayout converts strftime directives to a Go layout, layouts without % are returned as they are
+func goLayout(layout string) string {
+	if !strings.Contains(layout, "%") {
+		return layout
+	}
+	var b strings.Builder
+	for i := 0; i < len(layout); i++ {
+		if layout[i] == '%' && i+1 < len(layout) {
+			if l, ok := strftime[layout[i+1]]; ok {
+				b.WriteString(l)
+				i++
+				continue
+			}
+		}
+		b.WriteByte(layout[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,61 @@
+package wildcards
+
+import (
+	"sort"
+	"strings"
+)
+
+// SecretMask replaces the values of secrets wherever they would be stored or logged
+const SecretMask = "********"
+
+// Secrets maps secret names to their values, a secret is referenced as ${SECRET_<name>}
+type Secrets map[string]string
+
+// Only returns the secrets with the given names
+func (s Secrets) Only(names []string) Secrets {
+	secrets := Secrets{}
+	for _, name := range names {
+		if value, ok := s[name]; ok {
+			secrets[name] = value
+		}
+	}
+	return secrets
+}
+
+// Variables returns the wildcards resolving to the values of the secrets
+func (s Secrets) Variables() Variables {
+	vars := Variables{}
+	for name, value := range s {
+		vars["SECRET_"+name] = value
+	}
+	return vars
+}
+
+// Masked returns the wildcards of the secrets resolving to the mask instead of their values
+func (s Secrets) Masked() Variables {
+	vars := Variables{}
+	for name := range s {
+		vars["SECRET_"+name] = SecretMask
+	}
+	return vars
+}
+
+// Mask replaces all secret values in the input
+func (s Secrets) Mask(input string) string {
+	values := make([]string, 0, len(s))
+	for _, value := range s {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	if len(values) == 0 {
+		return input
+	}
+	// longer values first so a secret containing another one is masked as a whole
+	sort.Slice(values, func(i, j int) bool { return len(values[i]) > len(values[j]) })
+	pairs := make([]string, 0, len(values)*2)
+	for _, value := range values {
+		pairs = append(pairs, value, SecretMask)
+	}
+	return strings.NewReplacer(pairs...).Replace(input)
+}
@@ -0,0 +1,279 @@
+package wildcards
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// node is a parsed part of a template, either text, a variable or a conditional
+type node interface{}
+
+type text string
+
+// variable is a ${NAME:-default | function:arg} expression
+type variable struct {
+	raw        string // the wildcard as written, unknown variables are kept as they are
+	name       string
+	def        []node
+	hasDefault bool
+	calls      []call
+}
+
+type call struct {
+	name string
+	args []string
+}
+
+// conditional is a ${if NAME}...${else}...${end} block, the condition may be negated with ! or compare with == and !=
+type conditional struct {
+	name    string
+	negate  bool
+	op      string
+	operand string
+	then    []node
+	els     []node
+}
+
+type parser struct {
+	input string
+	pos   int
+}
+
+// parse tokenizes the template, $${ is a literal ${ and unterminated wildcards are kept as text
+func parse(input string) ([]node, error) {
+	p := &parser{input: input}
+	nodes, marker, err := p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if marker != "" {
+		return nil, fmt.Errorf("unexpected ${%s} without ${if}", marker)
+	}
+	return nodes, nil
+}
+
+// parseNodes parses until the end of the input or an ${else} or ${end} marker, which is returned
+func (p *parser) parseNodes() ([]node, string, error) {
+	var nodes []node
+	var buf strings.Builder
+	flush := func() {
+		if buf.Len() > 0 {
+			nodes = append(nodes, text(buf.String()))
+			buf.Reset()
+		}
+	}
+
+	for p.pos < len(p.input) {
+		rest := p.input[p.pos:]
+		if strings.HasPrefix(rest, "$${") {
+			buf.WriteString("${")
+			p.pos += 3
+			continue
+		}
+		if !strings.HasPrefix(rest, "${") {
+			buf.WriteByte(rest[0])
+			p.pos++
+			continue
+		}
+
+		end := closing(p.input, p.pos+2)
+		if end < 0 {
+			buf.WriteString(rest)
+			break
+		}
+		raw := p.input[p.pos : end+1]
+		inner := strings.TrimSpace(p.input[p.pos+2 : end])
+		p.pos = end + 1
+		flush()
+
+		switch {
+		case inner == "else" || inner == "end":
+			return nodes, inner, nil
+		case strings.HasPrefix(inner, "if "):
+			c, err := p.parseConditional(strings.TrimSpace(inner[3:]))
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, c)
+		default:
+			v, err := parseVariable(raw, inner)
+			if err != nil {
+				return nil, "", err
+			}
+			nodes = append(nodes, v)
+		}
+	}
+	p.pos = len(p.input)
+	flush()
+	return nodes, "", nil
+}
+
+func (p *parser) parseConditional(cond string) (*conditional, error) {
+	c := &conditional{}
+	if i := indexTop(cond, "=="); i >= 0 {
+		c.name, c.op, c.operand = strings.TrimSpace(cond[:i]), "==", unquote(strings.TrimSpace(cond[i+2:]))
+	} else if i := indexTop(cond, "!="); i >= 0 {
+		c.name, c.op, c.operand = strings.TrimSpace(cond[:i]), "!=", unquote(strings.TrimSpace(cond[i+2:]))
+	} else {
+		c.name = cond
+		if strings.HasPrefix(cond, "!") {
+			c.negate = true
+			c.name = strings.TrimSpace(cond[1:])
+		}
+	}
+	if c.name == "" || strings.ContainsAny(c.name, " \t") {
+		return nil, fmt.Errorf("invalid condition '%s'", cond)
+	}
+
+	var marker string
+	var err error
+	c.then, marker, err = p.parseNodes()
+	if err != nil {
+		return nil, err
+	}
+	if marker == "else" {
+		c.els, marker, err = p.parseNodes()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if marker != "end" {
+		return nil, fmt.Errorf("missing ${end} for ${if %s}", cond)
+	}
+	return c, nil
+}
+
+func parseVariable(raw string, inner string) (*variable, error) {
+	parts := splitTop(inner, '|')
+	v := &variable{raw: raw, name: strings.TrimSpace(parts[0])}
+
+	if i := indexTop(v.name, ":-"); i >= 0 {
+		def := strings.TrimSpace(v.name[i+2:])
+		v.name = strings.TrimSpace(v.name[:i])
+		v.hasDefault = true
+		if isQuoted(def) {
+			v.def = []node{text(unquote(def))}
+		} else {
+			nodes, err := parse(def)
+			if err != nil {
+				return nil, err
+			}
+			v.def = nodes
+		}
+	}
+	if v.name == "" || strings.ContainsAny(v.name, " \t") {
+		return nil, fmt.Errorf("invalid wildcard %s", raw)
+	}
+
+	for _, part := range parts[1:] {
+		fields := splitTop(part, ':')
+		c := call{name: strings.TrimSpace(fields[0])}
+		// gjson pipes and modifiers like ${METADATA_list|@reverse} are part of the metadata path
+		if _, ok := functions[c.name]; !ok && len(v.calls) == 0 && !v.hasDefault && strings.HasPrefix(v.name, "METADATA_") {
+			v.name += "|" + strings.TrimSpace(part)
+			continue
+		}
+		for _, arg := range fields[1:] {
+			c.args = append(c.args, unquote(strings.TrimSpace(arg)))
+		}
+		fn, ok := functions[c.name]
+		if !ok {
+			return nil, fmt.Errorf("unknown function '%s' in %s", c.name, raw)
+		}
+		if len(c.args) < fn.minArgs || len(c.args) > fn.maxArgs {
+			return nil, fmt.Errorf("function '%s' takes %d to %d arguments, got %d in %s", c.name, fn.minArgs, fn.maxArgs, len(c.args), raw)
+		}
+		v.calls = append(v.calls, c)
+	}
+	return v, nil
+}
+
+// closing returns the index of the } closing the wildcard starting at i, nested wildcards and quoted strings are skipped
+func closing(s string, i int) int {
+	depth := 1
+	for j := i; j < len(s); j++ {
+		switch {
+		case s[j] == '"':
+			j = closingQuote(s, j)
+			if j < 0 {
+				return -1
+			}
+		case s[j] == '$' && j+1 < len(s) && s[j+1] == '{':
+			depth++
+			j++
+		case s[j] == '}':
+			depth--
+			if depth == 0 {
+				return j
+			}
+		}
+	}
+	return -1
+}
+
+// closingQuote returns the index of the quote closing the string starting at i
+func closingQuote(s string, i int) int {
+	for j := i + 1; j < len(s); j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '"':
+			return j
+		}
+	}
+	return -1
+}
+
+// indexTop returns the index of sep outside of nested wildcards and quoted strings
+func indexTop(s string, sep string) int {
+	depth := 0
+	for j := 0; j < len(s); j++ {
+		switch {
+		case s[j] == '"':
+			if k := closingQuote(s, j); k >= 0 {
+				j = k
+				continue
+			}
+		case s[j] == '$' && j+1 < len(s) && s[j+1] == '{':
+			depth++
+			j++
+			continue
+		case s[j] == '}' && depth > 0:
+			depth--
+			continue
+		}
+		if depth == 0 && strings.HasPrefix(s[j:], sep) {
+			return j
+		}
+	}
+	return -1
+}
+
+// splitTop splits s at sep outside of nested wildcards and quoted strings
+func splitTop(s string, sep byte) []string {
+	var parts []string
+	for {
+		i := indexTop(s, string(sep))
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s = s[i+1:]
+	}
+}
+
+func isQuoted(s string) bool {
+	return len(s) >= 2 && s[0] == '"' && closingQuote(s, 0) == len(s)-1
+}
+
+// unquote removes the quotes of a quoted argument, unquoted arguments are returned as they are
+func unquote(s string) string {
+	if !isQuoted(s) {
+		return s
+	}
+	if u, err := strconv.Unquote(s); err == nil {
+		return u
+	}
+	return s[1 : len(s)-1]
+}
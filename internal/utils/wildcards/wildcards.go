@@ -6,6 +6,7 @@ import (
 	"path/filepath"
 	"regexp"
 	"runtime"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
@@ -16,64 +17,271 @@ import (
 	"github.com/welovemedia/ffmate/internal/dto"
 )
 
-func Replace(input string, inputFile string, outputFile string, source string, metadata *dto.InterfaceMap) string {
-	input = strings.ReplaceAll(input, "${INPUT_FILE}", fmt.Sprintf("\"%s\"", inputFile))
-	input = strings.ReplaceAll(input, "${OUTPUT_FILE}", fmt.Sprintf("\"%s\"", outputFile))
+// Variables holds task specific wildcards resolved in addition to the built-in ones, keyed by their name without ${}
+type Variables map[string]string
 
-	input = strings.ReplaceAll(input, "${INPUT_FILE_BASE}", filepath.Base(inputFile))
-	input = strings.ReplaceAll(input, "${OUTPUT_FILE_BASE}", filepath.Base(inputFile))
-	input = strings.ReplaceAll(input, "${INPUT_FILE_EXTENSION}", filepath.Ext(filepath.Base(inputFile)))
-	input = strings.ReplaceAll(input, "${OUTPUT_FILE_EXTENSION", filepath.Ext(filepath.Base(outputFile)))
-	input = strings.ReplaceAll(input, "${INPUT_FILE_BASENAME}", strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(filepath.Base(inputFile))))
-	input = strings.ReplaceAll(input, "${OUTPUT_FILE_BASENAME}", strings.TrimSuffix(filepath.Base(outputFile), filepath.Ext(filepath.Base(outputFile))))
-	input = strings.ReplaceAll(input, "${INPUT_FILE_DIR}", filepath.Dir(inputFile))
-	input = strings.ReplaceAll(input, "${OUTPUT_FILE_DIR}", filepath.Dir(inputFile))
+// Replace resolves the wildcards of the input, unknown wildcards are kept as they are.
+// Inputs that are no valid template fall back to resolving every plain ${NAME} wildcard on its own
+func Replace(input string, inputFile string, outputFile string, source string, metadata *dto.InterfaceMap, variables ...Variables) string {
+	out, err := render(input, false, inputFile, outputFile, source, metadata, variables...)
+	if err != nil {
+		return replaceEach(input, inputFile, outputFile, source, metadata, variables...)
+	}
+	return out
+}
 
-	input = strings.ReplaceAll(input, "${DATE_YEAR}", time.Now().Format("2006"))
-	input = strings.ReplaceAll(input, "${DATE_SHORTYEAR}", time.Now().Format("06"))
-	input = strings.ReplaceAll(input, "${DATE_MONTH}", time.Now().Format("01"))
-	input = strings.ReplaceAll(input, "${DATE_DAY}", time.Now().Format("02"))
+var reWildcard = regexp.MustCompile(`\$\{([^{}]+)\}`)
+
+// replaceEach resolves the wildcards without template syntax like ffmate did before templates were introduced
+func replaceEach(input string, inputFile string, outputFile string, source string, metadata *dto.InterfaceMap, variables ...Variables) string {
+	r := newRenderer(false, inputFile, outputFile, source, metadata, variables)
+	return reWildcard.ReplaceAllStringFunc(input, func(match string) string {
+		name := match[2 : len(match)-1]
+		if value, ok := r.lookup(name); ok {
+			return value
+		}
+		if r.hasMetadata && strings.HasPrefix(name, "METADATA_") {
+			return ""
+		}
+		return match
+	})
+}
 
-	_, week := time.Now().ISOWeek()
-	input = strings.ReplaceAll(input, "${DATE_WEEK}", strconv.Itoa(week))
+// Render resolves the wildcards of the input like Replace but returns an error for invalid templates and,
+// if strict wildcards are enabled, for unknown wildcards
+func Render(input string, inputFile string, outputFile string, source string, metadata *dto.InterfaceMap, variables ...Variables) (string, error) {
+	config.Config().Mutex.RLock()
+	strict := config.Config().StrictWildcards
+	config.Config().Mutex.RUnlock()
 
-	input = strings.ReplaceAll(input, "${TIME_HOUR}", time.Now().Format("15"))
-	input = strings.ReplaceAll(input, "${TIME_MINUTE}", time.Now().Format("04"))
-	input = strings.ReplaceAll(input, "${TIME_SECOND}", time.Now().Format("05"))
+	return render(input, strict, inputFile, outputFile, source, metadata, variables...)
+}
 
-	input = strings.ReplaceAll(input, "${TIMESTAMP_SECONDS}", strconv.FormatInt(time.Now().Unix(), 10))
-	input = strings.ReplaceAll(input, "${TIMESTAMP_MILLISECONDS}", strconv.FormatInt(time.Now().UnixMilli(), 10))
-	input = strings.ReplaceAll(input, "${TIMESTAMP_MICROSECONDS}", strconv.FormatInt(time.Now().UnixMicro(), 10))
-	input = strings.ReplaceAll(input, "${TIMESTAMP_NANOSECONDS}", strconv.FormatInt(time.Now().UnixNano(), 10))
+// Report lists the wildcards of a template that did not resolve to a value
+type Report struct {
+	Unknown []string `json:"unknown"`
+	Empty   []string `json:"empty"`
+}
+
+// Preview resolves the wildcards exactly like Render and additionally reports unknown and empty wildcards
+func Preview(input string, inputFile string, outputFile string, source string, metadata *dto.InterfaceMap, variables ...Variables) (string, *Report, error) {
+	config.Config().Mutex.RLock()
+	strict := config.Config().StrictWildcards
+	config.Config().Mutex.RUnlock()
+
+	return execute(input, strict, inputFile, outputFile, source, metadata, variables...)
+}
+
+func render(input string, strict bool, inputFile string, outputFile string, source string, metadata *dto.InterfaceMap, variables ...Variables) (string, error) {
+	out, _, err := execute(input, strict, inputFile, outputFile, source, metadata, variables...)
+	return out, err
+}
 
-	input = strings.ReplaceAll(input, "${OS_NAME}", runtime.GOOS)
-	input = strings.ReplaceAll(input, "${OS_ARCH}", runtime.GOARCH)
+func execute(input string, strict bool, inputFile string, outputFile string, source string, metadata *dto.InterfaceMap, variables ...Variables) (string, *Report, error) {
+	report := &Report{Unknown: []string{}, Empty: []string{}}
+	if !strings.Contains(input, "${") {
+		return input, report, nil
+	}
+	nodes, err := parse(input)
+	if err != nil {
+		return "", report, err
+	}
 
-	input = strings.ReplaceAll(input, "${SOURCE}", source)
+	r := newRenderer(strict, inputFile, outputFile, source, metadata, variables)
+	r.report = report
+	var b strings.Builder
+	if err := r.render(nodes, &b); err != nil {
+		return "", report, err
+	}
+	return b.String(), report, nil
+}
 
-	input = strings.ReplaceAll(input, "${UUID}", uuid.NewString())
+// builtins returns the wildcards available to every template
+func builtins(inputFile string, outputFile string, source string) Variables {
+	now := time.Now()
+	_, week := now.ISOWeek()
 
 	config.Config().Mutex.RLock()
-	input = strings.ReplaceAll(input, "${FFMPEG}", config.Config().FFMpeg)
-	defer config.Config().Mutex.RUnlock()
+	ffmpeg := config.Config().FFMpeg
+	config.Config().Mutex.RUnlock()
+
+	return Variables{
+		"INPUT_FILE":  fmt.Sprintf("\"%s\"", inputFile),
+		"OUTPUT_FILE": fmt.Sprintf("\"%s\"", outputFile),
+
+		"INPUT_FILE_BASE":       filepath.Base(inputFile),
+		"OUTPUT_FILE_BASE":      filepath.Base(outputFile),
+		"INPUT_FILE_EXTENSION":  filepath.Ext(filepath.Base(inputFile)),
+		"OUTPUT_FILE_EXTENSION": filepath.Ext(filepath.Base(outputFile)),
+		"INPUT_FILE_BASENAME":   strings.TrimSuffix(filepath.Base(inputFile), filepath.Ext(filepath.Base(inputFile))),
+		"OUTPUT_FILE_BASENAME":  strings.TrimSuffix(filepath.Base(outputFile), filepath.Ext(filepath.Base(outputFile))),
+		"INPUT_FILE_DIR":        filepath.Dir(inputFile),
+		"OUTPUT_FILE_DIR":       filepath.Dir(outputFile),
+
+		"DATE_YEAR":      now.Format("2006"),
+		"DATE_SHORTYEAR": now.Format("06"),
+		"DATE_MONTH":     now.Format("01"),
+		"DATE_DAY":       now.Format("02"),
+		"DATE_WEEK":      strconv.Itoa(week),
+
+		"TIME_HOUR":   now.Format("15"),
+		"TIME_MINUTE": now.Format("04"),
+		"TIME_SECOND": now.Format("05"),
+
+		"NOW": now.Format(time.RFC3339),
+
+		"TIMESTAMP_SECONDS":      strconv.FormatInt(now.Unix(), 10),
+		"TIMESTAMP_MILLISECONDS": strconv.FormatInt(now.UnixMilli(), 10),
+		"TIMESTAMP_MICROSECONDS": strconv.FormatInt(now.UnixMicro(), 10),
+		"TIMESTAMP_NANOSECONDS":  strconv.FormatInt(now.UnixNano(), 10),
+
+		"OS_NAME": runtime.GOOS,
+		"OS_ARCH": runtime.GOARCH,
+
+		"SOURCE": source,
+
+		"UUID": uuid.NewString(),
+
+		"FFMPEG": ffmpeg,
+	}
+}
+
+// addSequence adds the wildcards of image sequences found by directory watchfolders
+func addSequence(vars Variables, metadata string) {
+	sequence := gjson.Get(metadata, "ffmate.sequence")
+	if !sequence.Exists() {
+		return
+	}
+	vars["SEQUENCE_PATTERN"] = fmt.Sprintf("\"%s\"", sequence.Get("pattern").String())
+	vars["SEQUENCE_START"] = sequence.Get("start").String()
+	vars["SEQUENCE_FRAMES"] = sequence.Get("frames").String()
+}
 
-	// handle metadata wildcard
+func newRenderer(strict bool, inputFile string, outputFile string, source string, metadata *dto.InterfaceMap, variables []Variables) *renderer {
+	r := &renderer{strict: strict, variables: variables, builtins: builtins(inputFile, outputFile, source), report: &Report{}}
 	if metadata != nil {
-		metadataJSON, err := json.Marshal(metadata)
-
-		if err == nil {
-			jsonStr := string(metadataJSON)
-			re := regexp.MustCompile(`\$\{METADATA_([^}]+)\}`)
-			input = re.ReplaceAllStringFunc(input, func(match string) string {
-				path := re.FindStringSubmatch(match)[1]
-				val := gjson.Get(jsonStr, path)
-				if val.Exists() {
-					return val.String()
+		if metadataJSON, err := json.Marshal(metadata); err == nil {
+			r.metadata = string(metadataJSON)
+			r.hasMetadata = true
+			addSequence(r.builtins, r.metadata)
+		}
+	}
+	return r
+}
+
+// reserved lists the wildcards provided by ffmate only in some contexts, all other unknown names belong to the shell or executor
+var reserved = []string{"CRF", "ENCODER_ARGS", "OUTPUT_FILE_TEMP", "TASK_WORKDIR", "BATCH_UUID", "METADATA_", "SECRET_", "SEQUENCE_", "CHUNK_"}
+
+// owns reports whether the name is a wildcard of ffmate
+func (r *renderer) owns(name string) bool {
+	if _, ok := r.lookup(name); ok {
+		return true
+	}
+	for _, prefix := range reserved {
+		if name == prefix || (strings.HasSuffix(prefix, "_") && strings.HasPrefix(name, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+type renderer struct {
+	strict      bool
+	variables   []Variables
+	builtins    Variables
+	metadata    string
+	hasMetadata bool
+	report      *Report
+}
+
+// lookup resolves a wildcard, task variables take precedence over the built-in ones
+func (r *renderer) lookup(name string) (string, bool) {
+	for _, vars := range r.variables {
+		if value, ok := vars[name]; ok {
+			return value, true
+		}
+	}
+	if value, ok := r.builtins[name]; ok {
+		return value, true
+	}
+	if path, ok := strings.CutPrefix(name, "METADATA_"); ok && r.hasMetadata {
+		if val := gjson.Get(r.metadata, path); val.Exists() {
+			return val.String(), true
+		}
+	}
+	return "", false
+}
+
+func (r *renderer) render(nodes []node, b *strings.Builder) error {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case text:
+			b.WriteString(string(n))
+		case *variable:
+			if err := r.renderVariable(n, b); err != nil {
+				return err
+			}
+		case *conditional:
+			value, _ := r.lookup(n.name)
+			var ok bool
+			switch n.op {
+			case "==":
+				ok = value == n.operand
+			case "!=":
+				ok = value != n.operand
+			default:
+				ok = value != "" && value != "false" && value != "0"
+				if n.negate {
+					ok = !ok
 				}
-				return ""
-			})
+			}
+			branch := n.els
+			if ok {
+				branch = n.then
+			}
+			if err := r.render(branch, b); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
+}
 
-	return input
+func (r *renderer) renderVariable(v *variable, b *strings.Builder) error {
+	value, ok := r.lookup(v.name)
+	// wildcards with a default always resolve to a value
+	if !ok && !v.hasDefault && !slices.Contains(r.report.Unknown, v.name) {
+		r.report.Unknown = append(r.report.Unknown, v.name)
+	} else if ok && value == "" && !v.hasDefault && !slices.Contains(r.report.Empty, v.name) {
+		r.report.Empty = append(r.report.Empty, v.name)
+	}
+	switch {
+	case !ok && v.hasDefault && !r.owns(v.name):
+		// defaults of foreign names like ${HOME:-/tmp} are left to the shell
+		b.WriteString(v.raw)
+		return nil
+	case (!ok || value == "") && v.hasDefault:
+		var def strings.Builder
+		if err := r.render(v.def, &def); err != nil {
+			return err
+		}
+		value = def.String()
+	case !ok && r.strict:
+		return fmt.Errorf("unknown wildcard %s", v.raw)
+	case !ok && r.hasMetadata && strings.HasPrefix(v.name, "METADATA_"):
+		// missing metadata resolves to an empty string
+	case !ok:
+		b.WriteString(v.raw)
+		return nil
+	}
+
+	for _, c := range v.calls {
+		var err error
+		value, err = functions[c.name].fn(value, c.args)
+		if err != nil {
+			return fmt.Errorf("%s: %v", v.raw, err)
+		}
+	}
+	b.WriteString(value)
+	return nil
 }
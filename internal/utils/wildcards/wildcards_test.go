@@ -1,8 +1,11 @@
 package wildcards
 
 import (
+	"path/filepath"
 	"runtime"
+	"slices"
 	"testing"
+	"time"
 
 	"github.com/welovemedia/ffmate/internal/dto"
 )
@@ -70,6 +73,14 @@ func TestReplace(t *testing.T) {
 		},
 	}
 
+	t.Run("Task variables", func(t *testing.T) {
+		got := Replace("-i ${INPUT_FILE} ${ENCODER_ARGS} ${OUTPUT_FILE}", "in.mp4", "out.mkv", "test", nil, Variables{"ENCODER_ARGS": "-c:v libsvtav1 -crf 30"})
+		want := "-i \"in.mp4\" -c:v libsvtav1 -crf 30 \"out.mkv\""
+		if got != want {
+			t.Errorf("Replace() = %v, want %v", got, want)
+		}
+	})
+
 	for index, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got := Replace(tt.input, tt.inputFile, tt.outputFile, tt.source, metadata)
@@ -85,3 +96,138 @@ func TestReplace(t *testing.T) {
 		})
 	}
 }
+
+func TestReplaceSequence(t *testing.T) {
+	metadata := &dto.InterfaceMap{"ffmate": map[string]any{"sequence": map[string]any{"pattern": "/in/shot/frame_%06d.dpx", "start": "86400", "frames": "240"}}}
+	got := Replace("-start_number ${SEQUENCE_START} -i ${SEQUENCE_PATTERN} -frames:v ${SEQUENCE_FRAMES}", "/in/shot", "/out/shot.mov", "watchfolder", metadata)
+	want := "-start_number 86400 -i \"/in/shot/frame_%06d.dpx\" -frames:v 240"
+	if got != want {
+		t.Errorf("Replace() = %q, want %q", got, want)
+	}
+}
+
+func TestReplaceOutputFile(t *testing.T) {
+	got := Replace("${OUTPUT_FILE_BASE} ${OUTPUT_FILE_EXTENSION} ${OUTPUT_FILE_DIR}", "/in/input.mov", "/out/output.mp4", "test", nil)
+	want := "output.mp4 .mp4 " + filepath.Dir("/out/output.mp4")
+	if got != want {
+		t.Errorf("Replace() = %q, want %q", got, want)
+	}
+}
+
+func TestTemplate(t *testing.T) {
+	metadata := &dto.InterfaceMap{"show": "My Show: Pilot", "episode": "7", "empty": "", "draft": true, "created": "1700000000", "list": []string{"a", "b", "c"}}
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"default for missing metadata", "${METADATA_missing:-fallback}", "fallback"},
+		{"default for empty metadata", "${METADATA_empty:-fallback}", "fallback"},
+		{"default not used", "${METADATA_episode:-1}", "7"},
+		{"quoted default", `${METADATA_missing:-"a | b"}`, "a | b"},
+		{"nested default", "${METADATA_missing:-${INPUT_FILE_BASENAME}}", "input"},
+		{"unknown kept", "echo ${HOME} ${HOME | lower}", "echo ${HOME} ${HOME | lower}"},
+		{"shell default kept", "${HOME:-/tmp}/script.sh", "${HOME:-/tmp}/script.sh"},
+		{"reserved default", "${SECRET_token:-none} ${CRF:-30}", "none 30"},
+		{"gjson modifier", "${METADATA_list|@reverse|0}", "c"},
+		{"gjson modifier with function", "${METADATA_list|@reverse|0 | upper}", "C"},
+		{"lower upper", "${METADATA_show | lower} ${SOURCE | upper}", "my show: pilot TEST"},
+		{"replace", `${METADATA_show | replace:" ":"_"}`, "My_Show:_Pilot"},
+		{"pad", "${METADATA_episode | pad:3}", "007"},
+		{"pad char", "${METADATA_episode | pad:3:x}", "xx7"},
+		{"sanitize", "${METADATA_show | sanitize}", "My_Show__Pilot"},
+		{"chained", "${METADATA_show | sanitize | lower}", "my_show__pilot"},
+		{"date strftime", "${METADATA_created | date:%Y-%m-%d}", time.Unix(1700000000, 0).Format("2006-01-02")},
+		{"date go layout", `${METADATA_created | date:"2006/01"}`, time.Unix(1700000000, 0).Format("2006/01")},
+		{"if", "${if METADATA_draft}draft${end}", "draft"},
+		{"if else", "${if METADATA_missing}yes${else}no${end}", "no"},
+		{"if negated", "${if !METADATA_empty}empty${end}", "empty"},
+		{"if equals", `${if METADATA_episode == "7"}seven${else}other${end}`, "seven"},
+		{"if not equals", "${if SOURCE != test}other${else}test${end}", "test"},
+		{"nested if", "${if METADATA_draft}${if METADATA_episode == 7}a${else}b${end}${end}", "a"},
+		{"escaped", "$${INPUT_FILE} ${INPUT_FILE_BASE}", "${INPUT_FILE} input.mp4"},
+		{"unterminated", "${INPUT_FILE_BASE} ${", "input.mp4 ${"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := render(tt.input, false, "/in/input.mp4", "/out/output.mp4", "test", metadata)
+			if err != nil {
+				t.Fatalf("render() failed: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	for _, input := range []string{
+		"${INPUT_FILE | nope}",
+		"${INPUT_FILE | pad}",
+		"${if SOURCE}open",
+		"${else}",
+		"${end}",
+		"${METADATA_show | date:%Y}",
+	} {
+		if _, err := render(input, false, "in.mp4", "out.mp4", "test", &dto.InterfaceMap{"show": "x"}); err == nil {
+			t.Errorf("Expected %q to fail", input)
+		}
+	}
+
+	// invalid templates fall back to resolving every plain wildcard on its own
+	for input, want := range map[string]string{
+		"${if SOURCE}open ${INPUT_FILE_BASE} ${METADATA_show}": "${if SOURCE}open in.mp4 x",
+		"${INPUT_FILE | nope} ${OUTPUT_FILE}":                  `${INPUT_FILE | nope} "out.mp4"`,
+		"${end} ${CHUNK_LIST} ${METADATA_missing}":             `${end} "list.txt" `,
+	} {
+		if got := Replace(input, "in.mp4", "out.mp4", "test", &dto.InterfaceMap{"show": "x"}, Variables{"CHUNK_LIST": `"list.txt"`}); got != want {
+			t.Errorf("Replace(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	// strict mode fails on unknown wildcards but still honors defaults
+	if _, err := render("${HOME}", true, "in.mp4", "out.mp4", "test", nil); err == nil {
+		t.Error("Expected unknown wildcard to fail in strict mode")
+	}
+	if _, err := render("${METADATA_missing}", true, "in.mp4", "out.mp4", "test", &dto.InterfaceMap{}); err == nil {
+		t.Error("Expected missing metadata to fail in strict mode")
+	}
+	if got, err := render("${METADATA_missing:-/root}", true, "in.mp4", "out.mp4", "test", &dto.InterfaceMap{}); err != nil || got != "/root" {
+		t.Errorf("Expected default in strict mode, got %q (err: %v)", got, err)
+	}
+	// defaults of names ffmate does not know are left to the shell
+	if got, err := render("${HOME:-/root}", true, "in.mp4", "out.mp4", "test", nil); err != nil || got != "${HOME:-/root}" {
+		t.Errorf("Expected shell default to be kept, got %q (err: %v)", got, err)
+	}
+}
+
+func TestPreview(t *testing.T) {
+	got, report, err := Preview("${INPUT_FILE_BASE} ${HOME} ${HOME} ${METADATA_empty} ${METADATA_missing} ${METADATA_other:-x}", "/in/input.mp4", "out.mp4", "test", &dto.InterfaceMap{"empty": ""})
+	if err != nil {
+		t.Fatalf("Preview() failed: %v", err)
+	}
+	if want := "input.mp4 ${HOME} ${HOME}   x"; got != want {
+		t.Errorf("Preview() = %q, want %q", got, want)
+	}
+	if !slices.Equal(report.Unknown, []string{"HOME", "METADATA_missing"}) || !slices.Equal(report.Empty, []string{"METADATA_empty"}) {
+		t.Errorf("Unexpected report %+v", report)
+	}
+}
+
+func TestSecrets(t *testing.T) {
+	secrets := Secrets{"key": "abc", "long": "abcdef", "empty": ""}
+	got := Replace("--key ${SECRET_key} --long ${SECRET_long}", "in.mp4", "out.mp4", "test", nil, secrets.Variables())
+	if got != "--key abc --long abcdef" {
+		t.Errorf("Replace() = %q", got)
+	}
+	if masked := secrets.Mask(got); masked != "--key "+SecretMask+" --long "+SecretMask {
+		t.Errorf("Mask() = %q", masked)
+	}
+	if masked := Replace("${SECRET_key}", "in.mp4", "out.mp4", "test", nil, secrets.Masked()); masked != SecretMask {
+		t.Errorf("Masked() = %q", masked)
+	}
+	if only := secrets.Only([]string{"key", "missing"}); len(only) != 1 || only["key"] != "abc" {
+		t.Errorf("Only() = %v", only)
+	}
+}
@@ -0,0 +1,180 @@
+package watchfolder
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+)
+
+// postActionErrors holds the last failed post action per watchfolder, it is kept on the watchfolder until an action succeeds again
+var postActionErrors = sync.Map{}
+
+func (w *Watchfolder) monitorFinishedTasks() {
+	for task := range service.WatchfolderService().GetFinishedTasks() {
+		w.runPostAction(task)
+	}
+}
+
+// runPostAction applies the watchfolder's success or error action to the source file of the finished task
+func (w *Watchfolder) runPostAction(task *model.Task) {
+	uuid, relPath, ok := watchfolderSource(task)
+	if !ok {
+		return
+	}
+	watchfolder, err := w.WatchfolderRepository.First(uuid)
+	if err != nil {
+		debug.Debugf("watchfolder of finished task not found (uuid: %s): %v", task.Uuid, err)
+		return
+	}
+
+	var action *dto.WatchfolderAction
+	var defaultDir string
+	switch task.Status {
+	case dto.DONE_SUCCESSFUL:
+		action, defaultDir = watchfolder.OnSuccess, "done"
+	case dto.DONE_ERROR:
+		action, defaultDir = watchfolder.OnError, "error"
+	default:
+		// canceled tasks leave their source untouched
+		return
+	}
+	if action == nil || action.Action == "" || action.Action == dto.WATCHFOLDER_ACTION_LEAVE {
+		return
+	}
+
+	source := task.InputFile.Raw
+	if _, err := os.Stat(source); errors.Is(err, os.ErrNotExist) {
+		// e.g. a restarted task whose source was already moved by a previous run
+		debug.Debugf("source file %s of finished task does not exist anymore (uuid: %s)", source, task.Uuid)
+		return
+	}
+
+	switch action.Action {
+	case dto.WATCHFOLDER_ACTION_DELETE:
+		err = os.RemoveAll(source)
+	case dto.WATCHFOLDER_ACTION_MOVE:
+		err = moveFile(source, filepath.Join(actionDir(watchfolder, action, defaultDir), relPath))
+	}
+
+	if err != nil {
+		msg := fmt.Sprintf("failed to %s source file %s: %v", action.Action, source, err)
+		postActionErrors.Store(watchfolder.Uuid, msg)
+		watchfolder.Error = msg
+		service.WatchfolderService().UpdateWatchfolderInternal(watchfolder)
+		w.Sev.Logger().Errorf("watchfolder post action failed (uuid: %s): %s", watchfolder.Uuid, msg)
+		return
+	}
+	if _, failed := postActionErrors.LoadAndDelete(watchfolder.Uuid); failed {
+		watchfolder.Error = ""
+		service.WatchfolderService().UpdateWatchfolderInternal(watchfolder)
+	}
+	debug.Debugf("applied post action %s to %s (uuid: %s)", action.Action, source, task.Uuid)
+}
+
+// postActionError returns the last failed post action of the watchfolder
+func postActionError(uuid string) string {
+	if msg, ok := postActionErrors.Load(uuid); ok {
+		return msg.(string)
+	}
+	return ""
+}
+
+// watchfolderSource returns the watchfolder uuid and relative path stored in the ffmate metadata of a task
+func watchfolderSource(task *model.Task) (string, string, bool) {
+	if task.Metadata == nil {
+		return "", "", false
+	}
+	ffmate, ok := (*task.Metadata)["ffmate"].(map[string]any)
+	if !ok {
+		return "", "", false
+	}
+	watchfolder, ok := ffmate["watchfolder"].(map[string]any)
+	if !ok {
+		return "", "", false
+	}
+	uuid, _ := watchfolder["uuid"].(string)
+	relPath, _ := watchfolder["relativePath"].(string)
+	if uuid == "" || relPath == "" {
+		return "", "", false
+	}
+	return uuid, relPath, true
+}
+
+// actionDir returns the absolute directory a move action moves files to
+func actionDir(watchfolder *model.Watchfolder, action *dto.WatchfolderAction, defaultDir string) string {
+	dir := action.Directory
+	if dir == "" {
+		dir = defaultDir
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(watchfolder.Path, dir)
+	}
+	return filepath.Clean(dir)
+}
+
+// actionDirs returns the move targets inside the watchfolder, they must not be watched
+func actionDirs(watchfolder *model.Watchfolder) []string {
+	var dirs []string
+	root := filepath.Clean(watchfolder.Path)
+	for _, a := range []struct {
+		action     *dto.WatchfolderAction
+		defaultDir string
+	}{{watchfolder.OnSuccess, "done"}, {watchfolder.OnError, "error"}} {
+		if a.action == nil || a.action.Action != dto.WATCHFOLDER_ACTION_MOVE {
+			continue
+		}
+		dir := actionDir(watchfolder, a.action, a.defaultDir)
+		if dir != root && isBelow(dir, root) {
+			dirs = append(dirs, dir)
+		}
+	}
+	return dirs
+}
+
+// isBelow reports whether path is dir or inside of it
+func isBelow(path string, dir string) bool {
+	return path == dir || strings.HasPrefix(path, dir+string(filepath.Separator))
+}
+
+// moveFile moves the file or directory and creates missing directories, files are copied if they cannot be renamed (e.g. across filesystems)
+func moveFile(src string, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	err := os.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+	if info, statErr := os.Stat(src); statErr == nil && info.IsDir() {
+		return err
+	}
+
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(dst)
+		return err
+	}
+	in.Close()
+	return os.Remove(src)
+}
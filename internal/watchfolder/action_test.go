@@ -0,0 +1,161 @@
+package watchfolder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+)
+
+func TestRunPostAction(t *testing.T) {
+	w, tasks, preset := setupWatchfolderTest(t)
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	good := filepath.Join(dir, "sub", "good.mp4")
+	bad := filepath.Join(dir, "bad.mp4")
+	for _, path := range []string{good, bad} {
+		if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	watchfolder, err := w.WatchfolderRepository.Create(&dto.NewWatchfolder{
+		Path:      dir,
+		Preset:    preset.Uuid,
+		OnSuccess: &dto.WatchfolderAction{Action: dto.WATCHFOLDER_ACTION_MOVE},
+		OnError:   &dto.WatchfolderAction{Action: dto.WATCHFOLDER_ACTION_DELETE},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watchfolder: %v", err)
+	}
+
+	finish := func(path string, status dto.TaskStatus) {
+		uuid, _, _ := w.createTask(path, stat(t, path), watchfolder, nil)
+		task, err := tasks.First(uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		task.Status = status
+		w.runPostAction(task)
+	}
+
+	finish(good, dto.DONE_SUCCESSFUL)
+	if _, err := os.Stat(filepath.Join(dir, "done", "sub", "good.mp4")); err != nil {
+		t.Errorf("Expected source to be moved to done/ keeping its relative path: %v", err)
+	}
+	if _, err := os.Stat(good); !os.IsNotExist(err) {
+		t.Error("Expected source to be removed from the watchfolder")
+	}
+
+	finish(bad, dto.DONE_ERROR)
+	if _, err := os.Stat(bad); !os.IsNotExist(err) {
+		t.Error("Expected failed source to be deleted")
+	}
+
+	// the move target inside the watchfolder is not watched
+	s := &scanner{w: w, watchfolder: watchfolder, ignoredDirs: actionDirs(watchfolder)}
+	if !s.ignored(filepath.Join(dir, "done", "sub")) || s.ignored(filepath.Join(dir, "sub")) {
+		t.Errorf("Unexpected ignored directories: %v", s.ignoredDirs)
+	}
+}
+
+func TestRunPostActionError(t *testing.T) {
+	w, tasks, preset := setupWatchfolderTest(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "movie.mp4")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// a file where the target directory should be makes the move fail
+	target := filepath.Join(t.TempDir(), "blocked")
+	if err := os.WriteFile(target, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	watchfolder, err := w.WatchfolderRepository.Create(&dto.NewWatchfolder{
+		Path:      dir,
+		Preset:    preset.Uuid,
+		OnSuccess: &dto.WatchfolderAction{Action: dto.WATCHFOLDER_ACTION_MOVE, Directory: target},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watchfolder: %v", err)
+	}
+
+	uuid, _, _ := w.createTask(path, stat(t, path), watchfolder, nil)
+	task, err := tasks.First(uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	task.Status = dto.DONE_SUCCESSFUL
+	w.runPostAction(task)
+
+	updated, err := w.WatchfolderRepository.First(watchfolder.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Error == "" || postActionError(watchfolder.Uuid) == "" {
+		t.Error("Expected the failed post action to be reported on the watchfolder")
+	}
+}
+
+func TestCreateTaskPresets(t *testing.T) {
+	w, tasks, preset := setupWatchfolderTest(t)
+	second, err := service.PresetService().NewPreset(&dto.NewPreset{Name: "Proxy", Command: "-i ${INPUT_FILE} ${OUTPUT_FILE}", OutputFile: "/tmp/proxy.mp4"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	path := filepath.Join(dir, "movie.mp4")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	watchfolder, err := w.WatchfolderRepository.Create(&dto.NewWatchfolder{
+		Path:    dir,
+		Presets: dto.WatchfolderPresets{preset.Uuid, second.Uuid},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watchfolder: %v", err)
+	}
+
+	_, batch, _ := w.createTask(path, stat(t, path), watchfolder, nil)
+	renditions, err := tasks.RootsByBatchId(batch)
+	if err != nil || len(*renditions) != 2 {
+		t.Fatalf("Expected one task per preset in batch %s, got %v (err: %v)", batch, renditions, err)
+	}
+
+	// the post action runs once for the whole batch with the status of its worst task
+	statuses := []dto.TaskStatus{dto.DONE_SUCCESSFUL, dto.DONE_ERROR}
+	for i, task := range *renditions {
+		task.Status = statuses[i]
+		if _, err := service.TaskService().UpdateTask(&task); err != nil {
+			t.Fatal(err)
+		}
+	}
+	select {
+	case finished := <-service.WatchfolderService().GetFinishedTasks():
+		if finished.Batch != batch || finished.Status != dto.DONE_ERROR {
+			t.Errorf("Expected failed batch %s, got %s (batch: %s)", batch, finished.Status, finished.Batch)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected the finished batch to be handed to the post actions")
+	}
+	select {
+	case finished := <-service.WatchfolderService().GetFinishedTasks():
+		t.Errorf("Expected a single post action per batch, got another for task %s", finished.Uuid)
+	default:
+	}
+}
+
+func stat(t *testing.T, path string) os.FileInfo {
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
@@ -0,0 +1,207 @@
+package watchfolder
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// assetInfo describes a directory as a whole, its size and modification time cover all files inside so growth checks apply to the directory
+type assetInfo struct {
+	os.FileInfo
+	size    int64
+	modTime time.Time
+	files   []string
+}
+
+func (a *assetInfo) Size() int64        { return a.size }
+func (a *assetInfo) ModTime() time.Time { return a.modTime }
+
+// sequence is an image sequence found in a directory
+type sequence struct {
+	Pattern string
+	Start   int
+	Frames  int
+}
+
+func (s *sequence) metadata() map[string]string {
+	return map[string]string{
+		"pattern": s.Pattern,
+		"start":   strconv.Itoa(s.Start),
+		"frames":  strconv.Itoa(s.Frames),
+	}
+}
+
+var frameNumber = regexp.MustCompile(`^(.*?)(\d+)(\.[A-Za-z0-9]+)$`)
+
+// walkDirs checks every subdirectory of the watchfolder as a single asset
+func (s *scanner) walkDirs() error {
+	entries, err := os.ReadDir(s.watchfolder.Path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		path := filepath.Join(s.watchfolder.Path, entry.Name())
+		if !entry.IsDir() || s.skipDir(path) {
+			continue
+		}
+		if s.checkDir(path) && s.pending != nil {
+			s.pending[path] = struct{}{}
+		}
+	}
+	return nil
+}
+
+// checkDir creates a task for the directory once it passed the filters and growth checks, it returns true if it must be checked again
+func (s *scanner) checkDir(path string) bool {
+	if strings.HasPrefix(filepath.Base(path), ".") || s.skipDir(path) {
+		return false
+	}
+	rel, err := filepath.Rel(s.watchfolder.Path, path)
+	if err != nil {
+		return false
+	}
+	info, err := s.asset(path)
+	if err != nil {
+		debug.Debugf("failed to read directory %s (uuid: %s): %v", path, s.watchfolder.Uuid, err)
+		return false
+	}
+	// empty directories are likely still being copied
+	if len(info.files) == 0 {
+		return true
+	}
+	if !s.filter.MatchDir(rel, info) {
+		return false
+	}
+	return s.process(path, info, detectSequence(info.files))
+}
+
+// assetDir returns the subdirectory of the watchfolder the path belongs to
+func (s *scanner) assetDir(path string) string {
+	rel, err := filepath.Rel(s.watchfolder.Path, path)
+	if err != nil || rel == "." || strings.HasPrefix(rel, "..") {
+		return ""
+	}
+	return filepath.Join(s.watchfolder.Path, strings.Split(filepath.ToSlash(rel), "/")[0])
+}
+
+// asset sums up all visible files of the directory that pass the extension filter
+func (s *scanner) asset(dir string) (*assetInfo, error) {
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+	info := &assetInfo{FileInfo: dirInfo}
+	err = filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		hidden := strings.HasPrefix(d.Name(), ".")
+		if d.IsDir() {
+			if path != dir && (hidden || s.ignored(path)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if hidden || s.filter.IsMarker(path) || !s.filter.MatchExtension(path) {
+			return nil
+		}
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+		info.size += fi.Size()
+		if fi.ModTime().After(info.modTime) {
+			info.modTime = fi.ModTime()
+		}
+		info.files = append(info.files, path)
+		return nil
+	})
+	return info, err
+}
+
+// detectSequence returns the largest numbered sequence of files with the same name, directory and extension, or nil if there is none.
+// Zero padded frame numbers result in a %0Nd pattern, unpadded ones like f_9, f_10 and f_100 in a %d pattern
+func detectSequence(files []string) *sequence {
+	type frame struct {
+		number int
+		digits string
+	}
+	// group by everything but the frame number, the padding is decided per group
+	groups := map[string][]frame{}
+	for _, file := range files {
+		m := frameNumber.FindStringSubmatch(filepath.Base(file))
+		if m == nil {
+			continue
+		}
+		n, err := strconv.Atoi(m[2])
+		if err != nil {
+			continue
+		}
+		key := filepath.Dir(file) + "\x00" + m[1] + "\x00" + m[3]
+		groups[key] = append(groups[key], frame{number: n, digits: m[2]})
+	}
+
+	type candidate struct {
+		pattern string
+		frames  []int
+	}
+	var best *candidate
+	for key, frames := range groups {
+		parts := strings.Split(key, "\x00")
+		dir, prefix, ext := escapePattern(parts[0]), escapePattern(parts[1]), escapePattern(parts[2])
+
+		// every width of zero padded numbers is a candidate, unpadded numbers fit a padded pattern as long as they are not shorter
+		widths := map[int]bool{0: true}
+		for _, f := range frames {
+			if padded(f.digits) {
+				widths[len(f.digits)] = true
+			}
+		}
+		for width := range widths {
+			c := &candidate{pattern: filepath.Join(dir, prefix+"%d"+ext)}
+			if width > 0 {
+				c.pattern = filepath.Join(dir, prefix+"%0"+strconv.Itoa(width)+"d"+ext)
+			}
+			for _, f := range frames {
+				if fitsWidth(f.digits, width) {
+					c.frames = append(c.frames, f.number)
+				}
+			}
+			if len(c.frames) < 2 {
+				continue
+			}
+			if best == nil || len(c.frames) > len(best.frames) || (len(c.frames) == len(best.frames) && c.pattern < best.pattern) {
+				best = c
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	sort.Ints(best.frames)
+	return &sequence{Pattern: best.pattern, Start: best.frames[0], Frames: len(best.frames)}
+}
+
+// padded reports whether the frame number has leading zeros
+func padded(digits string) bool {
+	return len(digits) > 1 && digits[0] == '0'
+}
+
+// fitsWidth reports whether the frame number is matched by a pattern padded to the width, 0 being unpadded
+func fitsWidth(digits string, width int) bool {
+	if padded(digits) {
+		return len(digits) == width
+	}
+	return len(digits) >= width
+}
+
+// escapePattern escapes % in paths for the printf style pattern of ffmpeg's image2 demuxer
+func escapePattern(name string) string {
+	return strings.ReplaceAll(name, "%", "%%")
+}
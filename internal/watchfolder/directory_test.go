@@ -0,0 +1,94 @@
+package watchfolder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestDetectSequence(t *testing.T) {
+	files := []string{"/in/shot/frame_000099.dpx", "/in/shot/frame_000100.dpx", "/in/shot/frame_000101.dpx", "/in/shot/notes.txt", "/in/shot/audio_1.wav"}
+	seq := detectSequence(files)
+	if seq == nil {
+		t.Fatal("detectSequence() found no sequence")
+	}
+	if seq.Pattern != "/in/shot/frame_%06d.dpx" || seq.Start != 99 || seq.Frames != 3 {
+		t.Errorf("detectSequence() = %+v", seq)
+	}
+	if seq := detectSequence([]string{"/in/card/CLIP0001.MXF"}); seq != nil {
+		t.Errorf("detectSequence() = %+v for a single file", seq)
+	}
+
+	tests := []struct {
+		name    string
+		files   []string
+		pattern string
+		start   int
+		frames  int
+	}{
+		{"Unpadded", []string{"/in/f_9.png", "/in/f_10.png", "/in/f_100.png"}, "/in/f_%d.png", 9, 3},
+		{"Padded beyond width", []string{"/in/f_098.png", "/in/f_099.png", "/in/f_1000.png"}, "/in/f_%03d.png", 98, 3},
+		{"Escaped percent", []string{"/in/100%/f_%_01.png", "/in/100%/f_%_02.png"}, "/in/100%%/f_%%_%02d.png", 1, 2},
+		{"Number only", []string{"/in/0001.exr", "/in/0002.exr"}, "/in/%04d.exr", 1, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			seq := detectSequence(tt.files)
+			if seq == nil || seq.Pattern != filepath.FromSlash(tt.pattern) || seq.Start != tt.start || seq.Frames != tt.frames {
+				t.Errorf("detectSequence() = %+v", seq)
+			}
+		})
+	}
+}
+
+func TestWatchfolderDirectories(t *testing.T) {
+	w, tasks, preset := setupWatchfolderTest(t)
+	dir := t.TempDir()
+	shot := filepath.Join(dir, "shot_010")
+	if err := os.MkdirAll(shot, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1001; i < 1011; i++ {
+		if err := os.WriteFile(filepath.Join(shot, fmt.Sprintf("shot_010.%04d.exr", i)), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// files in the watchfolder itself are ignored in directory mode
+	if err := os.WriteFile(filepath.Join(dir, "loose.mp4"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	watchfolder, err := w.WatchfolderRepository.Create(&dto.NewWatchfolder{
+		Path:     dir,
+		Interval: 1,
+		Preset:   preset.Uuid,
+		Unit:     dto.WATCHFOLDER_UNIT_DIRECTORY,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watchfolder: %v", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(errors.New("test finished"))
+	go w.process(watchfolder, ctx)
+	waitForTasks(t, tasks, 1)
+
+	list, _, err := tasks.List(0, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	task := (*list)[0]
+	if task.InputFile.Raw != shot {
+		t.Errorf("Expected the directory as input, got %s", task.InputFile.Raw)
+	}
+	ffmate := (*task.Metadata)["ffmate"].(map[string]any)
+	sequence := ffmate["sequence"].(map[string]any)
+	if sequence["pattern"] != filepath.Join(shot, "shot_010.%d.exr") || sequence["start"] != "1001" || sequence["frames"] != "10" {
+		t.Errorf("Unexpected sequence metadata: %v", sequence)
+	}
+}
@@ -0,0 +1,165 @@
+package watchfolder
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+)
+
+// defaultReconcileInterval is used if an event based watchfolder has no reconcile interval set
+const defaultReconcileInterval = 300
+
+// watch processes the watchfolder based on filesystem notifications, files that are still growing are re-checked every interval
+// and the whole folder is walked every reconcile interval to catch missed events. It returns nil once the context is done.
+func (w *Watchfolder) watch(watchfolder *model.Watchfolder, ctx context.Context, s *scanner) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return err
+	}
+	defer watcher.Close()
+
+	if err := addRecursive(watcher, watchfolder.Path, s); err != nil {
+		return err
+	}
+	debug.Debugf("watching watchfolder for events (uuid: %s)", watchfolder.Uuid)
+
+	interval := time.Duration(max(watchfolder.Interval, 1)) * time.Second
+	reconcileInterval := watchfolder.ReconcileInterval
+	if reconcileInterval <= 0 {
+		reconcileInterval = defaultReconcileInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	reconciler := time.NewTicker(time.Duration(reconcileInterval) * time.Second)
+	defer reconciler.Stop()
+
+	// files that were added while ffmate was not running
+	pending := map[string]struct{}{}
+	s.pending = pending
+	if w.reconcile(watchfolder, s) {
+		return nil
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			w.Sev.Logger().Infof("stopped watchfolder (uuid: %s): %s", watchfolder.Uuid, context.Cause(ctx))
+			return nil
+
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return errors.New("watcher closed")
+			}
+			if s.skipDir(filepath.Dir(event.Name)) {
+				continue
+			}
+			if event.Has(fsnotify.Remove) || event.Has(fsnotify.Rename) {
+				delete(pending, event.Name)
+				s.fileStates.Delete(event.Name)
+				continue
+			}
+			if !event.Has(fsnotify.Create) && !event.Has(fsnotify.Write) {
+				continue
+			}
+			info, err := os.Stat(event.Name)
+			if err != nil {
+				continue
+			}
+			if info.IsDir() && event.Has(fsnotify.Create) {
+				// directories that are created or moved into the folder need their own watches and may already contain files
+				if err := addRecursive(watcher, event.Name, s); err != nil {
+					w.Sev.Logger().Warnf("failed to watch directory '%s' (uuid: %s): %v", event.Name, watchfolder.Uuid, err)
+				}
+				if watchfolder.Unit != dto.WATCHFOLDER_UNIT_DIRECTORY {
+					if err := s.walk(event.Name); err != nil {
+						debug.Debugf("failed to walk new directory '%s' (uuid: %s): %v", event.Name, watchfolder.Uuid, err)
+					}
+				}
+			}
+			// files are checked on the next tick, a file is usually created empty and written afterwards
+			if watchfolder.Unit == dto.WATCHFOLDER_UNIT_DIRECTORY {
+				if dir := s.assetDir(event.Name); dir != "" {
+					pending[dir] = struct{}{}
+				}
+			} else if !info.IsDir() {
+				pending[event.Name] = struct{}{}
+			}
+			// a ready marker makes the files it belongs to ready
+			for _, file := range s.filter.MarkedFiles(event.Name) {
+				pending[file] = struct{}{}
+			}
+
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return errors.New("watcher closed")
+			}
+			w.Sev.Logger().Warnf("watchfolder event error (uuid: %s): %v", watchfolder.Uuid, err)
+			if errors.Is(err, fsnotify.ErrEventOverflow) && w.reconcile(watchfolder, s) {
+				return nil
+			}
+
+		case <-ticker.C:
+			// check the files changed since the last tick, files that are not ready yet stay pending
+			for path := range pending {
+				info, err := os.Stat(path)
+				if err != nil {
+					delete(pending, path)
+					s.fileStates.Delete(path)
+					continue
+				}
+				if !s.checkPath(path, info) {
+					delete(pending, path)
+				}
+			}
+			watchfolder.LastCheck = time.Now().UnixMilli()
+			watchfolder.Error = s.error()
+			w.Sev.Metrics().Gauge("watchfolder.executed").Inc()
+			service.WatchfolderService().UpdateWatchfolderInternal(watchfolder)
+
+		case <-reconciler.C:
+			if w.reconcile(watchfolder, s) {
+				return nil
+			}
+		}
+	}
+}
+
+// reconcile walks the whole watchfolder as a fallback for missed events, it returns true if the watchfolder got suspended
+func (w *Watchfolder) reconcile(watchfolder *model.Watchfolder, s *scanner) bool {
+	debug.Debugf("reconciling watchfolder (uuid: %s)", watchfolder.Uuid)
+	watchfolder.LastCheck = time.Now().UnixMilli()
+	s.walkError = ""
+	err := s.scan()
+	if err != nil {
+		s.walkError = err.Error()
+	}
+	suspended := w.recordCheck(watchfolder, err)
+	watchfolder.Error = s.error()
+	w.Sev.Metrics().Gauge("watchfolder.executed").Inc()
+	service.WatchfolderService().UpdateWatchfolderInternal(watchfolder)
+	return suspended
+}
+
+// addRecursive adds a watch for the directory and all of its subdirectories except ignored ones
+func addRecursive(watcher *fsnotify.Watcher, root string, s *scanner) error {
+	return filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if s.skipDir(path) {
+				return filepath.SkipDir
+			}
+			return watcher.Add(path)
+		}
+		return nil
+	})
+}
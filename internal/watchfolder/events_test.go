@@ -0,0 +1,157 @@
+package watchfolder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/database/repository"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/metrics"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/sev"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupWatchfolderTest(t *testing.T) (*Watchfolder, *repository.Task, *model.Preset) {
+	db, err := gorm.Open(sqlite.Open(fmt.Sprintf("file:%s?mode=memory&cache=shared", t.Name())), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+	if err := db.AutoMigrate(&model.Watchfolder{}, &model.WatchfolderFile{}, &model.Preset{}, &model.Webhook{}, &model.Task{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	s := sev.New("test", "", "", 3000)
+	s.SetDB(db)
+	service.Init(s)
+
+	metrics := &metrics.Metrics{}
+	for name, gauge := range metrics.Gauges() {
+		s.Metrics().RegisterGauge(name, gauge)
+	}
+	for name, gauge := range metrics.GaugesVec() {
+		s.Metrics().RegisterGaugeVec(name, gauge)
+	}
+
+	preset, err := service.PresetService().NewPreset(&dto.NewPreset{Name: "Test Preset", Command: "-i ${INPUT_FILE} ${OUTPUT_FILE}", OutputFile: "/tmp/out.mp4"})
+	if err != nil {
+		t.Fatalf("Failed to create preset: %v", err)
+	}
+
+	return &Watchfolder{Sev: s, WatchfolderRepository: &repository.Watchfolder{DB: db}}, &repository.Task{DB: db}, preset
+}
+
+func waitForTasks(t *testing.T, tasks *repository.Task, want int64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		if count, _ := tasks.Count(); count == want {
+			return
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	count, _ := tasks.Count()
+	t.Fatalf("Expected %d tasks, got %d", want, count)
+}
+
+func TestWatchfolderEvents(t *testing.T) {
+	w, tasks, preset := setupWatchfolderTest(t)
+	dir := t.TempDir()
+
+	// existing files are picked up by the initial reconcile walk
+	if err := os.WriteFile(filepath.Join(dir, "existing.mp4"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	watchfolder, err := w.WatchfolderRepository.Create(&dto.NewWatchfolder{
+		Path:     dir,
+		Interval: 1,
+		Preset:   preset.Uuid,
+		Mode:     dto.WATCHFOLDER_MODE_EVENTS,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watchfolder: %v", err)
+	}
+
+	ctx, cancel := context.WithCancelCause(context.Background())
+	defer cancel(errors.New("test finished"))
+	go w.process(watchfolder, ctx)
+	waitForTasks(t, tasks, 1)
+
+	// new files and files in new subdirectories are picked up by events
+	if err := os.WriteFile(filepath.Join(dir, "new.mp4"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForTasks(t, tasks, 2)
+
+	if err := os.MkdirAll(filepath.Join(dir, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	if err := os.WriteFile(filepath.Join(dir, "sub", "nested.mp4"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForTasks(t, tasks, 3)
+
+	// hidden files are ignored
+	if err := os.WriteFile(filepath.Join(dir, ".hidden.mp4"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(500 * time.Millisecond)
+	waitForTasks(t, tasks, 3)
+}
+
+func TestWatchfolderPersistedFiles(t *testing.T) {
+	w, tasks, preset := setupWatchfolderTest(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "movie.mp4")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	watchfolder, err := w.WatchfolderRepository.Create(&dto.NewWatchfolder{
+		Path:     dir,
+		Interval: 1,
+		Preset:   preset.Uuid,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watchfolder: %v", err)
+	}
+
+	run := func() context.CancelCauseFunc {
+		ctx, cancel := context.WithCancelCause(context.Background())
+		wf := *watchfolder
+		go w.process(&wf, ctx)
+		return cancel
+	}
+
+	cancel := run()
+	waitForTasks(t, tasks, 1)
+	cancel(errors.New("restart"))
+
+	// a restarted watchfolder does not process the same file again
+	cancel = run()
+	time.Sleep(1500 * time.Millisecond)
+	waitForTasks(t, tasks, 1)
+
+	// but a changed file is processed again
+	if err := os.WriteFile(path, []byte("changed"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	waitForTasks(t, tasks, 2)
+	cancel(errors.New("test finished"))
+
+	files, _, err := service.WatchfolderService().ListWatchfolderFiles(watchfolder.Uuid, -1, -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*files) != 1 || (*files)[0].Size != int64(len("changed")) || (*files)[0].Task == "" {
+		t.Errorf("Unexpected processed files: %+v", *files)
+	}
+}
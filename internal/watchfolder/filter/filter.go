@@ -0,0 +1,240 @@
+package filter
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+// Filter decides which files of a watchfolder are processed
+type Filter struct {
+	filter *dto.WatchfolderFilter
+	regex  *regexp.Regexp
+
+	includeExt []string
+	excludeExt []string
+	markers    []string
+}
+
+// Compile validates the filter and prepares it for matching, a nil filter matches every file
+func Compile(f *dto.WatchfolderFilter) (*Filter, error) {
+	if f == nil {
+		f = &dto.WatchfolderFilter{}
+	}
+	c := &Filter{filter: f}
+
+	if f.Regex != "" {
+		regex, err := regexp.Compile(f.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %v", err)
+		}
+		c.regex = regex
+	}
+	for _, patterns := range [][]string{f.Include, f.Exclude, f.IgnoreDirs} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(strings.ReplaceAll(pattern, "**", "*"), ""); err != nil {
+				return nil, fmt.Errorf("invalid glob pattern '%s': %v", pattern, err)
+			}
+		}
+	}
+	if f.MinSize < 0 || f.MaxSize < 0 || (f.MaxSize > 0 && f.MinSize > f.MaxSize) {
+		return nil, fmt.Errorf("invalid size range %d-%d", f.MinSize, f.MaxSize)
+	}
+	if f.MinAge < 0 || f.MaxDepth < 0 {
+		return nil, fmt.Errorf("minAge and maxDepth must not be negative")
+	}
+
+	if f.Extensions != nil {
+		c.includeExt = normalizeExtensions(f.Extensions.Include)
+		c.excludeExt = normalizeExtensions(f.Extensions.Exclude)
+	}
+	c.markers = normalizeExtensions(f.ReadyMarkers)
+	return c, nil
+}
+
+// Match reports whether the file at the path relative to the watchfolder passes the filter, the age and ready markers are checked separately
+func (c *Filter) Match(rel string, info os.FileInfo) bool {
+	return c.MatchExtension(rel) && c.match(rel, info)
+}
+
+// MatchDir reports whether the directory passes the filter in directory mode, extensions are applied to the files inside with MatchExtension
+func (c *Filter) MatchDir(rel string, info os.FileInfo) bool {
+	return c.match(rel, info)
+}
+
+// MatchExtension reports whether the extension of the file passes the extension filter
+func (c *Filter) MatchExtension(file string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(file), "."))
+	if len(c.excludeExt) > 0 && slices.Contains(c.excludeExt, ext) {
+		return false
+	}
+	if len(c.includeExt) > 0 && !slices.Contains(c.includeExt, ext) {
+		return false
+	}
+	return true
+}
+
+func (c *Filter) match(rel string, info os.FileInfo) bool {
+	rel = filepath.ToSlash(rel)
+	f := c.filter
+
+	if f.MaxDepth > 0 && strings.Count(rel, "/")+1 > f.MaxDepth {
+		return false
+	}
+	if dir := path.Dir(rel); dir != "." && c.ignoredDir(dir) {
+		return false
+	}
+	if c.IsMarker(rel) {
+		return false
+	}
+
+	if len(f.Include) > 0 && !matchAny(f.Include, rel) {
+		return false
+	}
+	if matchAny(f.Exclude, rel) {
+		return false
+	}
+	if c.regex != nil && !c.regex.MatchString(rel) {
+		return false
+	}
+
+	if info.Size() < f.MinSize || (f.MaxSize > 0 && info.Size() > f.MaxSize) {
+		return false
+	}
+	return true
+}
+
+// Aged reports whether the file was last modified long enough ago
+func (c *Filter) Aged(info os.FileInfo, now time.Time) bool {
+	return c.filter.MinAge == 0 || now.Sub(info.ModTime()) >= time.Duration(c.filter.MinAge)*time.Second
+}
+
+// SkipDir reports whether the directory relative to the watchfolder must not be looked into
+func (c *Filter) SkipDir(rel string) bool {
+	rel = filepath.ToSlash(rel)
+	if rel == "." || rel == "" {
+		return false
+	}
+	if c.filter.MaxDepth > 0 && strings.Count(rel, "/")+1 >= c.filter.MaxDepth {
+		return true
+	}
+	return c.ignoredDir(rel)
+}
+
+// IsMarker reports whether the file is a ready marker
+func (c *Filter) IsMarker(file string) bool {
+	ext := strings.ToLower(strings.TrimPrefix(path.Ext(filepath.ToSlash(file)), "."))
+	return ext != "" && slices.Contains(c.markers, ext)
+}
+
+// Ready reports whether a ready marker exists next to the file, files are always ready if no markers are configured
+func (c *Filter) Ready(file string) bool {
+	if len(c.markers) == 0 {
+		return true
+	}
+	stem := strings.TrimSuffix(file, filepath.Ext(file))
+	for _, marker := range c.markers {
+		for _, candidate := range []string{file + "." + marker, stem + "." + marker} {
+			if info, err := os.Stat(candidate); err == nil && info.Mode().IsRegular() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// MarkedFiles returns the files in the directory the ready marker belongs to
+func (c *Filter) MarkedFiles(marker string) []string {
+	if !c.IsMarker(marker) {
+		return nil
+	}
+	name := strings.TrimSuffix(marker, filepath.Ext(marker))
+	files := []string{name}
+	entries, err := os.ReadDir(filepath.Dir(marker))
+	if err != nil {
+		return files
+	}
+	for _, entry := range entries {
+		p := filepath.Join(filepath.Dir(marker), entry.Name())
+		if !entry.IsDir() && p != name && p != marker && strings.TrimSuffix(p, filepath.Ext(p)) == name {
+			files = append(files, p)
+		}
+	}
+	return files
+}
+
+func (c *Filter) ignoredDir(rel string) bool {
+	for _, pattern := range c.filter.IgnoreDirs {
+		// patterns without a slash match any directory of the path
+		if !strings.Contains(pattern, "/") {
+			for _, segment := range strings.Split(rel, "/") {
+				if ok, _ := path.Match(pattern, segment); ok {
+					return true
+				}
+			}
+			continue
+		}
+		if Glob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAny(patterns []string, rel string) bool {
+	for _, pattern := range patterns {
+		if !strings.Contains(pattern, "/") {
+			if ok, _ := path.Match(pattern, path.Base(rel)); ok {
+				return true
+			}
+			continue
+		}
+		if Glob(pattern, rel) {
+			return true
+		}
+	}
+	return false
+}
+
+// Glob matches the slash separated path against the pattern, ** matches any number of directories
+func Glob(pattern string, name string) bool {
+	return globSegments(strings.Split(pattern, "/"), strings.Split(name, "/"))
+}
+
+func globSegments(pattern []string, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if globSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(name) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+		pattern, name = pattern[1:], name[1:]
+	}
+	return len(name) == 0
+}
+
+func normalizeExtensions(extensions []string) []string {
+	var normalized []string
+	for _, ext := range extensions {
+		if ext = strings.ToLower(strings.TrimPrefix(ext, ".")); ext != "" {
+			normalized = append(normalized, ext)
+		}
+	}
+	return normalized
+}
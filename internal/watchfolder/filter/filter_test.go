@@ -0,0 +1,133 @@
+package filter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+type fakeInfo struct {
+	os.FileInfo
+	size    int64
+	modTime time.Time
+}
+
+func (f fakeInfo) Size() int64        { return f.size }
+func (f fakeInfo) ModTime() time.Time { return f.modTime }
+func (f fakeInfo) IsDir() bool        { return false }
+
+func TestGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		name    string
+		want    bool
+	}{
+		{"*.mp4", "movie.mp4", true},
+		{"incoming/*.mp4", "incoming/movie.mp4", true},
+		{"incoming/*.mp4", "incoming/sub/movie.mp4", false},
+		{"incoming/**/*.mp4", "incoming/sub/deep/movie.mp4", true},
+		{"incoming/**/*.mp4", "incoming/movie.mp4", true},
+		{"**/proxies", "a/b/proxies", true},
+		{"**/proxies", "a/b/proxies/x", false},
+	}
+	for _, tt := range tests {
+		if got := Glob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("Glob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	info := fakeInfo{size: 100}
+	tests := []struct {
+		name   string
+		filter *dto.WatchfolderFilter
+		rel    string
+		want   bool
+	}{
+		{"No filter", nil, "a/movie.mov", true},
+		{"Include extension with dot", &dto.WatchfolderFilter{Extensions: &dto.WatchfolderFilterExtensions{Include: []string{".MP4"}}}, "movie.mp4", true},
+		{"Include extension does not match suffix", &dto.WatchfolderFilter{Extensions: &dto.WatchfolderFilterExtensions{Include: []string{"mp4"}}}, "movie.xmp4", false},
+		{"Exclude and include combined", &dto.WatchfolderFilter{Extensions: &dto.WatchfolderFilterExtensions{Include: []string{"mp4"}, Exclude: []string{"tmp"}}}, "movie.mov", false},
+		{"Glob include", &dto.WatchfolderFilter{Include: []string{"incoming/**/*.mxf"}}, "incoming/cam/a.mxf", true},
+		{"Glob include mismatch", &dto.WatchfolderFilter{Include: []string{"incoming/**/*.mxf"}}, "other/a.mxf", false},
+		{"Glob exclude on name", &dto.WatchfolderFilter{Exclude: []string{"*_proxy.*"}}, "a/b_proxy.mp4", false},
+		{"Regex", &dto.WatchfolderFilter{Regex: `^[A-Z]{3}_\d+\.mov$`}, "ABC_123.mov", true},
+		{"Regex mismatch", &dto.WatchfolderFilter{Regex: `^[A-Z]{3}_\d+\.mov$`}, "abc_123.mov", false},
+		{"Min size", &dto.WatchfolderFilter{MinSize: 101}, "a.mp4", false},
+		{"Max size", &dto.WatchfolderFilter{MaxSize: 99}, "a.mp4", false},
+		{"Max depth", &dto.WatchfolderFilter{MaxDepth: 1}, "sub/a.mp4", false},
+		{"Max depth top level", &dto.WatchfolderFilter{MaxDepth: 1}, "a.mp4", true},
+		{"Ignored directory", &dto.WatchfolderFilter{IgnoreDirs: []string{"tmp"}}, "x/tmp/a.mp4", false},
+		{"Ready marker itself", &dto.WatchfolderFilter{ReadyMarkers: []string{"done"}}, "a.mp4.done", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := Compile(tt.filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := f.Match(tt.rel, info); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.rel, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompile(t *testing.T) {
+	for _, f := range []*dto.WatchfolderFilter{
+		{Regex: "("},
+		{Include: []string{"[a-"}},
+		{MinSize: 10, MaxSize: 5},
+		{MaxDepth: -1},
+	} {
+		if _, err := Compile(f); err == nil {
+			t.Errorf("Compile(%+v) accepted an invalid filter", f)
+		}
+	}
+}
+
+func TestSkipDir(t *testing.T) {
+	f, _ := Compile(&dto.WatchfolderFilter{MaxDepth: 2, IgnoreDirs: []string{"proxies/*"}})
+	if f.SkipDir(".") || f.SkipDir("a") {
+		t.Error("SkipDir() skipped a directory within the max depth")
+	}
+	if !f.SkipDir("a/b") {
+		t.Error("SkipDir() did not skip a directory beyond the max depth")
+	}
+	if !f.SkipDir("proxies/low") {
+		t.Error("SkipDir() did not skip an ignored directory")
+	}
+}
+
+func TestAgedAndReady(t *testing.T) {
+	f, _ := Compile(&dto.WatchfolderFilter{MinAge: 60, ReadyMarkers: []string{".done", "md5"}})
+	now := time.Now()
+	if f.Aged(fakeInfo{modTime: now.Add(-30 * time.Second)}, now) {
+		t.Error("Aged() accepted a file that is too young")
+	}
+	if !f.Aged(fakeInfo{modTime: now.Add(-90 * time.Second)}, now) {
+		t.Error("Aged() rejected an old file")
+	}
+
+	dir := t.TempDir()
+	file := filepath.Join(dir, "movie.mp4")
+	if f.Ready(file) {
+		t.Error("Ready() without marker")
+	}
+	if err := os.WriteFile(filepath.Join(dir, "movie.md5"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if !f.Ready(file) {
+		t.Error("Ready() did not find the marker")
+	}
+	if err := os.WriteFile(file, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if files := f.MarkedFiles(filepath.Join(dir, "movie.md5")); len(files) != 2 || files[1] != file {
+		t.Errorf("MarkedFiles() = %v", files)
+	}
+}
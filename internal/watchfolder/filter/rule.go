@@ -0,0 +1,75 @@
+package filter
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+// Resolution returns the width and height of the first video stream of a file
+type Resolution func() (int, int, error)
+
+// Rules routes the files of a watchfolder, the first matching rule wins
+type Rules struct {
+	rules   dto.WatchfolderRules
+	filters []*Filter
+}
+
+// CompileRules validates the rules and prepares them for matching
+func CompileRules(rules dto.WatchfolderRules) (*Rules, error) {
+	c := &Rules{rules: rules}
+	for i, rule := range rules {
+		m := rule.Match
+		f, err := Compile(&dto.WatchfolderFilter{
+			Extensions: &dto.WatchfolderFilterExtensions{Include: m.Extensions},
+			Include:    m.Include,
+			Exclude:    m.Exclude,
+			Regex:      m.Regex,
+			MinSize:    m.MinSize,
+			MaxSize:    m.MaxSize,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("rule %d: %v", i, err)
+		}
+		if m.MinWidth < 0 || m.MaxWidth < 0 || m.MinHeight < 0 || m.MaxHeight < 0 {
+			return nil, fmt.Errorf("rule %d: resolution must not be negative", i)
+		}
+		c.filters = append(c.filters, f)
+	}
+	return c, nil
+}
+
+// Route returns the index of the first rule matching the file at the path relative to the watchfolder, or -1.
+// The resolution is only looked up once and only if a rule depends on it
+func (c *Rules) Route(rel string, info os.FileInfo, resolution Resolution) (int, *dto.WatchfolderRule) {
+	probed := false
+	var width, height int
+	var probeErr error
+
+	for i, f := range c.filters {
+		if info.IsDir() && !f.MatchDir(rel, info) || !info.IsDir() && !f.Match(rel, info) {
+			continue
+		}
+		m := c.rules[i].Match
+		if m.MinWidth > 0 || m.MaxWidth > 0 || m.MinHeight > 0 || m.MaxHeight > 0 {
+			if !probed {
+				probed = true
+				if resolution == nil {
+					probeErr = fmt.Errorf("resolution unavailable")
+				} else {
+					width, height, probeErr = resolution()
+				}
+			}
+			if probeErr != nil || !inRange(width, m.MinWidth, m.MaxWidth) || !inRange(height, m.MinHeight, m.MaxHeight) {
+				continue
+			}
+		}
+		return i, &c.rules[i]
+	}
+	return -1, nil
+}
+
+func inRange(v int, min int, max int) bool {
+	return v >= min && (max == 0 || v <= max)
+}
@@ -0,0 +1,60 @@
+package filter
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+)
+
+func TestRoute(t *testing.T) {
+	zero := uint(0)
+	rules, err := CompileRules(dto.WatchfolderRules{
+		{Name: "mxf", Match: dto.WatchfolderRuleMatch{Extensions: []string{"mxf"}}, Presets: dto.WatchfolderPresets{"a"}},
+		{Name: "proxy", Match: dto.WatchfolderRuleMatch{Include: []string{"*_proxy*"}}, Presets: dto.WatchfolderPresets{"b"}},
+		{Name: "uhd", Match: dto.WatchfolderRuleMatch{MinWidth: 3840}, Presets: dto.WatchfolderPresets{"c"}},
+		{Name: "large", Match: dto.WatchfolderRuleMatch{MinSize: 10 << 30}, Priority: &zero},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	probes := 0
+	hd := func() (int, int, error) { probes++; return 1920, 1080, nil }
+	uhd := func() (int, int, error) { probes++; return 3840, 2160, nil }
+	failing := func() (int, int, error) { probes++; return 0, 0, errors.New("no video stream") }
+
+	tests := []struct {
+		rel        string
+		size       int64
+		resolution Resolution
+		want       int
+		wantProbes int
+	}{
+		{"in/MOVIE.MXF", 1, hd, 0, 0},
+		{"in/movie_proxy.mov", 1, hd, 1, 0},
+		{"in/movie.mov", 1, uhd, 2, 1},
+		{"in/movie.mov", 11 << 30, hd, 3, 1},
+		{"in/movie.mov", 1, failing, -1, 1},
+		{"in/movie.mov", 1, nil, -1, 0},
+	}
+	for _, tt := range tests {
+		probes = 0
+		got, _ := rules.Route(tt.rel, fakeInfo{size: tt.size}, tt.resolution)
+		if got != tt.want || probes != tt.wantProbes {
+			t.Errorf("Route(%q, %d) = %d with %d probes, want %d with %d probes", tt.rel, tt.size, got, probes, tt.want, tt.wantProbes)
+		}
+	}
+}
+
+func TestCompileRulesInvalid(t *testing.T) {
+	for _, rule := range []dto.WatchfolderRule{
+		{Match: dto.WatchfolderRuleMatch{Regex: "("}},
+		{Match: dto.WatchfolderRuleMatch{MinSize: 10, MaxSize: 1}},
+		{Match: dto.WatchfolderRuleMatch{MinWidth: -1}},
+	} {
+		if _, err := CompileRules(dto.WatchfolderRules{rule}); err == nil {
+			t.Errorf("Expected rule %+v to be invalid", rule.Match)
+		}
+	}
+}
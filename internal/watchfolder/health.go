@@ -0,0 +1,96 @@
+package watchfolder
+
+import (
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+)
+
+const (
+	// historySize is the number of errors and task creations kept per watchfolder
+	historySize = 10
+	// defaultFailingAfter is the number of consecutive failed checks after which a watchfolder that is never suspended counts as failing
+	defaultFailingAfter = 3
+)
+
+// recordCheck updates the health of the watchfolder after it was checked, it returns true if the watchfolder got suspended
+func (w *Watchfolder) recordCheck(watchfolder *model.Watchfolder, err error) bool {
+	if err == nil {
+		if watchfolder.ConsecutiveErrors > 0 {
+			w.Sev.Logger().Infof("watchfolder recovered after %d failed checks (uuid: %s)", watchfolder.ConsecutiveErrors, watchfolder.Uuid)
+		}
+		watchfolder.ConsecutiveErrors = 0
+		watchfolder.Health = dto.WATCHFOLDER_HEALTH_OK
+		return false
+	}
+
+	watchfolder.ConsecutiveErrors++
+	// the same error is only logged once to not flood the log every interval
+	if repeated := recordError(watchfolder, err.Error()); repeated {
+		debug.Debugf("walking watchfolder directory failed again (uuid: %s): %v", watchfolder.Uuid, err)
+	} else {
+		w.Sev.Logger().Errorf("walking watchfolder directory failed (uuid: %s): %v", watchfolder.Uuid, err)
+	}
+
+	failingAfter := defaultFailingAfter
+	if watchfolder.SuspendAfter > 0 {
+		failingAfter = watchfolder.SuspendAfter
+	}
+	if watchfolder.ConsecutiveErrors < failingAfter {
+		watchfolder.Health = dto.WATCHFOLDER_HEALTH_DEGRADED
+		return false
+	}
+
+	if watchfolder.Health != dto.WATCHFOLDER_HEALTH_FAILING {
+		watchfolder.Health = dto.WATCHFOLDER_HEALTH_FAILING
+		w.Sev.Logger().Warnf("watchfolder is failing after %d consecutive failed checks (uuid: %s)", watchfolder.ConsecutiveErrors, watchfolder.Uuid)
+		service.WatchfolderService().WatchfolderFailing(watchfolder)
+	}
+	if watchfolder.SuspendAfter > 0 {
+		watchfolder.Suspended = true
+		w.Sev.Logger().Warnf("suspended watchfolder after %d consecutive failed checks (uuid: %s)", watchfolder.ConsecutiveErrors, watchfolder.Uuid)
+		return true
+	}
+	return false
+}
+
+// recordError adds the error to the history of the watchfolder, it returns true if it repeats the last error
+func recordError(watchfolder *model.Watchfolder, msg string) bool {
+	history := watchfolderHistory(watchfolder)
+	now := time.Now().UnixMilli()
+	if n := len(history.Errors); n > 0 && history.Errors[n-1].Error == msg {
+		history.Errors[n-1].Count++
+		history.Errors[n-1].Time = now
+		return true
+	}
+	history.Errors = appendHistory(history.Errors, dto.WatchfolderHistoryEntry{Time: now, Error: msg, Count: 1})
+	return false
+}
+
+// recordTasks counts the tasks created for the file and adds them to the history of the watchfolder
+func recordTasks(watchfolder *model.Watchfolder, path string, tasks []model.Task) {
+	history := watchfolderHistory(watchfolder)
+	now := time.Now().UnixMilli()
+	watchfolder.TasksCreated += int64(len(tasks))
+	watchfolder.LastTask = tasks[0].Uuid
+	watchfolder.LastTaskAt = now
+	history.Tasks = appendHistory(history.Tasks, dto.WatchfolderHistoryEntry{Time: now, Path: path, Task: tasks[0].Uuid, Batch: tasks[0].Batch})
+}
+
+func watchfolderHistory(watchfolder *model.Watchfolder) *dto.WatchfolderHistory {
+	if watchfolder.History == nil {
+		watchfolder.History = &dto.WatchfolderHistory{}
+	}
+	return watchfolder.History
+}
+
+// appendHistory appends the entry and drops the oldest entries beyond the history size
+func appendHistory(entries []dto.WatchfolderHistoryEntry, entry dto.WatchfolderHistoryEntry) []dto.WatchfolderHistoryEntry {
+	entries = append(entries, entry)
+	if len(entries) > historySize {
+		entries = entries[len(entries)-historySize:]
+	}
+	return entries
+}
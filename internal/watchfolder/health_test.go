@@ -0,0 +1,87 @@
+package watchfolder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/welovemedia/ffmate/internal/database/model"
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+)
+
+func TestRecordCheck(t *testing.T) {
+	w, _, _ := setupWatchfolderTest(t)
+	watchfolder := &model.Watchfolder{Uuid: "test"}
+
+	// watchfolders that are never suspended start failing after the default threshold
+	for i := 1; i <= defaultFailingAfter+1; i++ {
+		if w.recordCheck(watchfolder, errors.New("path gone")) {
+			t.Fatal("Expected the watchfolder not to be suspended")
+		}
+		want := dto.WATCHFOLDER_HEALTH_DEGRADED
+		if i >= defaultFailingAfter {
+			want = dto.WATCHFOLDER_HEALTH_FAILING
+		}
+		if watchfolder.Health != want || watchfolder.ConsecutiveErrors != i {
+			t.Errorf("After %d errors expected %s, got %s with %d errors", i, want, watchfolder.Health, watchfolder.ConsecutiveErrors)
+		}
+	}
+	if errs := watchfolder.History.Errors; len(errs) != 1 || errs[0].Count != defaultFailingAfter+1 {
+		t.Errorf("Expected repeated errors to be collapsed, got %+v", errs)
+	}
+
+	w.recordCheck(watchfolder, nil)
+	if watchfolder.Health != dto.WATCHFOLDER_HEALTH_OK || watchfolder.ConsecutiveErrors != 0 {
+		t.Errorf("Expected the watchfolder to recover, got %s with %d errors", watchfolder.Health, watchfolder.ConsecutiveErrors)
+	}
+
+	for i := 0; i < historySize+5; i++ {
+		recordError(watchfolder, fmt.Sprintf("error %d", i))
+		recordTasks(watchfolder, "/in/movie.mp4", []model.Task{{Uuid: fmt.Sprintf("task %d", i)}})
+	}
+	if len(watchfolder.History.Errors) != historySize || len(watchfolder.History.Tasks) != historySize {
+		t.Errorf("Expected the history to be capped at %d entries", historySize)
+	}
+	if watchfolder.TasksCreated != historySize+5 || watchfolder.LastTask != fmt.Sprintf("task %d", historySize+4) {
+		t.Errorf("Unexpected task stats: %d tasks, last %s", watchfolder.TasksCreated, watchfolder.LastTask)
+	}
+}
+
+func TestWatchfolderSuspendAfter(t *testing.T) {
+	w, _, preset := setupWatchfolderTest(t)
+	watchfolder, err := w.WatchfolderRepository.Create(&dto.NewWatchfolder{
+		Path:         filepath.Join(t.TempDir(), "missing"),
+		Preset:       preset.Uuid,
+		SuspendAfter: 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watchfolder: %v", err)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		w.process(watchfolder, context.Background())
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the failing watchfolder to be suspended")
+	}
+
+	stats, err := service.WatchfolderService().GetWatchfolderStats(watchfolder.Uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Health != dto.WATCHFOLDER_HEALTH_FAILING || stats.ConsecutiveErrors != 2 || len(stats.History.Errors) != 1 {
+		t.Errorf("Unexpected stats of suspended watchfolder: %+v", stats)
+	}
+	stored, _ := w.WatchfolderRepository.First(watchfolder.Uuid)
+	if !stored.Suspended {
+		t.Error("Expected the suspended state to be stored")
+	}
+}
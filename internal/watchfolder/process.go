@@ -2,7 +2,11 @@ package watchfolder
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"strings"
@@ -13,6 +17,7 @@ import (
 	"github.com/welovemedia/ffmate/internal/database/repository"
 	"github.com/welovemedia/ffmate/internal/dto"
 	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/internal/watchfolder/filter"
 	"github.com/welovemedia/ffmate/sev"
 	"github.com/yosev/debugo"
 )
@@ -38,6 +43,7 @@ func (w *Watchfolder) Init() {
 	debug.Debugf("initializing %d watchfolders", total)
 
 	go w.monitorWatchfolderUpdates()
+	go w.monitorFinishedTasks()
 
 	for _, watchfolder := range *watchfolders {
 		if !watchfolder.Suspended && !watchfolder.DeletedAt.Valid {
@@ -69,10 +75,27 @@ func (w *Watchfolder) monitorWatchfolderUpdates() {
 }
 
 func (w *Watchfolder) process(watchfolder *model.Watchfolder, ctx context.Context) {
-	fileStates := sync.Map{}
-	processedFiles := sync.Map{}
+	f, err := filter.Compile(watchfolder.Filter)
+	if err != nil {
+		// filters are validated when the watchfolder is saved, this only happens for watchfolders stored by older versions
+		watchfolder.Error = err.Error()
+		service.WatchfolderService().UpdateWatchfolderInternal(watchfolder)
+		w.Sev.Logger().Errorf("invalid watchfolder filter, not watching (uuid: %s): %v", watchfolder.Uuid, err)
+		return
+	}
+	s := &scanner{w: w, watchfolder: watchfolder, filter: f, ignoredDirs: actionDirs(watchfolder)}
+	s.load()
 	debug.Debugf("initialized new watchfolder watcher (uuid: %s)", watchfolder.Uuid)
 
+	if watchfolder.Mode == dto.WATCHFOLDER_MODE_EVENTS {
+		err := w.watch(watchfolder, ctx, s)
+		if err == nil {
+			return
+		}
+		// e.g. network filesystems without notification support or exhausted inotify watches
+		w.Sev.Logger().Warnf("event based watching failed, falling back to polling (uuid: %s): %v", watchfolder.Uuid, err)
+	}
+
 	for {
 		select {
 		case <-ctx.Done():
@@ -82,56 +105,225 @@ func (w *Watchfolder) process(watchfolder *model.Watchfolder, ctx context.Contex
 		}
 		debug.Debugf("processing watchfolder (uuid: %s)", watchfolder.Uuid)
 		watchfolder.LastCheck = time.Now().UnixMilli()
-		watchfolder.Error = ""
 
-		// Walk the directory
-		err := filepath.Walk(watchfolder.Path, func(path string, info os.FileInfo, err error) error {
-			if err != nil {
-				return err
-			}
+		err := s.scan()
+		s.walkError = ""
+		if err != nil {
+			s.walkError = err.Error()
+		}
+		suspended := w.recordCheck(watchfolder, err)
+		watchfolder.Error = s.error()
 
-			// Skip directories
-			if info.IsDir() {
-				return nil
-			}
+		w.Sev.Metrics().Gauge("watchfolder.executed").Inc()
+		service.WatchfolderService().UpdateWatchfolderInternal(watchfolder)
+		if suspended {
+			return
+		}
+		time.Sleep(time.Duration(watchfolder.Interval * int(time.Second)))
+	}
+}
 
-			// Skip invisible files
-			if strings.HasPrefix(filepath.Base(path), ".") {
-				return nil
-			}
+// scanner tracks the files of a single watchfolder run
+type scanner struct {
+	w           *Watchfolder
+	watchfolder *model.Watchfolder
+	filter      *filter.Filter
+	ignoredDirs []string
+	walkError   string
+	// taskError holds the last failed task creation, it is kept until a task is created again
+	taskError string
+
+	// pending collects files that are not ready yet in event mode, polling re-checks them with the next walk anyway
+	pending map[string]struct{}
+
+	fileStates     sync.Map
+	processedFiles sync.Map // path -> *model.WatchfolderFile
+}
 
-			// Filter extensions
-			if filterOutExtension(watchfolder, path) {
-				return nil
-			}
+// load restores the files processed by previous runs
+func (s *scanner) load() {
+	files, err := service.WatchfolderService().ProcessedWatchfolderFiles(s.watchfolder.Uuid)
+	if err != nil {
+		s.w.Sev.Logger().Errorf("failed to load processed files of watchfolder (uuid: %s): %v", s.watchfolder.Uuid, err)
+		return
+	}
+	for i := range *files {
+		file := &(*files)[i]
+		s.processedFiles.Store(file.Path, file)
+	}
+	debug.Debugf("loaded %d processed files (uuid: %s)", len(*files), s.watchfolder.Uuid)
+}
 
-			// Check if the file has already been processed
-			if _, seen := processedFiles.Load(path); seen {
-				return nil
-			}
+// scan checks the whole watchfolder
+func (s *scanner) scan() error {
+	if s.watchfolder.Unit == dto.WATCHFOLDER_UNIT_DIRECTORY {
+		return s.walkDirs()
+	}
+	return s.walk(s.watchfolder.Path)
+}
 
-			// Determine if the file is ready for processing
-			if shouldProcessFile(path, info, &fileStates, watchfolder.GrowthChecks) {
-				w.createTask(path, watchfolder)
-				processedFiles.Store(path, true) // Mark as processed
-				fileStates.Delete(path)          // Remove from tracking
-			}
+// checkPath checks a single file or directory depending on the unit of the watchfolder
+func (s *scanner) checkPath(path string, info os.FileInfo) bool {
+	if s.watchfolder.Unit == dto.WATCHFOLDER_UNIT_DIRECTORY {
+		return info.IsDir() && s.checkDir(path)
+	}
+	return !info.IsDir() && s.check(path, info)
+}
+
+// walk checks every file below the given directory
+func (s *scanner) walk(root string) error {
+	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 
+		// Skip directories
+		if info.IsDir() {
+			if s.skipDir(path) {
+				return filepath.SkipDir
+			}
 			return nil
-		})
+		}
+
+		if s.check(path, info) && s.pending != nil {
+			s.pending[path] = struct{}{}
+		}
+		return nil
+	})
+}
 
+// error returns the error shown on the watchfolder, walking errors take precedence over failed task creations and post actions
+func (s *scanner) error() string {
+	if s.walkError != "" {
+		return s.walkError
+	}
+	if s.taskError != "" {
+		return s.taskError
+	}
+	return postActionError(s.watchfolder.Uuid)
+}
+
+// ignored reports whether the path is inside a directory the post actions move files to
+func (s *scanner) ignored(path string) bool {
+	for _, dir := range s.ignoredDirs {
+		if isBelow(filepath.Clean(path), dir) {
+			return true
+		}
+	}
+	return false
+}
+
+// skipDir reports whether the directory must not be looked into
+func (s *scanner) skipDir(path string) bool {
+	if s.ignored(path) {
+		return true
+	}
+	rel, err := filepath.Rel(s.watchfolder.Path, path)
+	return err == nil && s.filter.SkipDir(rel)
+}
+
+// check creates a task for the file once it passed the filters and growth checks, it returns true if the file is not ready yet and must be checked again
+func (s *scanner) check(path string, info os.FileInfo) bool {
+	// Skip invisible files
+	if strings.HasPrefix(filepath.Base(path), ".") {
+		return false
+	}
+
+	rel, err := filepath.Rel(s.watchfolder.Path, path)
+	if err != nil || !s.filter.Match(rel, info) {
+		return false
+	}
+	return s.process(path, info, nil)
+}
+
+// process creates a task for the file or directory once it is ready, it returns true if it must be checked again
+func (s *scanner) process(path string, info os.FileInfo, seq *sequence) bool {
+	// Check if the file has already been processed, files that changed since are processed again
+	file := &model.WatchfolderFile{Watchfolder: s.watchfolder.Uuid, Path: path}
+	if processed, seen := s.processedFiles.Load(path); seen {
+		file = processed.(*model.WatchfolderFile)
+		if !fileChanged(file, info) {
+			return false
+		}
+	}
+
+	// Wait for the minimum age and the ready marker
+	if !s.filter.Aged(info, time.Now()) || !s.filter.Ready(path) {
+		return true
+	}
+
+	// Determine if the file is ready for processing
+	if !shouldProcessFile(path, info, &s.fileStates, s.watchfolder.GrowthChecks) {
+		return true
+	}
+	s.fileStates.Delete(path) // Remove from tracking
+
+	var hash string
+	if s.watchfolder.HashFiles && !info.IsDir() {
+		var err error
+		hash, err = hashFile(path)
 		if err != nil {
-			watchfolder.Error = err.Error()
-			w.Sev.Logger().Errorf("walking watchfolder directory failed (uuid: %s): %v", watchfolder.Uuid, err)
+			s.w.Sev.Logger().Warnf("failed to hash file %s (uuid: %s): %v", path, s.watchfolder.Uuid, err)
 		}
+	}
 
-		w.Sev.Metrics().Gauge("watchfolder.executed").Inc()
-		service.WatchfolderService().UpdateWatchfolderInternal(watchfolder)
-		time.Sleep(time.Duration(watchfolder.Interval * int(time.Second)))
+	// only the timestamp changed, the content is the same
+	if hash != "" && hash == file.Hash {
+		debug.Debugf("file %s changed without changing its content (uuid: %s)", path, s.watchfolder.Uuid)
+	} else {
+		task, batch, err := s.w.createTask(path, info, s.watchfolder, seq)
+		if err != nil {
+			// the file is not recorded as processed and tried again with the next check
+			s.taskError = err.Error()
+			return true
+		}
+		s.taskError = ""
+		file.Task, file.Batch = task, batch
+	}
+	s.watchfolder.FilesSeen++
+
+	file.Size = info.Size()
+	file.ModTime = info.ModTime().UnixMilli()
+	file.Hash = hash
+	if _, err := service.WatchfolderService().SaveWatchfolderFile(file); err != nil {
+		s.w.Sev.Logger().Errorf("failed to save processed file %s (uuid: %s): %v", path, s.watchfolder.Uuid, err)
+	}
+	s.processedFiles.Store(path, file) // Mark as processed
+	return false
+}
+
+// fileChanged reports whether the file differs in size or modification time from when it was processed
+func fileChanged(file *model.WatchfolderFile, info os.FileInfo) bool {
+	return file.Size != info.Size() || file.ModTime != info.ModTime().UnixMilli()
+}
+
+// hashFile returns the hex encoded sha256 checksum of the file
+func hashFile(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
 	}
+	return hex.EncodeToString(h.Sum(nil)), nil
 }
 
-func (w *Watchfolder) createTask(path string, watchfolder *model.Watchfolder) {
+// createTask creates a batch with one task per preset of the matching rule or the watchfolder for the file or directory
+// and returns the uuid of the first task and the batch
+func (w *Watchfolder) createTask(path string, info os.FileInfo, watchfolder *model.Watchfolder, seq *sequence) (string, string, error) {
+	route, err := service.WatchfolderService().RouteFile(watchfolder, path, info)
+	if err != nil {
+		w.Sev.Logger().Errorf("failed to route file %s of watchfolder (uuid: %s): %v", path, watchfolder.Uuid, err)
+		return "", "", fmt.Errorf("failed to route file %s: %v", path, err)
+	}
+	if route.Rule >= 0 {
+		debug.Debugf("file %s matched rule %d '%s' of watchfolder (uuid: %s)", path, route.Rule, route.RuleName, watchfolder.Uuid)
+	}
+
 	// create ffmate metadata map
 	ffmate := map[string]map[string]string{
 		"watchfolder": {
@@ -139,16 +331,9 @@ func (w *Watchfolder) createTask(path string, watchfolder *model.Watchfolder) {
 			"path": watchfolder.Path,
 		},
 	}
-	metadata := &dto.InterfaceMap{"ffmate": ffmate}
-
-	// create new task
-	task := &dto.NewTask{
-		Preset:    watchfolder.Preset,
-		Name:      filepath.Base(path),
-		Metadata:  metadata,
-		InputFile: path,
+	if seq != nil {
+		ffmate["sequence"] = seq.metadata()
 	}
-
 	// hydrate paths in ffmate metadata map
 	relPath, err := filepath.Rel(watchfolder.Path, path)
 	if err != nil {
@@ -158,40 +343,38 @@ func (w *Watchfolder) createTask(path string, watchfolder *model.Watchfolder) {
 		ffmate["watchfolder"]["relativeDir"] = filepath.Dir(relPath)
 	}
 
-	// add new Task
-	_, err = service.TaskService().NewTask(task, "", "watchfolder")
-	if err != nil {
-		w.Sev.Logger().Errorf("failed to create task for watchfolder (uuid: %s) file: %s: %v", watchfolder.Uuid, path, err)
-		return
-	}
-	debug.Debugf("created new task for watchfolder (uuid: %s) file: %s", watchfolder.Uuid, path)
-}
-
-func filterOutExtension(watchfolder *model.Watchfolder, path string) bool {
-	if watchfolder.Filter != nil && watchfolder.Filter.Extensions != nil {
-		if len(watchfolder.Filter.Extensions.Exclude) > 0 {
-			var exclude bool = false
-			for _, ext := range watchfolder.Filter.Extensions.Exclude {
-				if strings.HasSuffix(path, "."+ext) {
-					exclude = true
-					break
-				}
-			}
-			return exclude
+	metadata := &dto.InterfaceMap{}
+	if route.Metadata != nil {
+		for k, v := range *route.Metadata {
+			(*metadata)[k] = v
 		}
+	}
+	(*metadata)["ffmate"] = ffmate
+
+	// create one task per preset
+	tasks := []dto.NewTask{}
+	for _, preset := range route.Presets {
+		tasks = append(tasks, dto.NewTask{
+			Preset:    preset,
+			Name:      filepath.Base(path),
+			Metadata:  metadata,
+			InputFile: path,
+			Priority:  route.Priority,
+		})
+	}
 
-		if len(watchfolder.Filter.Extensions.Include) > 0 {
-			var include bool = true
-			for _, ext := range watchfolder.Filter.Extensions.Include {
-				if strings.HasSuffix(path, ext) {
-					include = false
-					break
-				}
-			}
-			return include
-		}
+	// add new batch
+	t, err := service.TaskService().NewBatch(&tasks, "watchfolder")
+	if err == nil && len(*t) == 0 {
+		err = errors.New("no tasks created")
 	}
-	return false
+	if err != nil {
+		w.Sev.Logger().Errorf("failed to create tasks for watchfolder (uuid: %s) file: %s: %v", watchfolder.Uuid, path, err)
+		return "", "", fmt.Errorf("failed to create tasks for file %s: %v", path, err)
+	}
+	recordTasks(watchfolder, path, *t)
+	debug.Debugf("created %d new tasks for watchfolder (uuid: %s) file: %s", len(*t), watchfolder.Uuid, path)
+	return (*t)[0].Uuid, (*t)[0].Batch, nil
 }
 
 // shouldProcessFile determines if a file is ready for processing based on growth attempts.
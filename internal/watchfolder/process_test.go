@@ -0,0 +1,110 @@
+package watchfolder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/dto"
+	"github.com/welovemedia/ffmate/internal/service"
+	"github.com/welovemedia/ffmate/internal/watchfolder/filter"
+)
+
+func TestCreateTaskRules(t *testing.T) {
+	w, tasks, preset := setupWatchfolderTest(t)
+	proxy, err := service.PresetService().NewPreset(&dto.NewPreset{Name: "Proxy", Command: "-i ${INPUT_FILE} ${OUTPUT_FILE}", OutputFile: "/tmp/proxy.mp4", Priority: 50})
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	for _, name := range []string{"movie.mp4", "movie_proxy.mp4"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("x"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	zero := uint(0)
+	watchfolder, err := service.WatchfolderService().NewWatchfolder(&dto.NewWatchfolder{
+		Path:   dir,
+		Preset: preset.Uuid,
+		Rules: dto.WatchfolderRules{{
+			Name:     "proxies",
+			Match:    dto.WatchfolderRuleMatch{Include: []string{"*_proxy*"}},
+			Presets:  dto.WatchfolderPresets{proxy.Uuid},
+			Priority: &zero,
+			Metadata: &dto.InterfaceMap{"team": "proxy"},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create watchfolder: %v", err)
+	}
+
+	route, err := service.WatchfolderService().DryRunWatchfolder(watchfolder.Uuid, "movie_proxy.mp4")
+	if err != nil || route.Rule != 0 || route.RuleName != "proxies" {
+		t.Fatalf("Expected movie_proxy.mp4 to hit the proxies rule, got %+v (err: %v)", route, err)
+	}
+	route, err = service.WatchfolderService().DryRunWatchfolder(watchfolder.Uuid, filepath.Join(dir, "movie.mp4"))
+	if err != nil || route.Rule != -1 || len(route.Presets) != 1 || route.Presets[0] != preset.Uuid {
+		t.Fatalf("Expected movie.mp4 to use the watchfolder preset, got %+v (err: %v)", route, err)
+	}
+	if _, err := service.WatchfolderService().DryRunWatchfolder(watchfolder.Uuid, "missing.mp4"); err == nil {
+		t.Error("Expected dry-run of a missing file to fail")
+	}
+
+	path := filepath.Join(dir, "movie_proxy.mp4")
+	uuid, _, _ := w.createTask(path, stat(t, path), watchfolder, nil)
+	task, err := tasks.First(uuid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.OutputFile.Raw != "/tmp/proxy.mp4" || task.Priority != 0 {
+		t.Errorf("Expected the proxy preset with priority 0, got %s with priority %d", task.OutputFile.Raw, task.Priority)
+	}
+	if (*task.Metadata)["team"] != "proxy" || (*task.Metadata)["ffmate"] == nil {
+		t.Errorf("Expected rule metadata next to the ffmate metadata, got %v", *task.Metadata)
+	}
+
+	if _, err := service.WatchfolderService().NewWatchfolder(&dto.NewWatchfolder{
+		Path:   dir,
+		Preset: preset.Uuid,
+		Rules:  dto.WatchfolderRules{{Presets: dto.WatchfolderPresets{"missing"}}},
+	}); err == nil {
+		t.Error("Expected a rule with an unknown preset to be rejected")
+	}
+}
+
+func TestProcessTaskCreationFailure(t *testing.T) {
+	w, _, preset := setupWatchfolderTest(t)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "movie.mp4")
+	if err := os.WriteFile(path, []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	watchfolder, err := service.WatchfolderService().NewWatchfolder(&dto.NewWatchfolder{Path: dir, Preset: preset.Uuid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	watchfolder.GrowthChecks = 0
+	f, err := filter.Compile(watchfolder.Filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &scanner{w: w, watchfolder: watchfolder, filter: f}
+
+	// the preset is gone, the file must be tried again instead of being recorded as processed
+	if err := service.PresetService().DeletePreset(preset.Uuid); err != nil {
+		t.Fatal(err)
+	}
+	if !s.process(path, stat(t, path), nil) {
+		t.Error("Expected the file to be checked again after the task creation failed")
+	}
+	if s.error() == "" {
+		t.Error("Expected the failed task creation to show up as watchfolder error")
+	}
+	if _, seen := s.processedFiles.Load(path); seen {
+		t.Error("Expected the file not to be marked as processed")
+	}
+	if files, err := service.WatchfolderService().ProcessedWatchfolderFiles(watchfolder.Uuid); err != nil || len(*files) != 0 {
+		t.Errorf("Expected no processed file to be persisted, got %v (err: %v)", files, err)
+	}
+}
@@ -0,0 +1,69 @@
+package workspace
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/welovemedia/ffmate/internal/config"
+	"github.com/welovemedia/ffmate/internal/utils"
+)
+
+// Root returns the absolute directory all task workspaces are created in
+func Root() string {
+	config.Config().Mutex.RLock()
+	root := config.Config().WorkspaceRoot
+	config.Config().Mutex.RUnlock()
+
+	if root == "" {
+		return filepath.Join(os.TempDir(), "ffmate")
+	}
+	if abs, err := filepath.Abs(root); err == nil {
+		return abs
+	}
+	return root
+}
+
+// Dir returns the workspace directory of the task
+func Dir(uuid string) string {
+	return filepath.Join(Root(), uuid)
+}
+
+// Create creates the workspace of the task after making sure the root has enough free space left
+func Create(uuid string) (string, error) {
+	root := Root()
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return "", fmt.Errorf("failed to create workspace root: %v", err)
+	}
+
+	config.Config().Mutex.RLock()
+	minFree := uint64(config.Config().WorkspaceMinFree) << 20
+	config.Config().Mutex.RUnlock()
+	if minFree > 0 {
+		free, err := utils.FreeDiskSpace(root)
+		if err != nil {
+			return "", fmt.Errorf("failed to determine free disk space of workspace root: %v", err)
+		}
+		if free < minFree {
+			return "", fmt.Errorf("not enough free disk space in workspace root '%s' (free: %d MB, required: %d MB)", root, free>>20, minFree>>20)
+		}
+	}
+
+	dir := Dir(uuid)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create workspace: %v", err)
+	}
+	return dir, nil
+}
+
+// Cleanup removes the workspace of the task, workspaces of failed tasks are kept if configured
+func Cleanup(uuid string, failed bool) error {
+	config.Config().Mutex.RLock()
+	keep := config.Config().KeepFailedWorkspaces
+	config.Config().Mutex.RUnlock()
+
+	if failed && keep {
+		return nil
+	}
+	return os.RemoveAll(Dir(uuid))
+}
@@ -0,0 +1,54 @@
+package workspace
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/welovemedia/ffmate/internal/config"
+)
+
+func setConfig(root string, minFree uint, keep bool) {
+	config.Config().Mutex.Lock()
+	config.Config().WorkspaceRoot = root
+	config.Config().WorkspaceMinFree = minFree
+	config.Config().KeepFailedWorkspaces = keep
+	config.Config().Mutex.Unlock()
+}
+
+func TestCreateAndCleanup(t *testing.T) {
+	root := t.TempDir()
+	setConfig(root, 0, true)
+	defer setConfig("", 0, false)
+
+	dir, err := Create("task-1")
+	if err != nil {
+		t.Fatalf("Create() failed: %v", err)
+	}
+	if dir != filepath.Join(root, "task-1") {
+		t.Errorf("Create() = %s", dir)
+	}
+
+	if err := Cleanup("task-1", true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); err != nil {
+		t.Error("Cleanup() removed the workspace of a failed task although it should be kept")
+	}
+
+	if err := Cleanup("task-1", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Error("Cleanup() did not remove the workspace")
+	}
+}
+
+func TestCreateMinFree(t *testing.T) {
+	setConfig(t.TempDir(), ^uint(0)>>20, false)
+	defer setConfig("", 0, false)
+
+	if _, err := Create("task-1"); err == nil {
+		t.Error("Create() succeeded without enough free disk space")
+	}
+}